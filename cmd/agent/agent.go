@@ -26,7 +26,7 @@ func printVersion() {
 
 type Agent interface {
 	Start(ctx context.Context)
-	Wait()
+	Shutdown() error
 }
 
 func main() {
@@ -39,30 +39,79 @@ func main() {
 	}
 
 	// Отвечает за сбор метрик
-	metricStorage := agent.NewMemStatsStorage()
+	metricStorage, err := agent.NewCollectorStorage(agentCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("collectors: %v", metricStorage.Collectors())
+
+	// Принимает метрики от приложений на том же хосте
+	var pushReceiver interface{ Serve(ctx context.Context) }
+	if agentCfg.PushAddress != "" || agentCfg.PushSocket != "" {
+		receiver := agent.NewPushReceiver(agentCfg)
+		if err := receiver.Listen(); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("push receiver: %v", receiver.Addrs())
+		metricStorage.Add("push", receiver)
+		pushReceiver = receiver
+	}
+
+	// Добавляет агрегаты gauge за интервал отчета
+	var reportStorage agent.MetricStorage = metricStorage
+	if agentCfg.Aggregates != "" {
+		reportStorage, err = agent.NewAggregateStorage(agentCfg, metricStorage)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	// Отвечает за отправку по http/grpc
 	// При наличии префикса dns
 	// https://github.com/grpc/grpc/blob/master/doc/naming.md
-	var resultSender agent.ResultSender
+	var senderFactory agent.ResultSenderFactory
 	if agentCfg.UseGRPC {
-		resultSender = agent.NewGRPCResultSender(agentCfg)
+		senderFactory = func(conf *config.AgentConfiguration) agent.ResultSender {
+			return agent.NewGRPCResultSender(conf)
+		}
 		log.Println("work via grpc")
 	} else {
-		resultSender = agent.NewHTTPResultSender(agentCfg)
+		senderFactory = func(conf *config.AgentConfiguration) agent.ResultSender {
+			return agent.NewHTTPResultSender(conf)
+		}
 		log.Println("work via http")
 	}
 
+	// Отвечает за выбор сервера из нескольких адресов
+	resultSender, err := agent.NewFailoverResultSender(agentCfg, senderFactory)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("servers: %v (%v)", agentCfg.ServerAddr, agentCfg.ServerSelection)
+
 	// Отвечает за повтор отправки
 	retryCfg := agent.DefaultConf(syscall.ECONNREFUSED)
 	retryableResultSender := agent.NewHTTPRetryableResultSender(*retryCfg, resultSender)
 
+	// Отвечает за сохранение неотправленных пакетов на диск
+	var queuedResultSender agent.ResultSender = retryableResultSender
+	if agentCfg.QueueDir != "" {
+		queue, err := agent.NewQueueResultSender(agentCfg, retryableResultSender)
+		if err != nil {
+			log.Fatal(err)
+		}
+		metricStorage.Add("queue", queue)
+		queuedResultSender = queue
+		log.Printf("send queue: %v", agentCfg.QueueDir)
+	}
+
 	// Отвечает за пулы отправки
-	limitedResultSender := agent.NewPoolResultSender(agentCfg, retryableResultSender)
+	limitedResultSender := agent.NewPoolResultSender(agentCfg, queuedResultSender)
+	metricStorage.Add("pool", limitedResultSender)
 
 	var agnt Agent = agent.Create(agentCfg,
 		limitedResultSender,
-		metricStorage,
+		reportStorage,
 	)
 
 	ctx, cancelFn := context.WithCancel(context.Background())
@@ -70,9 +119,15 @@ func main() {
 	signal.Notify(exit, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 
 	agnt.Start(ctx)
+	if pushReceiver != nil {
+		go pushReceiver.Serve(ctx)
+	}
 	defer func() {
+		// опрос останавливается, оставшиеся метрики отправляются не дольше ShutdownTimeout
 		cancelFn()
-		agnt.Wait()
+		if err := agnt.Shutdown(); err != nil {
+			log.Println(err)
+		}
 	}()
 	<-exit
 }
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -15,6 +16,8 @@ import (
 	"github.com/StasMerzlyakov/go-metrics/internal/keygen"
 	pb "github.com/StasMerzlyakov/go-metrics/internal/proto"
 	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/fs/backup"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/fs/rules"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/graphite"
 	gdpt "github.com/StasMerzlyakov/go-metrics/internal/server/adapter/grpc"
 	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/handler"
 	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/middleware"
@@ -24,6 +27,8 @@ import (
 	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/middleware/logging"
 	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/middleware/retry"
 	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/middleware/trusted"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/notify"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/statsd"
 	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/storage/memory"
 	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/storage/postgres"
 	"github.com/StasMerzlyakov/go-metrics/internal/server/app"
@@ -41,7 +46,11 @@ type Server interface {
 }
 
 const (
-	maxRetryCount = 4
+	maxRetryCount       = 4
+	historyTrimInterval = time.Minute
+	batchTrimInterval   = time.Minute
+	notifyDedupWindow   = time.Hour
+	notifyTimeout       = 10 * time.Second
 )
 
 func createMiddleWareList(srvConf *config.ServerConfiguration) []func(http.Handler) http.Handler {
@@ -97,6 +106,8 @@ type FullStorage interface {
 	app.Storage
 	app.AllMetricsStorage
 	app.Pinger
+	app.HistoryStorage
+	app.IdempotencyStorage
 	Bootstrap(ctx context.Context) error
 	Close(ctx context.Context) error
 }
@@ -142,6 +153,50 @@ func main() {
 	// операции с метриками
 	metricApp := app.NewMetrics(storage)
 
+	// -------- История значений ------------
+	if srvConf.HistoryRetention > 0 {
+		metricApp.SetHistory(storage, time.Duration(srvConf.HistoryRetention)*time.Second)
+
+		// удаление устаревших значений
+		go func() {
+			var ticker = time.NewTicker(historyTrimInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-srvCtx.Done():
+					sugarLog.Infow("Run", "msg", "history trim finished")
+					return
+				case <-ticker.C:
+					if err := metricApp.TrimHistory(srvCtx); err != nil {
+						sugarLog.Errorw("TrimHistory", "msg", err.Error())
+					}
+				}
+			}
+		}()
+	}
+
+	// -------- Дедупликация пакетов ------------
+	if srvConf.IdempotencyWindow > 0 {
+		metricApp.SetIdempotency(storage, time.Duration(srvConf.IdempotencyWindow)*time.Second)
+
+		// удаление устаревших ключей пакетов
+		go func() {
+			var ticker = time.NewTicker(batchTrimInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-srvCtx.Done():
+					sugarLog.Infow("Run", "msg", "batch keys trim finished")
+					return
+				case <-ticker.C:
+					if err := metricApp.TrimBatchKeys(srvCtx); err != nil {
+						sugarLog.Errorw("TrimBatchKeys", "msg", err.Error())
+					}
+				}
+			}
+		}()
+	}
+
 	// -------- Бэкап ------------
 	backupFomratter := backup.NewJSON(srvConf.FileStoragePath)
 	backUper := app.NewBackup(storage, backupFomratter, metricApp)
@@ -177,6 +232,56 @@ func main() {
 
 	}
 
+	// -------- Оповещения ------------
+	alertApp := app.NewAlerting(storage, rules.NewJSON(srvConf.AlertRulesFile))
+
+	if srvConf.AlertRulesFile != "" {
+		if err := alertApp.LoadRules(srvCtx); err != nil {
+			panic(err)
+		}
+
+		// доставка оповещений; у каждого канала свои повторы
+		notifier := app.NewNotifier(time.Duration(srvConf.NotifyGroupWait)*time.Second, notifyDedupWindow)
+		notifier.AddChannel(notify.NewLog(), domain.CreateRetriableInvokerByErr(domain.ErrNotification))
+
+		if srvConf.NotifyWebhookURL != "" {
+			webhook := notify.NewWebhook(srvConf.NotifyWebhookURL, &http.Client{Timeout: notifyTimeout})
+			notifier.AddChannel(webhook, domain.CreateRetriableInvokerByErr(domain.ErrNotification))
+		}
+
+		if srvConf.NotifySMTPAddr != "" {
+			smtpSender := notify.NewSMTP(&notify.SMTPConf{
+				Addr:     srvConf.NotifySMTPAddr,
+				From:     srvConf.NotifySMTPFrom,
+				To:       strings.Split(srvConf.NotifySMTPTo, ","),
+				Username: srvConf.NotifySMTPUser,
+				Password: srvConf.NotifySMTPPassword,
+			})
+			notifier.AddChannel(smtpSender, domain.CreateRetriableInvokerByErr(domain.ErrNotification))
+		}
+
+		alertApp.AddListener(notifier.OnAlert)
+		go notifier.Run(srvCtx)
+
+		// периодическая проверка правил
+		go func() {
+			alertInterval := time.Duration(srvConf.AlertInterval) * time.Second
+			var ticker = time.NewTicker(alertInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-srvCtx.Done():
+					sugarLog.Infow("Run", "msg", "alerting finished")
+					return
+				case <-ticker.C:
+					if err := alertApp.Evaluate(srvCtx); err != nil {
+						sugarLog.Errorw("Evaluate", "msg", err.Error())
+					}
+				}
+			}
+		}()
+	}
+
 	// ---------- Http сервер -----------
 	httpHandler := chi.NewMux()
 
@@ -201,10 +306,22 @@ func main() {
 
 	handler.AddMetricOperations(httpHandler, metricApp, updateMWList...)
 
+	// прием данных от Prometheus
+	handler.AddRemoteWriteOperations(httpHandler, metricApp)
+
+	// прием данных в формате InfluxDB line protocol; проходит общую цепочку мидлов createMiddleWareList
+	handler.AddInfluxOperations(httpHandler, metricApp)
+
+	// прием данных OpenTelemetry (OTLP/HTTP)
+	handler.AddOTLPOperations(httpHandler, metricApp)
+
 	// административные операции
 	adminApp := app.NewAdminApp(storage)
 	handler.AddAdminOperations(httpHandler, adminApp)
 
+	// оповещения
+	handler.AddAlertOperations(httpHandler, alertApp)
+
 	// ppfod
 	handler.AddPProfOperations(httpHandler)
 
@@ -288,5 +405,39 @@ func main() {
 
 	}
 
+	// StatsD
+	if srvConf.StatsDAddress != "" {
+		sugarLog.Info("start with statsd")
+
+		statsdListener := statsd.NewListener(srvConf.StatsDAddress, time.Duration(srvConf.StatsDFlush)*time.Second, metricApp)
+		if err := statsdListener.Listen(); err != nil {
+			panic(err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statsdListener.Serve(srvCtx)
+			sugarLog.Infow("Run", "msg", "statsd finished")
+		}()
+	}
+
+	// Graphite
+	if srvConf.GraphiteAddress != "" {
+		sugarLog.Info("start with graphite")
+
+		graphiteListener := graphite.NewListener(srvConf.GraphiteAddress, int(srvConf.GraphiteMaxConns), metricApp)
+		if err := graphiteListener.Listen(); err != nil {
+			panic(err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			graphiteListener.Serve(srvCtx)
+			sugarLog.Infow("Run", "msg", "graphite finished")
+		}()
+	}
+
 	wg.Wait()
 }
@@ -7,19 +7,28 @@ import (
 	"sync"
 	"time"
 
-	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	cfg "github.com/StasMerzlyakov/go-metrics/internal/config"
 	"github.com/sirupsen/logrus"
 )
 
-func Create(config *config.AgentConfiguration,
+func Create(config *cfg.AgentConfiguration,
 	resultSender ResultSender,
 	metricStorage MetricStorage,
 ) *agent {
+	labels, _ := cfg.ParseLabels(config.Labels) // формат меток проверяется при загрузке конфигурации
+
+	shutdownTimeout := config.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = cfg.AgentDefaultShutdownTime
+	}
+
 	agent := &agent{
 		metricStorage:     metricStorage,
 		resultSender:      resultSender,
 		pollIntervalSec:   config.PollInterval,
 		reportIntervalSec: config.ReportInterval,
+		shutdownTimeout:   time.Duration(shutdownTimeout) * time.Second,
+		labels:            labels,
 	}
 
 	return agent
@@ -30,7 +39,11 @@ type agent struct {
 	resultSender      ResultSender
 	pollIntervalSec   int
 	reportIntervalSec int
+	shutdownTimeout   time.Duration
+	labels            map[string]string
 	wg                sync.WaitGroup
+	sendCtx           context.Context // отправка не прерывается отменой контекста Start до завершения Shutdown
+	cancelSend        context.CancelFunc
 }
 
 func (a *agent) Wait() {
@@ -38,9 +51,10 @@ func (a *agent) Wait() {
 }
 
 func (a *agent) Start(ctx context.Context) {
+	a.sendCtx, a.cancelSend = context.WithCancel(context.WithoutCancel(ctx))
+	a.wg.Add(2)
 	go a.pollMetrics(ctx)
 	go a.reportMetrics(ctx)
-	a.wg.Add(2)
 }
 
 func (a *agent) pollMetrics(ctx context.Context) {
@@ -71,13 +85,70 @@ func (a *agent) reportMetrics(ctx context.Context) {
 			a.wg.Done()
 			return
 		case <-time.After(reportInterval):
-			metrics := a.metricStorage.GetMetrics()
-			err := a.resultSender.SendMetrics(ctx, metrics)
-			if err != nil {
-				logrus.Infof("ReportMetrics ERROR: %v\n", err)
-			} else {
-				logrus.Info("ReportMetrics SUCCESS")
-			}
+			a.report(a.sendCtx)
+		}
+	}
+}
+
+// Shutdown завершает работу агента после отмены контекста Start.
+//
+// Дожидается остановки опроса, собирает и отправляет метрики последний раз, ожидает отправки
+// принятых отправителем метрик и закрывает соединения. Отправка прерывается по истечении ShutdownTimeout.
+func (a *agent) Shutdown() error {
+	ctx, cancelFn := context.WithTimeout(a.sendCtx, a.shutdownTimeout)
+	defer cancelFn()
+	// по истечении времени прерывается и отправка, начатая до отмены контекста Start
+	context.AfterFunc(ctx, a.cancelSend)
+
+	a.Wait()
+
+	if err := a.metricStorage.Refresh(); err != nil {
+		logrus.Errorf("Shutdown refresh error: %v", err)
+	}
+	a.report(ctx)
+
+	var err error
+	if f, ok := a.resultSender.(flusher); ok {
+		err = f.Flush(ctx)
+	}
+	a.resultSender.Stop()
+
+	if err != nil {
+		logrus.Errorf("Shutdown ERROR: %v", err)
+		return err
+	}
+	logrus.Info("Shutdown SUCCESS")
+	return nil
+}
+
+func (a *agent) report(ctx context.Context) {
+	metrics := a.withLabels(a.metricStorage.GetMetrics())
+	err := a.resultSender.SendMetrics(ctx, metrics)
+	if _, ok := a.resultSender.(asyncSender); !ok {
+		// неотправленные приращения counter и histogram уйдут со следующим отчетом
+		settleAll(metrics, err)
+	}
+	if err != nil {
+		logrus.Infof("ReportMetrics ERROR: %v\n", err)
+	} else {
+		logrus.Info("ReportMetrics SUCCESS")
+	}
+}
+
+// withLabels добавляет к метрикам метки агента; метки, установленные источником метрики, не перезаписываются
+func (a *agent) withLabels(metrics []Metrics) []Metrics {
+	if len(a.labels) == 0 {
+		return metrics
+	}
+	for i := range metrics {
+		labels := make(map[string]string, len(a.labels)+len(metrics[i].Labels))
+		for k, v := range a.labels {
+			labels[k] = v
+		}
+		for k, v := range metrics[i].Labels {
+			labels[k] = v
 		}
+		metrics[i].Labels = labels
 	}
+	return metrics
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/StasMerzlyakov/go-metrics/internal/agent"
 	"github.com/StasMerzlyakov/go-metrics/internal/config"
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAgentOk(t *testing.T) {
@@ -66,3 +67,77 @@ func TestAgentErr(t *testing.T) {
 
 	client.Wait()
 }
+
+func TestAgentLabels(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	value := 1.
+	mockStorage := NewMockMetricStorage(ctrl)
+	mockStorage.EXPECT().GetMetrics().Return([]agent.Metrics{
+		{
+			ID:    "Alloc",
+			MType: agent.GaugeType,
+			Value: &value,
+		},
+		{
+			ID:     "Custom",
+			MType:  agent.GaugeType,
+			Value:  &value,
+			Labels: map[string]string{"host": "own"},
+		},
+	}).MinTimes(1)
+	mockStorage.EXPECT().Refresh().AnyTimes()
+
+	mockSender := NewMockResultSender(ctrl)
+	mockSender.EXPECT().SendMetrics(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, metrics []agent.Metrics) error {
+			require.Equal(t, map[string]string{"host": "h1", "zone": "eu"}, metrics[0].Labels)
+			require.Equal(t, map[string]string{"host": "own", "zone": "eu"}, metrics[1].Labels)
+			return nil
+		}).MinTimes(1)
+
+	config := config.AgentConfiguration{
+		PollInterval:   1,
+		ReportInterval: 1,
+		Labels:         "host=h1,zone=eu",
+	}
+
+	client := agent.Create(&config, mockSender, mockStorage)
+
+	ctx, fn := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer fn()
+
+	client.Start(ctx)
+
+	client.Wait()
+}
+
+func TestAgentShutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSender := NewMockResultSender(ctrl)
+	mockStorage := NewMockMetricStorage(ctrl)
+
+	// опрос и отчет по таймеру не успевают выполниться - метрики собираются и отправляются при завершении
+	gomock.InOrder(
+		mockStorage.EXPECT().Refresh().Return(nil),
+		mockStorage.EXPECT().GetMetrics().Return([]agent.Metrics{}),
+		mockSender.EXPECT().SendMetrics(gomock.Any(), gomock.Any()).Return(nil),
+		mockSender.EXPECT().Stop(),
+	)
+
+	config := config.AgentConfiguration{
+		PollInterval:   10,
+		ReportInterval: 10,
+	}
+
+	client := agent.Create(&config, mockSender, mockStorage)
+
+	ctx, fn := context.WithCancel(context.Background())
+	client.Start(ctx)
+	fn()
+
+	require.NoError(t, client.Shutdown())
+}
@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"errors"
+	"math"
+	"path"
+	"sync"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+)
+
+// errNotReported возвращает источнику приращения, прочитанные при опросе, а не для отчета
+var errNotReported = errors.New("metrics are not reported")
+
+// gaugeWindow значения gauge за интервал отчета
+type gaugeWindow struct {
+	id     string
+	labels map[string]string
+	stats  []string
+	min    float64
+	max    float64
+	sum    float64
+	last   float64
+	count  int
+}
+
+func (w *gaugeWindow) observe(v float64) {
+	if w.count == 0 {
+		w.min, w.max = v, v
+	}
+	w.min = math.Min(w.min, v)
+	w.max = math.Max(w.max, v)
+	w.sum += v
+	w.last = v
+	w.count++
+}
+
+func (w *gaugeWindow) metrics() []Metrics {
+	values := map[string]float64{
+		config.AggregateMin:   w.min,
+		config.AggregateMax:   w.max,
+		config.AggregateMean:  w.sum / float64(w.count),
+		config.AggregateLast:  w.last,
+		config.AggregateCount: float64(w.count),
+	}
+
+	metrics := make([]Metrics, 0, len(w.stats))
+	for _, stat := range w.stats {
+		value := values[stat]
+		metrics = append(metrics, Metrics{
+			ID:     w.id + "_" + stat,
+			MType:  GaugeType,
+			Value:  &value,
+			Labels: w.labels,
+		})
+	}
+	return metrics
+}
+
+func NewAggregateStorage(conf *config.AgentConfiguration, storage MetricStorage) (*aggregateStorage, error) {
+	aggregates, err := config.ParseAggregates(conf.Aggregates)
+	if err != nil {
+		return nil, err
+	}
+	return &aggregateStorage{
+		storage:    storage,
+		aggregates: aggregates,
+		windows:    make(map[string]*gaugeWindow),
+	}, nil
+}
+
+// aggregateStorage добавляет к метрикам источника агрегаты gauge за интервал отчета.
+//
+// Значения gauge, имя которых соответствует шаблону, запоминаются при каждом опросе агента; в отчет
+// добавляются производные gauge <имя>_min, _max, _mean, _last и _count с метками исходной метрики.
+// Сборщик со своим интервалом опроса между обновлениями повторяет последнее значение.
+// После отчета значения накапливаются заново.
+type aggregateStorage struct {
+	storage    MetricStorage
+	aggregates []config.AggregateConf
+
+	mu      sync.Mutex
+	windows map[string]*gaugeWindow
+}
+
+func (as *aggregateStorage) Refresh() error {
+	err := as.storage.Refresh()
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	metrics := as.storage.GetMetrics()
+	for _, m := range metrics {
+		if m.MType != GaugeType || m.Value == nil {
+			continue
+		}
+		stats := as.stats(m.ID)
+		if stats == nil {
+			continue
+		}
+
+		key := seriesKey(m.ID, m.Labels)
+		window, ok := as.windows[key]
+		if !ok {
+			window = &gaugeWindow{id: m.ID, labels: m.Labels, stats: stats}
+			as.windows[key] = window
+		}
+		window.observe(*m.Value)
+	}
+	// приращения counter и histogram уходят со следующим отчетом
+	settleAll(metrics, errNotReported)
+
+	return err
+}
+
+// stats возвращает агрегаты первого подходящего шаблона.
+func (as *aggregateStorage) stats(id string) []string {
+	for _, aggregate := range as.aggregates {
+		if ok, _ := path.Match(aggregate.Pattern, id); ok {
+			return aggregate.Stats
+		}
+	}
+	return nil
+}
+
+func (as *aggregateStorage) GetMetrics() []Metrics {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	metrics := as.storage.GetMetrics()
+	for key, window := range as.windows {
+		metrics = append(metrics, window.metrics()...)
+		delete(as.windows, key)
+	}
+	return metrics
+}
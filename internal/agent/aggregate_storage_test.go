@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// sequenceStorage при каждом опросе возвращает следующее значение gauge и увеличивает counter
+type sequenceStorage struct {
+	values []float64
+	poll   int
+	count  counterDelta
+}
+
+func (s *sequenceStorage) Refresh() error {
+	s.poll++
+	s.count.Add(1)
+	return nil
+}
+
+func (s *sequenceStorage) GetMetrics() []Metrics {
+	value := s.values[s.poll-1]
+	other := 1.
+	delta, sent := s.count.Take()
+	return []Metrics{
+		{ID: "HeapAlloc", MType: GaugeType, Value: &value, Labels: map[string]string{"host": "h1"}},
+		{ID: "NumGC", MType: GaugeType, Value: &other},
+		{ID: "PollCount", MType: CounterType, Delta: &delta, sent: sent},
+	}
+}
+
+func TestAggregateStorage(t *testing.T) {
+	source := &sequenceStorage{values: []float64{3, 9, 1, 5, 7}}
+	storage, err := NewAggregateStorage(&config.AgentConfiguration{Aggregates: "Heap*=min,max,mean,count;HeapAlloc"}, source)
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, storage.Refresh())
+	}
+
+	metrics := storage.GetMetrics()
+	require.Len(t, metrics, 7)
+
+	labels := map[string]string{"host": "h1"}
+	expected := map[string]float64{
+		"HeapAlloc":       5,
+		"HeapAlloc_min":   1,
+		"HeapAlloc_max":   9,
+		"HeapAlloc_mean":  4.5,
+		"HeapAlloc_count": 4,
+	}
+	for id, value := range expected {
+		m := findMetric(metrics, id, labels)
+		require.NotNil(t, m, id)
+		require.Equal(t, value, *m.Value, id)
+	}
+	require.Nil(t, findMetric(metrics, "HeapAlloc_last", labels))
+	require.Nil(t, findMetric(metrics, "NumGC_max", nil))
+
+	// приращения counter, прочитанные при опросе, не теряются
+	require.Equal(t, int64(4), *findMetric(metrics, "PollCount", nil).Delta)
+	settleAll(metrics, nil)
+
+	// агрегаты считаются заново после отчета
+	require.NoError(t, storage.Refresh())
+	metrics = storage.GetMetrics()
+	require.Equal(t, 7., *findMetric(metrics, "HeapAlloc_min", labels).Value)
+	require.Equal(t, 1., *findMetric(metrics, "HeapAlloc_count", labels).Value)
+	require.Equal(t, int64(1), *findMetric(metrics, "PollCount", nil).Delta)
+}
@@ -1,6 +1,15 @@
 package agent
 
-import "context"
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrServerRejected сервер отклонил пакет метрик; повтор отправки того же пакета не поможет
+var ErrServerRejected = errors.New("server rejected metrics")
 
 //go:generate mockgen -destination "./generated_mocks_test.go" -package ${GOPACKAGE}_test . ResultSender,MetricStorage,Logger
 type ResultSender interface {
@@ -8,6 +17,29 @@ type ResultSender interface {
 	Stop()
 }
 
+// asyncSender возвращает управление из SendMetrics до окончания отправки
+// и сам сообщает результат отправки каждой метрики.
+type asyncSender interface {
+	settlesMetrics()
+}
+
+// flusher дожидается отправки принятых метрик при завершении агента.
+type flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// isRejected проверяет, что пакет отклонен сервером, а не потерян при передаче.
+func isRejected(err error) bool {
+	if st, ok := status.FromError(err); ok && st.Code() == codes.InvalidArgument {
+		return true
+	}
+	return errors.Is(err, ErrServerRejected)
+}
+
+// MetricStorage источник метрик.
+//
+// GetMetrics передает приращения counter и histogram на отправку; результат отправки
+// сообщается источнику, неотправленные приращения возвращаются в следующий GetMetrics.
 type MetricStorage interface {
 	Refresh() error
 	GetMetrics() []Metrics
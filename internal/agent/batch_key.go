@@ -0,0 +1,28 @@
+package agent
+
+import "context"
+
+type batchKeyCtxKey struct{}
+
+const (
+	// IdempotencyKeyHeader заголовок HTTP с ключом пакета метрик
+	IdempotencyKeyHeader = "X-Idempotency-Key"
+
+	// IdempotencyKeyMD ключ метаданных gRPC с ключом пакета метрик
+	IdempotencyKeyMD = "x-idempotency-key"
+)
+
+// WithBatchKey добавляет в контекст ключ пакета метрик.
+//
+// Повторные отправки пакета с тем же ключом сервер не накапливает повторно.
+func WithBatchKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, batchKeyCtxKey{}, key)
+}
+
+// BatchKey возвращает ключ пакета метрик из контекста или пустую строку.
+func BatchKey(ctx context.Context) string {
+	if key, ok := ctx.Value(batchKeyCtxKey{}).(string); ok {
+		return key
+	}
+	return ""
+}
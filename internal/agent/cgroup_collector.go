@@ -0,0 +1,260 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+)
+
+const defaultProcPath = "/proc"
+
+// pressureResources ресурсы PSI и их имена в метриках
+var pressureResources = []struct {
+	file string
+	name string
+}{
+	{"cpu", "CPU"},
+	{"memory", "Memory"},
+	{"io", "IO"},
+}
+
+// ioStatNames имена метрик для полей io.stat
+var ioStatNames = map[string]string{
+	"rbytes": "CgroupIOReadBytes",
+	"wbytes": "CgroupIOWriteBytes",
+	"rios":   "CgroupIOReadOps",
+	"wios":   "CgroupIOWriteOps",
+	"dbytes": "CgroupIODiscardBytes",
+	"dios":   "CgroupIODiscardOps",
+}
+
+// cgroupCollector собирает данные cgroup v2 и информацию о простоях из-за нехватки ресурсов (PSI).
+//
+//   - CgroupMemoryCurrent, CgroupMemoryMax - gauge из memory.current и memory.max (без ограничения не передается);
+//   - CgroupCPU<Поле> - counter из cpu.stat, например CgroupCPUUsageUsec для usage_usec;
+//   - CgroupIO<Поле> - counter из io.stat с меткой device ("8:0");
+//   - Pressure<Ресурс><Some|Full>Avg10/Avg60/Avg300 - gauge, Pressure<Ресурс><Some|Full>TotalUsec - counter
+//     из <ресурс>.pressure cgroup (метка scope=cgroup) и /proc/pressure/<ресурс> (метка scope=host).
+//
+// Отсутствующие файлы (контроллер не включен, ядро без PSI) пропускаются.
+type cgroupCollector struct {
+	cgroupPath string
+	procPath   string
+	gauges     []Metrics
+	counters   cumulativeCounters
+}
+
+func newCgroupCollector(conf *config.AgentConfiguration) (Collector, error) {
+	cgroupPath := conf.CgroupPath
+	if cgroupPath == "" {
+		cgroupPath = config.AgentDefaultCgroupPath
+	}
+	return newCgroupCollectorPaths(cgroupPath, defaultProcPath)
+}
+
+func newCgroupCollectorPaths(cgroupPath, procPath string) (*cgroupCollector, error) {
+	if _, err := os.Stat(filepath.Join(cgroupPath, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("cgroup v2 not found at %v: %w", cgroupPath, err)
+	}
+	return &cgroupCollector{
+		cgroupPath: cgroupPath,
+		procPath:   procPath,
+	}, nil
+}
+
+func (c *cgroupCollector) Refresh() error {
+	var errs []error
+	var gauges []Metrics
+
+	for _, name := range []string{"memory.current", "memory.max"} {
+		value, ok, err := readCgroupValue(filepath.Join(c.cgroupPath, name))
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if ok {
+			id := "CgroupMemoryCurrent"
+			if name == "memory.max" {
+				id = "CgroupMemoryMax"
+			}
+			gauges = append(gauges, labeledGauge(id, nil, float64(value)))
+		}
+	}
+
+	if err := c.readCPUStat(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.readIOStat(); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, res := range pressureResources {
+		for _, src := range []struct {
+			path  string
+			scope string
+		}{
+			{filepath.Join(c.cgroupPath, res.file+".pressure"), "cgroup"},
+			{filepath.Join(c.procPath, "pressure", res.file), "host"},
+		} {
+			pressure, err := c.readPressure(src.path, res.name, map[string]string{"scope": src.scope})
+			if err != nil {
+				errs = append(errs, err)
+			}
+			gauges = append(gauges, pressure...)
+		}
+	}
+
+	c.gauges = gauges
+	return errors.Join(errs...)
+}
+
+func (c *cgroupCollector) GetMetrics() []Metrics {
+	return append(cloneMetrics(c.gauges), c.counters.GetMetrics()...)
+}
+
+// readCPUStat читает строки "usage_usec 2000000"
+func (c *cgroupCollector) readCPUStat() error {
+	path := filepath.Join(c.cgroupPath, "cpu.stat")
+	return readCgroupLines(path, func(fields []string) error {
+		if len(fields) != 2 {
+			return fmt.Errorf("wrong line format")
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("wrong value %v", fields[1])
+		}
+		c.counters.Observe("CgroupCPU"+snakeToCamel(fields[0]), nil, value)
+		return nil
+	})
+}
+
+// readIOStat читает строки "8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0"
+func (c *cgroupCollector) readIOStat() error {
+	path := filepath.Join(c.cgroupPath, "io.stat")
+	return readCgroupLines(path, func(fields []string) error {
+		labels := map[string]string{"device": fields[0]}
+		for _, field := range fields[1:] {
+			k, v, ok := strings.Cut(field, "=")
+			if !ok {
+				return fmt.Errorf("wrong field %v", field)
+			}
+			id, ok := ioStatNames[k]
+			if !ok {
+				continue
+			}
+			value, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("wrong value %v", field)
+			}
+			c.counters.Observe(id, labels, value)
+		}
+		return nil
+	})
+}
+
+// readPressure читает строки "some avg10=1.50 avg60=0.75 avg300=0.25 total=1000"
+func (c *cgroupCollector) readPressure(path string, resource string, labels map[string]string) ([]Metrics, error) {
+	var gauges []Metrics
+	err := readCgroupLines(path, func(fields []string) error {
+		var prefix string
+		switch fields[0] {
+		case "some":
+			prefix = "Pressure" + resource + "Some"
+		case "full":
+			prefix = "Pressure" + resource + "Full"
+		default:
+			return fmt.Errorf("wrong line type %v", fields[0])
+		}
+
+		for _, field := range fields[1:] {
+			k, v, ok := strings.Cut(field, "=")
+			if !ok {
+				return fmt.Errorf("wrong field %v", field)
+			}
+			switch k {
+			case "avg10", "avg60", "avg300":
+				value, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return fmt.Errorf("wrong value %v", field)
+				}
+				gauges = append(gauges, labeledGauge(prefix+snakeToCamel(k), labels, value))
+			case "total":
+				value, err := strconv.ParseUint(v, 10, 64)
+				if err != nil {
+					return fmt.Errorf("wrong value %v", field)
+				}
+				c.counters.Observe(prefix+"TotalUsec", labels, value)
+			}
+		}
+		return nil
+	})
+	return gauges, err
+}
+
+// readCgroupValue читает файл с одним числом; ok == false, если файла нет или ограничение не задано ("max")
+func readCgroupValue(path string) (value uint64, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	str := string(bytes.TrimSpace(data))
+	if str == "max" {
+		return 0, false, nil
+	}
+
+	value, err = strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("%v: wrong value %v", path, str)
+	}
+	return value, true, nil
+}
+
+// readCgroupLines передает в fn поля непустых строк файла; отсутствующий файл пропускается
+func readCgroupLines(path string, fn func(fields []string) error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if err := fn(fields); err != nil {
+			return fmt.Errorf("%v: line %d: %w", path, lineNum, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// snakeToCamel преобразует имя поля "usage_usec" в "UsageUsec"
+func snakeToCamel(s string) string {
+	var sb strings.Builder
+	for _, part := range strings.Split(s, "_") {
+		if part == "" {
+			continue
+		}
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
+
+func cloneMetrics(metrics []Metrics) []Metrics {
+	return append([]Metrics(nil), metrics...)
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const cgroupTestDataDirectory = "../../testdata/cgroup"
+
+// copyFixture копирует каталог с тестовыми данными во временный каталог
+func copyFixture(t *testing.T, src string) string {
+	dst := t.TempDir()
+	err := filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0o755)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dst, rel), data, 0o644)
+	})
+	require.NoError(t, err)
+	return dst
+}
+
+func findMetric(metrics []Metrics, id string, labels map[string]string) *Metrics {
+	for i := range metrics {
+		if metrics[i].ID == id && seriesKey(id, metrics[i].Labels) == seriesKey(id, labels) {
+			return &metrics[i]
+		}
+	}
+	return nil
+}
+
+func TestCgroupCollector(t *testing.T) {
+	root := copyFixture(t, cgroupTestDataDirectory)
+	cgroupPath := filepath.Join(root, "sys", "fs", "cgroup")
+
+	c, err := newCgroupCollectorPaths(cgroupPath, filepath.Join(root, "proc"))
+	require.NoError(t, err)
+	require.NoError(t, c.Refresh())
+
+	metrics := c.GetMetrics()
+
+	gauges := map[string]float64{
+		"CgroupMemoryCurrent": 104857600,
+		"CgroupMemoryMax":     536870912,
+	}
+	for id, value := range gauges {
+		m := findMetric(metrics, id, nil)
+		require.NotNil(t, m, id)
+		require.Equal(t, GaugeType, m.MType)
+		require.Equal(t, value, *m.Value)
+	}
+
+	m := findMetric(metrics, "PressureCPUSomeAvg10", map[string]string{"scope": "cgroup"})
+	require.NotNil(t, m)
+	require.Equal(t, 1.5, *m.Value)
+
+	m = findMetric(metrics, "PressureCPUSomeAvg10", map[string]string{"scope": "host"})
+	require.NotNil(t, m)
+	require.Equal(t, 2.0, *m.Value)
+
+	m = findMetric(metrics, "PressureMemoryFullAvg300", map[string]string{"scope": "host"})
+	require.NotNil(t, m)
+	require.Equal(t, 0.15, *m.Value)
+
+	// первые накопленные значения только запоминаются
+	m = findMetric(metrics, "CgroupCPUUsageUsec", nil)
+	require.NotNil(t, m)
+	require.Equal(t, CounterType, m.MType)
+	require.Equal(t, int64(0), *m.Delta)
+
+	require.NoError(t, os.WriteFile(filepath.Join(cgroupPath, "cpu.stat"),
+		[]byte("usage_usec 2500000\nuser_usec 1500000\nsystem_usec 1000000\nnr_periods 110\nnr_throttled 10\nthrottled_usec 30000\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(cgroupPath, "io.stat"),
+		[]byte("8:0 rbytes=5096 wbytes=8192 rios=2 wios=2 dbytes=0 dios=0\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "proc", "pressure", "cpu"),
+		[]byte("some avg10=2.00 avg60=1.00 avg300=0.50 total=5600\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(cgroupPath, "memory.max"), []byte("max\n"), 0o644))
+	require.NoError(t, c.Refresh())
+
+	metrics = c.GetMetrics()
+
+	counters := []struct {
+		id     string
+		labels map[string]string
+		delta  int64
+	}{
+		{"CgroupCPUUsageUsec", nil, 500000},
+		{"CgroupCPUSystemUsec", nil, 500000},
+		{"CgroupCPUNrPeriods", nil, 10},
+		{"CgroupCPUNrThrottled", nil, 0},
+		{"CgroupIOReadBytes", map[string]string{"device": "8:0"}, 1000},
+		{"CgroupIOReadOps", map[string]string{"device": "8:0"}, 1},
+		{"PressureCPUSomeTotalUsec", map[string]string{"scope": "host"}, 600},
+		{"PressureCPUSomeTotalUsec", map[string]string{"scope": "cgroup"}, 0},
+	}
+	for _, tt := range counters {
+		m := findMetric(metrics, tt.id, tt.labels)
+		require.NotNil(t, m, tt.id)
+		require.Equal(t, CounterType, m.MType)
+		require.Equal(t, tt.delta, *m.Delta, tt.id)
+	}
+
+	// без ограничения памяти CgroupMemoryMax не передается
+	require.Nil(t, findMetric(metrics, "CgroupMemoryMax", nil))
+}
+
+func TestCgroupCollector_MissingFiles(t *testing.T) {
+	root := copyFixture(t, cgroupTestDataDirectory)
+	cgroupPath := filepath.Join(root, "sys", "fs", "cgroup")
+
+	for _, name := range []string{"io.stat", "io.pressure", "memory.max"} {
+		require.NoError(t, os.Remove(filepath.Join(cgroupPath, name)))
+	}
+
+	// ядро без PSI
+	c, err := newCgroupCollectorPaths(cgroupPath, filepath.Join(root, "noproc"))
+	require.NoError(t, err)
+	require.NoError(t, c.Refresh())
+
+	metrics := c.GetMetrics()
+	require.NotNil(t, findMetric(metrics, "CgroupMemoryCurrent", nil))
+	require.Nil(t, findMetric(metrics, "CgroupMemoryMax", nil))
+	require.Nil(t, findMetric(metrics, "PressureCPUSomeAvg10", map[string]string{"scope": "host"}))
+	require.Nil(t, findMetric(metrics, "PressureIOSomeAvg10", map[string]string{"scope": "cgroup"}))
+	require.NotNil(t, findMetric(metrics, "PressureCPUSomeAvg10", map[string]string{"scope": "cgroup"}))
+}
+
+func TestCgroupCollector_Errors(t *testing.T) {
+	root := copyFixture(t, cgroupTestDataDirectory)
+	cgroupPath := filepath.Join(root, "sys", "fs", "cgroup")
+
+	// не cgroup v2
+	_, err := newCgroupCollectorPaths(root, filepath.Join(root, "proc"))
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(cgroupPath, "cpu.stat"), []byte("usage_usec abc\n"), 0o644))
+
+	c, err := newCgroupCollectorPaths(cgroupPath, filepath.Join(root, "proc"))
+	require.NoError(t, err)
+	require.ErrorContains(t, c.Refresh(), "cpu.stat")
+
+	// ошибка в одном файле не мешает остальным
+	require.NotNil(t, findMetric(c.GetMetrics(), "CgroupMemoryCurrent", nil))
+}
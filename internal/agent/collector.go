@@ -0,0 +1,211 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// Collector сборщик группы метрик.
+//
+// Refresh вызывается с интервалом опроса сборщика, GetMetrics - при отправке отчета;
+// вызовы одного сборщика не выполняются одновременно.
+type Collector interface {
+	Refresh() error
+	GetMetrics() []Metrics
+}
+
+// CollectorFactory создает сборщик по конфигурации агента; nil без ошибки - сборщику нечего собирать.
+type CollectorFactory func(conf *config.AgentConfiguration) (Collector, error)
+
+type collectorInfo struct {
+	enabled bool // сборщик включен, если не указан в настройках
+	factory CollectorFactory
+}
+
+var (
+	collectorFactoriesMu sync.Mutex
+	collectorFactories   = map[string]collectorInfo{
+		"runtime": {enabled: true, factory: newRuntimeCollector},
+		"mem":     {enabled: true, factory: newMemCollector},
+		"cpu":     {enabled: true, factory: newCPUCollector},
+		"disk":    {enabled: true, factory: newDiskCollector},
+		"net":     {enabled: true, factory: newNetCollector},
+		"load":    {enabled: true, factory: newLoadCollector},
+		"uptime":  {enabled: true, factory: newUptimeCollector},
+		"cgroup":  {enabled: false, factory: newCgroupCollector},
+		"process": {enabled: true, factory: newProcessCollector},
+		"exec":    {enabled: true, factory: newExecCollector},
+	}
+)
+
+// RegisterCollector добавляет сборщик с именем name; enabled - сборщик включен по-умолчанию.
+func RegisterCollector(name string, enabled bool, factory CollectorFactory) {
+	collectorFactoriesMu.Lock()
+	defer collectorFactoriesMu.Unlock()
+	collectorFactories[name] = collectorInfo{enabled: enabled, factory: factory}
+}
+
+// NewCollectorStorage создает источник метрик из сборщиков, включенных в conf.Collectors.
+func NewCollectorStorage(conf *config.AgentConfiguration) (*collectorStorage, error) {
+	settings, err := config.ParseCollectors(conf.Collectors)
+	if err != nil {
+		return nil, err
+	}
+
+	collectorFactoriesMu.Lock()
+	defer collectorFactoriesMu.Unlock()
+
+	for name := range settings {
+		if _, ok := collectorFactories[name]; !ok {
+			return nil, fmt.Errorf("unknown collector %v", name)
+		}
+	}
+
+	storage := &collectorStorage{
+		pollInterval: time.Duration(conf.PollInterval) * time.Second,
+		now:          time.Now,
+	}
+
+	for name, info := range collectorFactories {
+		setting, ok := settings[name]
+		if !ok {
+			setting.Enabled = info.enabled
+		}
+		if !setting.Enabled {
+			continue
+		}
+
+		collector, err := info.factory(conf)
+		if err != nil {
+			return nil, fmt.Errorf("collector %v: %w", name, err)
+		}
+		if collector == nil {
+			continue
+		}
+		storage.entries = append(storage.entries, &collectorEntry{
+			name:         name,
+			collector:    collector,
+			pollInterval: setting.PollInterval,
+		})
+	}
+
+	sort.Slice(storage.entries, func(i, j int) bool {
+		return storage.entries[i].name < storage.entries[j].name
+	})
+	return storage, nil
+}
+
+type collectorEntry struct {
+	mu           sync.Mutex
+	name         string
+	collector    Collector
+	pollInterval time.Duration // 0 - интервал опроса агента
+	lastRefresh  time.Time
+}
+
+// collectorStorage объединяет метрики включенных сборщиков.
+//
+// Ошибка или паника сборщика не влияет на остальные; до следующего успешного опроса
+// отправляются последние собранные значения.
+type collectorStorage struct {
+	entries      []*collectorEntry
+	pollInterval time.Duration // интервал вызова Refresh агентом
+	now          func() time.Time
+}
+
+// Refresh опрашивает сборщики, у которых истек интервал опроса.
+//
+// Интервал сборщика отсчитывается тактами опроса агента, поэтому он округляется до кратного интервалу агента.
+func (cs *collectorStorage) Refresh() error {
+	now := cs.now()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(cs.entries))
+	for i, entry := range cs.entries {
+		if !cs.isDue(entry, now) {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, entry *collectorEntry) {
+			defer wg.Done()
+			errs[i] = entry.refresh(now)
+		}(i, entry)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (cs *collectorStorage) isDue(entry *collectorEntry, now time.Time) bool {
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.lastRefresh.IsZero() || entry.pollInterval == 0 {
+		return true
+	}
+	// половина такта агента - допуск на неточность таймера
+	return now.Sub(entry.lastRefresh)+cs.pollInterval/2 >= entry.pollInterval
+}
+
+func (entry *collectorEntry) refresh(now time.Time) (err error) {
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+		if err != nil {
+			logrus.Errorf("collector %v refresh error: %v", entry.name, err)
+			err = fmt.Errorf("collector %v: %w", entry.name, err)
+		}
+	}()
+
+	entry.lastRefresh = now
+	return entry.collector.Refresh()
+}
+
+func (cs *collectorStorage) GetMetrics() []Metrics {
+	var metrics []Metrics
+	for _, entry := range cs.entries {
+		metrics = append(metrics, entry.getMetrics()...)
+	}
+	return metrics
+}
+
+func (entry *collectorEntry) getMetrics() (metrics []Metrics) {
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Errorf("collector %v get metrics panic: %v", entry.name, r)
+			metrics = nil
+		}
+	}()
+
+	return entry.collector.GetMetrics()
+}
+
+// Add добавляет сборщик, созданный вне реестра, например приемник метрик.
+func (cs *collectorStorage) Add(name string, collector Collector) {
+	cs.entries = append(cs.entries, &collectorEntry{
+		name:      name,
+		collector: collector,
+	})
+}
+
+// Collectors возвращает имена включенных сборщиков.
+func (cs *collectorStorage) Collectors() []string {
+	names := make([]string, 0, len(cs.entries))
+	for _, entry := range cs.entries {
+		names = append(names, entry.name)
+	}
+	return names
+}
@@ -0,0 +1,290 @@
+package agent
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/stretchr/testify/require"
+)
+
+type testCollector struct {
+	refreshCount int
+	err          error
+	panicMsg     string
+}
+
+func (c *testCollector) Refresh() error {
+	c.refreshCount++
+	if c.panicMsg != "" {
+		panic(c.panicMsg)
+	}
+	return c.err
+}
+
+func (c *testCollector) GetMetrics() []Metrics {
+	return gaugeMetrics(map[string]float64{"RefreshCount": float64(c.refreshCount)})
+}
+
+func registerTestCollector(name string) *testCollector {
+	c := &testCollector{}
+	RegisterCollector(name, false, func(conf *config.AgentConfiguration) (Collector, error) {
+		return c, nil
+	})
+	return c
+}
+
+// onlyCollectors возвращает настройки, в которых включены только сборщики names
+func onlyCollectors(names ...string) string {
+	settings := slices.Clone(names)
+	for name, info := range collectorFactories {
+		if info.enabled && !slices.Contains(names, name) {
+			settings = append(settings, name+"=off")
+		}
+	}
+	return strings.Join(settings, ",")
+}
+
+func TestCollectorStorage_Enabled(t *testing.T) {
+	storage, err := NewCollectorStorage(&config.AgentConfiguration{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"cpu", "disk", "load", "mem", "net", "runtime", "uptime"}, storage.Collectors())
+
+	storage, err = NewCollectorStorage(&config.AgentConfiguration{Collectors: "cpu=off,disk=off,net=off,mem=10s"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"load", "mem", "runtime", "uptime"}, storage.Collectors())
+
+	_, err = NewCollectorStorage(&config.AgentConfiguration{Collectors: "unknown"})
+	require.Error(t, err)
+
+	registerTestCollector("test_enabled")
+	storage, err = NewCollectorStorage(&config.AgentConfiguration{Collectors: onlyCollectors("test_enabled")})
+	require.NoError(t, err)
+	require.Equal(t, []string{"test_enabled"}, storage.Collectors())
+}
+
+func TestCollectorStorage_PollInterval(t *testing.T) {
+	fast := registerTestCollector("test_fast")
+	slow := registerTestCollector("test_slow")
+
+	storage, err := NewCollectorStorage(&config.AgentConfiguration{
+		PollInterval: 2,
+		Collectors:   onlyCollectors("test_fast", "test_slow=6s"),
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	storage.now = func() time.Time { return now }
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, storage.Refresh())
+		// такт агента немного неточен
+		now = now.Add(2*time.Second - time.Millisecond)
+	}
+
+	require.Equal(t, 7, fast.refreshCount)
+	require.Equal(t, 3, slow.refreshCount)
+}
+
+func TestCollectorStorage_ErrorIsolation(t *testing.T) {
+	ok := registerTestCollector("test_ok")
+	failed := registerTestCollector("test_failed")
+	failed.err = errors.New("test err")
+	panicked := registerTestCollector("test_panicked")
+	panicked.panicMsg = "test panic"
+
+	storage, err := NewCollectorStorage(&config.AgentConfiguration{
+		Collectors: onlyCollectors("test_ok", "test_failed", "test_panicked"),
+	})
+	require.NoError(t, err)
+
+	err = storage.Refresh()
+	require.ErrorIs(t, err, failed.err)
+	require.ErrorContains(t, err, "test_panicked")
+	require.Equal(t, 1, ok.refreshCount)
+
+	require.Equal(t, 3, len(storage.GetMetrics()))
+}
+
+func TestSystemCollectors(t *testing.T) {
+	for name, keys := range map[string][]string{
+		"mem":    {"FreeMemory", "TotalMemory"},
+		"load":   {"LoadAverage1", "LoadAverage5", "LoadAverage15"},
+		"uptime": {"Uptime"},
+	} {
+		collector, err := collectorFactories[name].factory(&config.AgentConfiguration{})
+		require.NoError(t, err)
+		require.NoError(t, collector.Refresh())
+
+		var ids []string
+		for _, m := range collector.GetMetrics() {
+			require.Equal(t, GaugeType, m.MType)
+			ids = append(ids, m.ID)
+		}
+		require.ElementsMatch(t, keys, ids)
+	}
+}
+
+func TestCPUCollector(t *testing.T) {
+	collector, err := newCPUCollector(&config.AgentConfiguration{})
+	require.NoError(t, err)
+	require.NoError(t, collector.Refresh())
+
+	ids := make(map[string]bool)
+	for _, m := range collector.GetMetrics() {
+		require.Equal(t, GaugeType, m.MType)
+		ids[m.ID] = true
+	}
+	require.True(t, ids["CPUutilization1"])
+	require.True(t, ids["CPUutilizationTotal"])
+}
+
+func TestNetCollector(t *testing.T) {
+	collector, err := newNetCollector(&config.AgentConfiguration{})
+	require.NoError(t, err)
+
+	// первое значение только запоминается
+	require.NoError(t, collector.Refresh())
+	require.NoError(t, collector.Refresh())
+
+	for _, m := range collector.GetMetrics() {
+		require.Equal(t, CounterType, m.MType)
+		require.NotEmpty(t, m.Labels["interface"])
+		require.GreaterOrEqual(t, *m.Delta, int64(0))
+	}
+}
+
+func TestDiskCollector(t *testing.T) {
+	partitions := []disk.PartitionStat{
+		{Device: "/dev/sda1", Mountpoint: "/"},
+		{Device: "/dev/sdb1", Mountpoint: "/data"},
+		{Device: "tmpfs", Mountpoint: "/tmp"},
+	}
+	errTest := errors.New("test err")
+
+	testCases := []struct {
+		name          string
+		partitionsErr error
+		usageErr      map[string]error
+		ioErr         map[string]error
+		gaugeMounts   []string
+		ioDevices     []string
+		errContains   []string
+	}{
+		{
+			name:        "ok",
+			gaugeMounts: []string{"/", "/data", "/tmp"},
+			ioDevices:   []string{"sda1", "sdb1"},
+		},
+		{
+			name:        "usage_error",
+			usageErr:    map[string]error{"/data": errTest},
+			gaugeMounts: []string{"/", "/tmp"},
+			ioDevices:   []string{"sda1", "sdb1"},
+			errContains: []string{"mount /data: test err"},
+		},
+		{
+			name:        "io_error",
+			ioErr:       map[string]error{"sda1": errTest},
+			gaugeMounts: []string{"/", "/data", "/tmp"},
+			ioDevices:   []string{"sdb1"},
+			errContains: []string{"device sda1: test err"},
+		},
+		{
+			name:        "usage_and_io_errors",
+			usageErr:    map[string]error{"/": errTest},
+			ioErr:       map[string]error{"sdb1": errTest},
+			gaugeMounts: []string{"/data", "/tmp"},
+			ioDevices:   []string{"sda1"},
+			errContains: []string{"mount /: test err", "device sdb1: test err"},
+		},
+		{
+			name:          "partitions_error",
+			partitionsErr: errTest,
+			errContains:   []string{"test err"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var ioValue uint64
+			c := &diskCollector{
+				partitions: func(all bool) ([]disk.PartitionStat, error) {
+					return partitions, test.partitionsErr
+				},
+				usage: func(path string) (*disk.UsageStat, error) {
+					if err := test.usageErr[path]; err != nil {
+						return nil, err
+					}
+					return &disk.UsageStat{Total: 100, Used: 40, Free: 60, UsedPercent: 40}, nil
+				},
+				ioCounters: func(names ...string) (map[string]disk.IOCountersStat, error) {
+					require.Len(t, names, 1)
+					if err := test.ioErr[names[0]]; err != nil {
+						return nil, err
+					}
+					if names[0] == "tmpfs" {
+						return map[string]disk.IOCountersStat{}, nil
+					}
+					return map[string]disk.IOCountersStat{
+						names[0]: {ReadBytes: ioValue, WriteBytes: ioValue, ReadCount: ioValue, WriteCount: ioValue},
+					}, nil
+				},
+			}
+
+			// первое значение счетчиков только запоминается
+			for ioValue = 10; ioValue <= 20; ioValue += 10 {
+				err := c.Refresh()
+				if len(test.errContains) == 0 {
+					require.NoError(t, err)
+				}
+				for _, msg := range test.errContains {
+					require.ErrorContains(t, err, msg)
+				}
+			}
+
+			var gaugeMounts, ioDevices []string
+			for _, m := range c.GetMetrics() {
+				switch m.MType {
+				case GaugeType:
+					if m.ID == "DiskTotal" {
+						gaugeMounts = append(gaugeMounts, m.Labels["mount"])
+					}
+				case CounterType:
+					require.Equal(t, int64(10), *m.Delta)
+					if m.ID == "DiskReadBytes" {
+						ioDevices = append(ioDevices, m.Labels["device"])
+					}
+				}
+			}
+			require.ElementsMatch(t, test.gaugeMounts, gaugeMounts)
+			require.ElementsMatch(t, test.ioDevices, ioDevices)
+		})
+	}
+}
+
+func TestCumulativeCounters(t *testing.T) {
+	var c cumulativeCounters
+	labels := map[string]string{"interface": "eth0"}
+
+	c.Observe("NetBytesSent", labels, 100)
+	metrics := c.GetMetrics()
+	require.Equal(t, 1, len(metrics))
+	require.Equal(t, int64(0), *metrics[0].Delta)
+
+	c.Observe("NetBytesSent", labels, 150)
+	c.Observe("NetBytesSent", labels, 170)
+	metrics = c.GetMetrics()
+	require.Equal(t, int64(70), *metrics[0].Delta)
+	require.Equal(t, labels, metrics[0].Labels)
+
+	// ошибка отправки - приращение уходит со следующим отчетом; сброс счетчика - приращение от нуля
+	metrics[0].settle(errors.New("test err"))
+	c.Observe("NetBytesSent", labels, 5)
+	metrics = c.GetMetrics()
+	require.Equal(t, int64(75), *metrics[0].Delta)
+}
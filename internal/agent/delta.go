@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SentFn получает результат отправки метрики; err == nil - метрика принята сервером.
+type SentFn func(err error)
+
+// counterDelta накапливает приращение счетчика до подтверждения отправки.
+//
+// Сервер суммирует полученные значения counter, поэтому агент передает только приращение,
+// еще не принятое сервером. Приращение неудачной отправки возвращается и уходит со следующим отчетом.
+// После перезапуска агента счет начинается с нуля - уже принятые сервером значения не передаются повторно.
+type counterDelta struct {
+	mu      sync.Mutex
+	pending int64 // приращение, еще не переданное на отправку
+}
+
+func (c *counterDelta) Add(delta int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending += delta
+}
+
+// Take забирает накопленное приращение на отправку; sent возвращает приращение при ошибке отправки.
+func (c *counterDelta) Take() (delta int64, sent SentFn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delta = c.pending
+	c.pending = 0
+	return delta, onceSent(func(err error) {
+		if err != nil {
+			c.Add(delta)
+		}
+	})
+}
+
+// histogramDelta накапливает наблюдения гистограммы до подтверждения отправки.
+type histogramDelta struct {
+	mu      sync.Mutex
+	bounds  []float64
+	pending *Histogram
+}
+
+func newHistogramDelta(bounds []float64) *histogramDelta {
+	pending := NewHistogram(bounds)
+	return &histogramDelta{
+		bounds:  pending.Bounds,
+		pending: pending,
+	}
+}
+
+func (h *histogramDelta) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pending.Observe(v)
+}
+
+// Take забирает накопленные наблюдения на отправку; sent возвращает их при ошибке отправки.
+func (h *histogramDelta) Take() (delta *Histogram, sent SentFn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delta = h.pending
+	h.pending = NewHistogram(h.bounds)
+	return delta, onceSent(func(err error) {
+		if err != nil {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			h.pending.merge(delta)
+		}
+	})
+}
+
+// add добавляет наблюдения другой гистограммы с теми же границами корзин.
+func (h *histogramDelta) add(other *Histogram) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pending.merge(other)
+}
+
+// merge добавляет наблюдения гистограммы с теми же границами корзин.
+func (h *Histogram) merge(other *Histogram) {
+	if !slices.Equal(h.Bounds, other.Bounds) {
+		return
+	}
+	for i := range other.Counts {
+		h.Counts[i] += other.Counts[i]
+	}
+	h.Sum += other.Sum
+	h.Count += other.Count
+}
+
+// onceSent гарантирует однократную обработку результата отправки.
+func onceSent(fn SentFn) SentFn {
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			fn(err)
+		})
+	}
+}
+
+// cumulativeCounters преобразует накопленные с момента запуска системы значения в приращения counter.
+//
+// Первое значение ряда только запоминается: после перезапуска агента уже учтенные сервером значения
+// не передаются повторно. Уменьшение значения (сброс счетчика) считается приращением от нуля.
+type cumulativeCounters struct {
+	series map[string]*cumulativeSeries
+}
+
+type cumulativeSeries struct {
+	id     string
+	labels map[string]string
+	last   uint64
+	delta  counterDelta
+}
+
+// Observe учитывает очередное накопленное значение ряда id с метками labels.
+func (c *cumulativeCounters) Observe(id string, labels map[string]string, value uint64) {
+	if c.series == nil {
+		c.series = make(map[string]*cumulativeSeries)
+	}
+
+	key := seriesKey(id, labels)
+	series, ok := c.series[key]
+	if !ok {
+		c.series[key] = &cumulativeSeries{id: id, labels: labels, last: value}
+		return
+	}
+
+	delta := value
+	if value >= series.last {
+		delta = value - series.last
+	}
+	series.last = value
+	if delta > math.MaxInt64 {
+		delta = math.MaxInt64
+	}
+	series.delta.Add(int64(delta))
+}
+
+// GetMetrics передает накопленные приращения на отправку.
+func (c *cumulativeCounters) GetMetrics() []Metrics {
+	metrics := make([]Metrics, 0, len(c.series))
+	for _, series := range c.series {
+		delta, sent := series.delta.Take()
+		metrics = append(metrics, Metrics{
+			ID:     series.id,
+			MType:  CounterType,
+			Delta:  &delta,
+			Labels: series.labels,
+			sent:   sent,
+		})
+	}
+	return metrics
+}
+
+func seriesKey(id string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(id)
+	for _, k := range keys {
+		sb.WriteString("," + k + "=" + labels[k])
+	}
+	return sb.String()
+}
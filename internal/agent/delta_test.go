@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/storage/memory"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/stretchr/testify/require"
+)
+
+type serverStorage interface {
+	AddMetrics(ctx context.Context, metric []domain.Metrics) error
+	Get(ctx context.Context, id string, labels domain.Labels, mType domain.MetricType) (*domain.Metrics, error)
+}
+
+// storageSender сохраняет приращения так же, как сервер - через AddMetrics
+type storageSender struct {
+	mu      sync.Mutex
+	storage serverStorage
+	fail    bool
+}
+
+func (s *storageSender) setFail(fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+func (s *storageSender) SendMetrics(ctx context.Context, metrics []Metrics) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("test err")
+	}
+
+	var deltas []domain.Metrics
+	for _, m := range metrics {
+		dm := domain.Metrics{ID: m.ID, MType: domain.MetricType(m.MType), Delta: m.Delta}
+		if m.Histogram != nil {
+			dm.Histogram = &domain.Histogram{
+				Bounds: m.Histogram.Bounds,
+				Counts: m.Histogram.Counts,
+				Sum:    m.Histogram.Sum,
+				Count:  m.Histogram.Count,
+			}
+		}
+		if m.MType != GaugeType {
+			deltas = append(deltas, dm)
+		}
+	}
+	return s.storage.AddMetrics(ctx, deltas)
+}
+
+func (s *storageSender) Stop() {}
+
+func (s *storageSender) pollCount(ctx context.Context) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, err := s.storage.Get(ctx, "PollCount", nil, domain.CounterType)
+	if err != nil {
+		return 0
+	}
+	return *m.Delta
+}
+
+func refresh(t *testing.T, src MetricStorage, n int) {
+	for i := 0; i < n; i++ {
+		require.NoError(t, src.Refresh())
+	}
+}
+
+func TestCounterDelta(t *testing.T) {
+	ctx := context.Background()
+	sender := &storageSender{storage: memory.NewStorage()}
+	conf := &config.AgentConfiguration{}
+
+	src := NewMemStatsStorage(conf)
+	a := Create(conf, sender, src)
+
+	refresh(t, src, 3)
+	a.report(ctx)
+	require.Equal(t, int64(3), sender.pollCount(ctx))
+
+	// повторный отчет без опросов ничего не добавляет
+	a.report(ctx)
+	require.Equal(t, int64(3), sender.pollCount(ctx))
+
+	// приращение неудачной отправки уходит со следующим отчетом
+	refresh(t, src, 2)
+	sender.setFail(true)
+	a.report(ctx)
+	sender.setFail(false)
+	require.Equal(t, int64(3), sender.pollCount(ctx))
+
+	refresh(t, src, 1)
+	a.report(ctx)
+	require.Equal(t, int64(6), sender.pollCount(ctx))
+
+	// после перезапуска агента передаются только новые опросы
+	src = NewMemStatsStorage(conf)
+	a = Create(conf, sender, src)
+
+	refresh(t, src, 2)
+	a.report(ctx)
+	require.Equal(t, int64(8), sender.pollCount(ctx))
+}
+
+func TestCounterDelta_Pool(t *testing.T) {
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	sender := &storageSender{storage: memory.NewStorage()}
+	conf := &config.AgentConfiguration{
+		BatchSize: 1,
+		RateLimit: 1,
+	}
+
+	src := NewMemStatsStorage(conf)
+	a := Create(conf, NewPoolResultSender(conf, sender), src)
+
+	// отправку подтверждает рабочий пула, а не возврат из SendMetrics
+	sender.setFail(true)
+	refresh(t, src, 3)
+	a.report(ctx)
+
+	require.Eventually(t, func() bool {
+		src.pollCount.mu.Lock()
+		defer src.pollCount.mu.Unlock()
+		return src.pollCount.pending == 3
+	}, time.Second, 10*time.Millisecond)
+
+	sender.setFail(false)
+	refresh(t, src, 1)
+	a.report(ctx)
+
+	require.Eventually(t, func() bool {
+		return sender.pollCount(ctx) == 4
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestCounterDelta_InFlight(t *testing.T) {
+	var c counterDelta
+
+	c.Add(2)
+	first, firstSent := c.Take()
+	require.Equal(t, int64(2), first)
+
+	// следующий отчет не включает приращение, которое еще отправляется
+	c.Add(1)
+	second, secondSent := c.Take()
+	require.Equal(t, int64(1), second)
+
+	firstSent(errors.New("test err"))
+	firstSent(errors.New("test err")) // результат учитывается один раз
+	secondSent(nil)
+
+	third, _ := c.Take()
+	require.Equal(t, int64(2), third)
+}
+
+func TestHistogramDelta(t *testing.T) {
+	h := newHistogramDelta([]float64{1})
+
+	h.Observe(0.5)
+	h.Observe(2)
+	delta, sent := h.Take()
+	require.Equal(t, int64(2), delta.Count)
+
+	h.Observe(0.5)
+	sent(errors.New("test err"))
+
+	delta, _ = h.Take()
+	require.Equal(t, []int64{2, 1}, delta.Counts)
+	require.Equal(t, int64(3), delta.Count)
+	require.Equal(t, 3.0, delta.Sum)
+}
+
+func TestNewHistogram(t *testing.T) {
+	h := NewHistogram([]float64{1, 0.1, 1, math.NaN(), math.Inf(1), math.Inf(-1)})
+	require.Equal(t, []float64{0.1, 1}, h.Bounds)
+	require.Len(t, h.Counts, 3)
+	require.NoError(t, h.Check())
+}
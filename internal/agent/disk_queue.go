@@ -0,0 +1,424 @@
+package agent
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queueSegmentExt  = ".seg"
+	queueCursorFile  = "cursor"
+	queueRecordHead  = 8 // длина (4 байта) и crc32 (4 байта) записи
+	queueDirPerm     = 0o700
+	queueFilePerm    = 0o600
+	queueMaxRecordSz = 1 << 30
+)
+
+var (
+	ErrQueueFull   = errors.New("send queue is full")
+	errQueueEmpty  = errors.New("send queue is empty")
+	errQueueClosed = errors.New("send queue is closed")
+)
+
+// queueSegment файл очереди; имя файла - номер сегмента
+type queueSegment struct {
+	seq  int64
+	size int64
+}
+
+// queueCursor позиция первой неподтвержденной записи
+type queueCursor struct {
+	seq    int64
+	offset int64
+}
+
+// diskQueue очередь записей на диске.
+//
+// Записи добавляются в конец последнего сегмента; при превышении размера сегмента создается новый.
+// Каждая запись сохраняется на диск (fsync) до возврата из Push. Позиция первой неподтвержденной
+// записи хранится в файле cursor, прочитанные сегменты удаляются.
+// При открытии очереди поврежденный конец сегмента (агент завершился во время записи) отбрасывается.
+//
+// Очередь рассчитана на одного читателя: Peek возвращает первую запись, Ack подтверждает ее обработку.
+type diskQueue struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+
+	mu       sync.Mutex
+	segments []queueSegment
+	size     int64 // суммарный размер сегментов
+	count    int   // количество неподтвержденных записей
+	head     queueCursor
+	next     int64 // позиция записи после возвращенной Peek
+	w        *os.File
+	closed   bool
+}
+
+func openDiskQueue(dir string, maxSize, segmentSize int64) (*diskQueue, error) {
+	if err := os.MkdirAll(dir, queueDirPerm); err != nil {
+		return nil, fmt.Errorf("create queue dir err %w", err)
+	}
+
+	q := &diskQueue{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: segmentSize,
+	}
+
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *diskQueue) segmentPath(seq int64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, queueSegmentExt))
+}
+
+func (q *diskQueue) load() error {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return fmt.Errorf("read queue dir err %w", err)
+	}
+
+	var seqs []int64
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), queueSegmentExt)
+		if !ok {
+			continue
+		}
+		seq, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+
+	if err := q.readCursor(); err != nil {
+		return err
+	}
+
+	for _, seq := range seqs {
+		if seq < q.head.seq {
+			// сегмент прочитан, но не удален до завершения агента
+			if err := os.Remove(q.segmentPath(seq)); err != nil {
+				return fmt.Errorf("remove queue segment err %w", err)
+			}
+			continue
+		}
+
+		size, count, err := q.scanSegment(seq)
+		if err != nil {
+			return err
+		}
+		q.segments = append(q.segments, queueSegment{seq: seq, size: size})
+		q.size += size
+		q.count += count
+	}
+
+	last := q.head.seq + 1
+	if len(q.segments) > 0 {
+		last = q.segments[len(q.segments)-1].seq
+	}
+	if err := q.openWriter(last); err != nil {
+		return err
+	}
+
+	first := q.segments[0]
+	if first.seq != q.head.seq {
+		// сегмент курсора удален - чтение с начала первого сегмента
+		q.head = queueCursor{seq: first.seq}
+	}
+	// конец сегмента мог быть отброшен как поврежденный
+	q.head.offset = min(q.head.offset, first.size)
+	return nil
+}
+
+// scanSegment проверяет записи сегмента и отбрасывает поврежденный конец.
+// Возвращает размер сегмента и количество записей после позиции курсора.
+func (q *diskQueue) scanSegment(seq int64) (int64, int, error) {
+	f, err := os.OpenFile(q.segmentPath(seq), os.O_RDWR, queueFilePerm)
+	if err != nil {
+		return 0, 0, fmt.Errorf("open queue segment err %w", err)
+	}
+	defer f.Close()
+
+	var offset int64
+	var count int
+	for {
+		_, size, err := readQueueRecord(f, offset)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			logrus.Warnf("queue segment %v truncated at %d: %v", seq, offset, err)
+			if err := f.Truncate(offset); err != nil {
+				return 0, 0, fmt.Errorf("truncate queue segment err %w", err)
+			}
+			if err := f.Sync(); err != nil {
+				return 0, 0, fmt.Errorf("sync queue segment err %w", err)
+			}
+			break
+		}
+		if seq > q.head.seq || offset >= q.head.offset {
+			count++
+		}
+		offset += size
+	}
+	return offset, count, nil
+}
+
+// readQueueRecord читает запись по смещению; возвращает данные и полный размер записи.
+// io.EOF - записей больше нет.
+func readQueueRecord(r io.ReaderAt, offset int64) ([]byte, int64, error) {
+	var header [queueRecordHead]byte
+	n, err := r.ReadAt(header[:], offset)
+	if n == 0 && errors.Is(err, io.EOF) {
+		return nil, 0, io.EOF
+	}
+	if n < queueRecordHead {
+		return nil, 0, fmt.Errorf("short record header: %w", io.ErrUnexpectedEOF)
+	}
+
+	length := binary.BigEndian.Uint32(header[:4])
+	if length > queueMaxRecordSz {
+		return nil, 0, fmt.Errorf("wrong record length %d", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := r.ReadAt(data, offset+queueRecordHead); err != nil {
+		return nil, 0, fmt.Errorf("short record: %w", io.ErrUnexpectedEOF)
+	}
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, 0, errors.New("record checksum mismatch")
+	}
+	return data, queueRecordHead + int64(length), nil
+}
+
+func (q *diskQueue) readCursor() error {
+	data, err := os.ReadFile(filepath.Join(q.dir, queueCursorFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read queue cursor err %w", err)
+	}
+	if _, err := fmt.Sscanf(string(data), "%d %d", &q.head.seq, &q.head.offset); err != nil {
+		logrus.Warnf("wrong queue cursor %q, queue is read from the beginning", data)
+		q.head = queueCursor{}
+	}
+	return nil
+}
+
+// writeCursor сохраняет позицию через временный файл, чтобы не оставить файл недописанным.
+func (q *diskQueue) writeCursor() error {
+	tmp := filepath.Join(q.dir, queueCursorFile+".tmp")
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, queueFilePerm)
+	if err != nil {
+		return fmt.Errorf("write queue cursor err %w", err)
+	}
+	_, err = fmt.Fprintf(f, "%d %d", q.head.seq, q.head.offset)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("write queue cursor err %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(q.dir, queueCursorFile)); err != nil {
+		return fmt.Errorf("write queue cursor err %w", err)
+	}
+	return nil
+}
+
+// openWriter открывает сегмент для записи; новый сегмент добавляется в список.
+func (q *diskQueue) openWriter(seq int64) error {
+	f, err := os.OpenFile(q.segmentPath(seq), os.O_WRONLY|os.O_CREATE|os.O_APPEND, queueFilePerm)
+	if err != nil {
+		return fmt.Errorf("open queue segment err %w", err)
+	}
+	if len(q.segments) == 0 || q.segments[len(q.segments)-1].seq != seq {
+		q.segments = append(q.segments, queueSegment{seq: seq})
+		if err := syncDir(q.dir); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	q.w = f
+	return nil
+}
+
+func (q *diskQueue) roll() error {
+	if err := q.w.Close(); err != nil {
+		return fmt.Errorf("close queue segment err %w", err)
+	}
+	return q.openWriter(q.segments[len(q.segments)-1].seq + 1)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("sync queue dir err %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("sync queue dir err %w", err)
+	}
+	return nil
+}
+
+// Push добавляет запись в конец очереди; ErrQueueFull - превышен максимальный размер очереди.
+func (q *diskQueue) Push(data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return errQueueClosed
+	}
+
+	recordSize := queueRecordHead + int64(len(data))
+	if len(data) > queueMaxRecordSz || q.size+recordSize > q.maxSize {
+		return ErrQueueFull
+	}
+
+	last := &q.segments[len(q.segments)-1]
+	if last.size > 0 && last.size+recordSize > q.segmentSize {
+		if err := q.roll(); err != nil {
+			return err
+		}
+		last = &q.segments[len(q.segments)-1]
+	}
+
+	record := make([]byte, queueRecordHead, recordSize)
+	binary.BigEndian.PutUint32(record[:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(record[4:], crc32.ChecksumIEEE(data))
+	record = append(record, data...)
+
+	if _, err := q.w.Write(record); err != nil {
+		// недописанная запись отбрасывается, чтобы не повредить следующие
+		q.w.Truncate(last.size)
+		return fmt.Errorf("write queue segment err %w", err)
+	}
+	if err := q.w.Sync(); err != nil {
+		q.w.Truncate(last.size)
+		return fmt.Errorf("sync queue segment err %w", err)
+	}
+
+	last.size += recordSize
+	q.size += recordSize
+	q.count++
+	return nil
+}
+
+// Peek возвращает первую неподтвержденную запись; errQueueEmpty - очередь пуста.
+func (q *diskQueue) Peek() ([]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, errQueueClosed
+	}
+	if q.count == 0 {
+		return nil, errQueueEmpty
+	}
+
+	if err := q.skipSegment(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(q.segmentPath(q.head.seq))
+	if err != nil {
+		return nil, fmt.Errorf("open queue segment err %w", err)
+	}
+	defer f.Close()
+
+	data, size, err := readQueueRecord(f, q.head.offset)
+	if err != nil {
+		return nil, fmt.Errorf("read queue segment %v err %w", q.head.seq, err)
+	}
+	q.next = q.head.offset + size
+	return data, nil
+}
+
+// Ack подтверждает обработку записи, возвращенной Peek.
+func (q *diskQueue) Ack() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return errQueueClosed
+	}
+	if q.next <= q.head.offset {
+		return nil
+	}
+
+	q.head.offset = q.next
+	q.count--
+	if err := q.skipSegment(); err != nil {
+		return err
+	}
+	// при сбое до сохранения курсора запись будет обработана повторно
+	return q.writeCursor()
+}
+
+// skipSegment удаляет прочитанные сегменты в начале очереди и переводит позицию на следующий сегмент.
+// Если прочитан последний сегмент, запись продолжается в новом.
+func (q *diskQueue) skipSegment() error {
+	for {
+		head := q.segments[0]
+		if q.head.offset < head.size {
+			return nil
+		}
+
+		if len(q.segments) == 1 {
+			if head.size == 0 {
+				return nil
+			}
+			if err := q.roll(); err != nil {
+				return err
+			}
+		}
+
+		// при сбое до сохранения курсора позиция переводится на первый сегмент при открытии очереди
+		if err := os.Remove(q.segmentPath(head.seq)); err != nil {
+			return fmt.Errorf("remove queue segment err %w", err)
+		}
+		q.segments = q.segments[1:]
+		q.size -= head.size
+		q.head = queueCursor{seq: q.segments[0].seq}
+		q.next = 0
+	}
+}
+
+// Len возвращает количество неподтвержденных записей.
+func (q *diskQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.count
+}
+
+func (q *diskQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	return q.w.Close()
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func pushRecords(t *testing.T, q *diskQueue, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		require.NoError(t, q.Push([]byte(fmt.Sprintf("record-%d", i))))
+	}
+}
+
+func popRecord(t *testing.T, q *diskQueue) string {
+	t.Helper()
+	data, err := q.Peek()
+	require.NoError(t, err)
+	require.NoError(t, q.Ack())
+	return string(data)
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+queueSegmentExt))
+	require.NoError(t, err)
+	return files
+}
+
+func TestDiskQueue(t *testing.T) {
+	dir := t.TempDir()
+
+	// запись "record-N" занимает 16 байт, в сегмент помещается 4 записи
+	q, err := openDiskQueue(dir, 1<<20, 64)
+	require.NoError(t, err)
+
+	_, err = q.Peek()
+	require.ErrorIs(t, err, errQueueEmpty)
+
+	pushRecords(t, q, 0, 10)
+	require.Equal(t, 10, q.Len())
+	require.Len(t, segmentFiles(t, dir), 3)
+
+	// Peek без Ack возвращает ту же запись
+	data, err := q.Peek()
+	require.NoError(t, err)
+	require.Equal(t, "record-0", string(data))
+
+	for i := 0; i < 4; i++ {
+		require.Equal(t, fmt.Sprintf("record-%d", i), popRecord(t, q))
+	}
+	require.Len(t, segmentFiles(t, dir), 2)
+	require.NoError(t, q.Close())
+
+	// очередь продолжается после перезапуска
+	q, err = openDiskQueue(dir, 1<<20, 64)
+	require.NoError(t, err)
+	require.Equal(t, 6, q.Len())
+
+	pushRecords(t, q, 10, 12)
+	for i := 4; i < 12; i++ {
+		require.Equal(t, fmt.Sprintf("record-%d", i), popRecord(t, q))
+	}
+	_, err = q.Peek()
+	require.ErrorIs(t, err, errQueueEmpty)
+	require.Len(t, segmentFiles(t, dir), 1)
+	require.NoError(t, q.Close())
+
+	q, err = openDiskQueue(dir, 1<<20, 64)
+	require.NoError(t, err)
+	require.Equal(t, 0, q.Len())
+	require.NoError(t, q.Close())
+}
+
+func TestDiskQueue_Full(t *testing.T) {
+	q, err := openDiskQueue(t.TempDir(), 100, 64)
+	require.NoError(t, err)
+	defer q.Close()
+
+	pushRecords(t, q, 0, 6)
+	require.ErrorIs(t, q.Push([]byte("record-6")), ErrQueueFull)
+	require.Equal(t, 6, q.Len())
+
+	// место освобождается после удаления прочитанного сегмента
+	for i := 0; i < 4; i++ {
+		popRecord(t, q)
+	}
+	require.NoError(t, q.Push([]byte("record-6")))
+}
+
+func TestDiskQueue_TruncatedRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := openDiskQueue(dir, 1<<20, 1<<10)
+	require.NoError(t, err)
+	pushRecords(t, q, 0, 3)
+	require.NoError(t, q.Close())
+
+	// агент завершился во время записи
+	files := segmentFiles(t, dir)
+	require.Len(t, files, 1)
+	f, err := os.OpenFile(files[0], os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 20, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	q, err = openDiskQueue(dir, 1<<20, 1<<10)
+	require.NoError(t, err)
+	defer q.Close()
+	require.Equal(t, 3, q.Len())
+
+	pushRecords(t, q, 3, 4)
+	for i := 0; i < 4; i++ {
+		require.Equal(t, fmt.Sprintf("record-%d", i), popRecord(t, q))
+	}
+}
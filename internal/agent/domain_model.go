@@ -1,15 +1,110 @@
 package agent
 
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+)
+
+// допустимые имена метрики и метки, как на сервере
+var (
+	metricNameRegexp = regexp.MustCompile("^[a-zA-Z][a-zA-Z0-9_]*$")
+	labelNameRegexp  = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+)
+
 type MetricType string
 
 type Metrics struct {
-	ID    string     `json:"id"`              // имя метрики
-	MType MetricType `json:"type"`            // параметр, принимающий значение gauge или counter
-	Delta *int64     `json:"delta,omitempty"` // значение метрики в случае передачи counter
-	Value *float64   `json:"value,omitempty"` // значение метрики в случае передачи gauge
+	ID        string            `json:"id"`                  // имя метрики
+	MType     MetricType        `json:"type"`                // параметр, принимающий значение gauge, counter или histogram
+	Delta     *int64            `json:"delta,omitempty"`     // значение метрики в случае передачи counter
+	Value     *float64          `json:"value,omitempty"`     // значение метрики в случае передачи gauge
+	Histogram *Histogram        `json:"histogram,omitempty"` // значение метрики в случае передачи histogram
+	Labels    map[string]string `json:"labels,omitempty"`    // метки метрики
+
+	sent SentFn // результат отправки приращения; nil для gauge
+}
+
+// settle сообщает источнику метрики результат отправки.
+func (m *Metrics) settle(err error) {
+	if m.sent != nil {
+		m.sent(err)
+	}
+}
+
+func settleAll(metrics []Metrics, err error) {
+	for i := range metrics {
+		metrics[i].settle(err)
+	}
+}
+
+// Histogram распределение наблюдений по корзинам; последний элемент Counts - корзина +Inf.
+type Histogram struct {
+	Bounds []float64 `json:"bounds"` // верхние границы корзин
+	Counts []int64   `json:"counts"` // количество наблюдений в каждой корзине
+	Sum    float64   `json:"sum"`    // сумма наблюдений
+	Count  int64     `json:"count"`  // общее количество наблюдений
+}
+
+// NewHistogram создает пустую гистограмму с заданными границами корзин.
+// Границы сортируются; повторы, NaN и ±Inf пропускаются - сервер принимает только строго возрастающие конечные границы.
+func NewHistogram(bounds []float64) *Histogram {
+	b := make([]float64, 0, len(bounds))
+	for _, bound := range bounds {
+		if !math.IsNaN(bound) && !math.IsInf(bound, 0) {
+			b = append(b, bound)
+		}
+	}
+	sort.Float64s(b)
+	b = slices.Compact(b)
+	return &Histogram{
+		Bounds: b,
+		Counts: make([]int64, len(b)+1),
+	}
+}
+
+// Observe добавляет наблюдение в гистограмму.
+func (h *Histogram) Observe(v float64) {
+	idx := sort.SearchFloat64s(h.Bounds, v)
+	h.Counts[idx]++
+	h.Sum += v
+	h.Count++
+}
+
+// Check проверяет гистограмму по тем же правилам, что и сервер.
+func (h *Histogram) Check() error {
+	if len(h.Counts) != len(h.Bounds)+1 {
+		return fmt.Errorf("histogram has %d bounds and %d counts", len(h.Bounds), len(h.Counts))
+	}
+
+	for i, b := range h.Bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			return errors.New("histogram bound is not finite")
+		}
+		if i > 0 && b <= h.Bounds[i-1] {
+			return errors.New("histogram bounds are not strictly increasing")
+		}
+	}
+
+	var total int64
+	for _, c := range h.Counts {
+		if c < 0 {
+			return errors.New("histogram has negative bucket count")
+		}
+		total += c
+	}
+
+	if total != h.Count {
+		return fmt.Errorf("histogram count %d doesn't match bucket counts sum %d", h.Count, total)
+	}
+	return nil
 }
 
 const (
-	GaugeType   MetricType = "gauge"
-	CounterType MetricType = "counter"
+	GaugeType     MetricType = "gauge"
+	CounterType   MetricType = "counter"
+	HistogramType MetricType = "histogram"
 )
@@ -0,0 +1,304 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	execMaxOutput = 1 << 20 // сохраняемый объем stdout и stderr команды
+	execWaitDelay = time.Second
+)
+
+// execCommand команда и ее состояние между запусками
+type execCommand struct {
+	config.ExecCommandConf
+
+	gauges   map[string]float64
+	counters map[string]*counterDelta
+
+	// метрики выполнения команды
+	exitCode    float64
+	duration    float64
+	stderrLines counterDelta
+	timeouts    counterDelta
+	parseErrors counterDelta
+}
+
+// execCollector выполняет команды и разбирает их stdout - строки "имя тип значение":
+//
+//	queue_size gauge 12.5
+//	jobs_done counter 3
+//
+// Тип - gauge или counter; значение counter - приращение. Пустые строки и строки с '#' в начале пропускаются.
+// Метрики команд передаются без изменений, поэтому имя метрики принадлежит первой по списку команде, которая его
+// вывела; та же метрика от другой команды отбрасывается и учитывается как ошибка разбора.
+// По истечении времени выполнения завершаются команда и запущенные ею процессы.
+//
+// Метрики выполнения с меткой command: ExecExitCode (-1, если команда не запустилась или превысила время выполнения),
+// ExecDurationSeconds - gauge; ExecStderrLines, ExecTimeouts, ExecParseErrors - counter.
+type execCollector struct {
+	commands    []*execCommand
+	timeout     time.Duration
+	concurrency int
+	owners      map[string]*execCommand // ключ - тип и имя метрики
+}
+
+func newExecCollector(conf *config.AgentConfiguration) (Collector, error) {
+	commands, err := config.ParseExecCommands(conf.ExecCommands)
+	if err != nil {
+		return nil, err
+	}
+	if len(commands) == 0 {
+		return nil, nil
+	}
+
+	c := &execCollector{
+		timeout:     time.Duration(conf.ExecTimeout) * time.Second,
+		concurrency: conf.ExecConcurrency,
+		owners:      make(map[string]*execCommand),
+	}
+	if c.timeout <= 0 {
+		c.timeout = config.AgentDefaultExecTimeout * time.Second
+	}
+	if c.concurrency <= 0 {
+		c.concurrency = config.AgentDefaultExecConcurrent
+	}
+
+	for _, command := range commands {
+		c.commands = append(c.commands, &execCommand{
+			ExecCommandConf: command,
+			counters:        make(map[string]*counterDelta),
+		})
+	}
+	return c, nil
+}
+
+// Refresh выполняет команды, одновременно - не более concurrency.
+func (c *execCollector) Refresh() error {
+	sem := make(chan struct{}, c.concurrency)
+	errs := make([]error, len(c.commands))
+
+	var wg sync.WaitGroup
+	for i, command := range c.commands {
+		wg.Add(1)
+		go func(i int, command *execCommand) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs[i] = command.run(c.timeout)
+		}(i, command)
+	}
+	wg.Wait()
+
+	for _, command := range c.commands {
+		errs = append(errs, c.dropDuplicates(command))
+	}
+	return errors.Join(errs...)
+}
+
+// dropDuplicates отбрасывает метрики команды, которые уже передает другая команда.
+func (c *execCollector) dropDuplicates(command *execCommand) error {
+	var duplicates []string
+	owned := func(mType MetricType, name string) bool {
+		key := string(mType) + ":" + name
+		owner, ok := c.owners[key]
+		if !ok {
+			c.owners[key] = command
+			return true
+		}
+		if owner != command {
+			duplicates = append(duplicates, fmt.Sprintf("%v (command %v)", name, owner.Name))
+		}
+		return owner == command
+	}
+
+	for name := range command.gauges {
+		if !owned(GaugeType, name) {
+			delete(command.gauges, name)
+		}
+	}
+	for name := range command.counters {
+		if !owned(CounterType, name) {
+			delete(command.counters, name)
+		}
+	}
+
+	if len(duplicates) == 0 {
+		return nil
+	}
+	sort.Strings(duplicates)
+	command.parseErrors.Add(int64(len(duplicates)))
+	return fmt.Errorf("exec command %v: metrics are reported by other commands: %v", command.Name, strings.Join(duplicates, ", "))
+}
+
+func (c *execCollector) GetMetrics() []Metrics {
+	var metrics []Metrics
+	for _, command := range c.commands {
+		metrics = append(metrics, command.getMetrics()...)
+	}
+	return metrics
+}
+
+func (command *execCommand) run(timeout time.Duration) error {
+	ctx, cancelFn := context.WithTimeout(context.Background(), timeout)
+	defer cancelFn()
+
+	var stdout, stderr limitedBuffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command.Command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	setProcessGroup(cmd)
+	// дочерние процессы команды могут удерживать stdout после ее завершения
+	cmd.WaitDelay = execWaitDelay
+
+	start := time.Now()
+	err := cmd.Run()
+	command.duration = time.Since(start).Seconds()
+
+	if lines := countLines(stderr.Bytes()); lines > 0 {
+		command.stderrLines.Add(lines)
+		logrus.Warnf("exec command %v stderr: %s", command.Name, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		command.exitCode = -1
+		command.timeouts.Add(1)
+		return fmt.Errorf("exec command %v: timeout %v exceeded", command.Name, timeout)
+	case errors.As(err, &exitErr):
+		command.exitCode = float64(exitErr.ExitCode())
+		return fmt.Errorf("exec command %v: %w", command.Name, err)
+	case err != nil:
+		command.exitCode = -1
+		return fmt.Errorf("exec command %v: %w", command.Name, err)
+	}
+
+	command.exitCode = 0
+	return command.parse(stdout.Bytes())
+}
+
+// parse разбирает вывод команды; ошибочные строки пропускаются
+func (command *execCommand) parse(output []byte) error {
+	gauges := make(map[string]float64)
+	var parseErrors int64
+	var firstErr error
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := command.parseLine(line, gauges); err != nil {
+			parseErrors++
+			if firstErr == nil {
+				firstErr = fmt.Errorf("exec command %v: line %d: %w", command.Name, lineNum, err)
+			}
+		}
+	}
+
+	command.gauges = gauges
+	command.parseErrors.Add(parseErrors)
+	return firstErr
+}
+
+func (command *execCommand) parseLine(line string, gauges map[string]float64) error {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return fmt.Errorf("wrong line format, expected 'name type value'")
+	}
+
+	name, mType, value := fields[0], MetricType(fields[1]), fields[2]
+	if !metricNameRegexp.MatchString(name) {
+		return fmt.Errorf("wrong metric name %v", name)
+	}
+
+	switch mType {
+	case GaugeType:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("wrong gauge value %v", value)
+		}
+		gauges[name] = v
+	case CounterType:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("wrong counter value %v", value)
+		}
+		counter, ok := command.counters[name]
+		if !ok {
+			counter = &counterDelta{}
+			command.counters[name] = counter
+		}
+		counter.Add(v)
+	default:
+		return fmt.Errorf("wrong metric type %v", mType)
+	}
+	return nil
+}
+
+func (command *execCommand) getMetrics() []Metrics {
+	metrics := gaugeMetrics(command.gauges)
+	for name, counter := range command.counters {
+		delta, sent := counter.Take()
+		metrics = append(metrics, Metrics{ID: name, MType: CounterType, Delta: &delta, sent: sent})
+	}
+
+	labels := map[string]string{"command": command.Name}
+	metrics = append(metrics,
+		labeledGauge("ExecExitCode", labels, command.exitCode),
+		labeledGauge("ExecDurationSeconds", labels, command.duration),
+	)
+	for _, self := range []struct {
+		id      string
+		counter *counterDelta
+	}{
+		{"ExecStderrLines", &command.stderrLines},
+		{"ExecTimeouts", &command.timeouts},
+		{"ExecParseErrors", &command.parseErrors},
+	} {
+		delta, sent := self.counter.Take()
+		metrics = append(metrics, Metrics{ID: self.id, MType: CounterType, Delta: &delta, Labels: labels, sent: sent})
+	}
+	return metrics
+}
+
+// limitedBuffer сохраняет не более execMaxOutput байт, остальное отбрасывает
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := execMaxOutput - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func countLines(data []byte) int64 {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return 0
+	}
+	return int64(bytes.Count(data, []byte("\n")) + 1)
+}
@@ -0,0 +1,42 @@
+//go:build linux
+
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// processAlive проверяет, что процесс существует и не завершен (не зомби).
+func processAlive(pid int) bool {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	// состояние процесса - поле после имени команды в скобках
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestExecCollector_TimeoutKillsChildren(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+	c := newTestExecCollector(t, "slow=sleep 30 & echo $! > "+pidFile+" && wait", 1, 1)
+
+	require.ErrorContains(t, c.Refresh(), "exec command slow: timeout")
+
+	data, err := os.ReadFile(pidFile)
+	require.NoError(t, err)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return !processAlive(pid)
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package agent
+
+import "os/exec"
+
+// setProcessGroup без групп процессов при превышении времени выполнения завершается только сама команда.
+func setProcessGroup(cmd *exec.Cmd) {}
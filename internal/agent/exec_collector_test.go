@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestExecCollector(t *testing.T, commands string, timeout int, concurrency int) *execCollector {
+	c, err := newExecCollector(&config.AgentConfiguration{
+		ExecCommands:    commands,
+		ExecTimeout:     timeout,
+		ExecConcurrency: concurrency,
+	})
+	require.NoError(t, err)
+	return c.(*execCollector)
+}
+
+func execSelfMetric(t *testing.T, metrics []Metrics, id, command string) *Metrics {
+	m := findMetric(metrics, id, map[string]string{"command": command})
+	require.NotNil(t, m, id)
+	return m
+}
+
+func TestExecCollector_Disabled(t *testing.T) {
+	c, err := newExecCollector(&config.AgentConfiguration{})
+	require.NoError(t, err)
+	require.Nil(t, c)
+}
+
+func TestExecCollector(t *testing.T) {
+	c := newTestExecCollector(t, `jobs=printf '# comment\n\nQueueSize gauge 12.5\nJobsDone counter 3\n'`, 1, 1)
+
+	require.NoError(t, c.Refresh())
+	require.NoError(t, c.Refresh())
+
+	metrics := c.GetMetrics()
+
+	m := findMetric(metrics, "QueueSize", nil)
+	require.NotNil(t, m)
+	require.Equal(t, GaugeType, m.MType)
+	require.Equal(t, 12.5, *m.Value)
+
+	// значения counter - приращения, они суммируются до отправки
+	m = findMetric(metrics, "JobsDone", nil)
+	require.NotNil(t, m)
+	require.Equal(t, int64(6), *m.Delta)
+
+	require.Equal(t, 0.0, *execSelfMetric(t, metrics, "ExecExitCode", "jobs").Value)
+	require.Equal(t, int64(0), *execSelfMetric(t, metrics, "ExecParseErrors", "jobs").Delta)
+	require.Equal(t, int64(0), *execSelfMetric(t, metrics, "ExecStderrLines", "jobs").Delta)
+
+	// неудачная отправка - приращение уходит со следующим отчетом
+	m.settle(errors.New("test err"))
+	require.NoError(t, c.Refresh())
+	m = findMetric(c.GetMetrics(), "JobsDone", nil)
+	require.Equal(t, int64(9), *m.Delta)
+}
+
+func TestExecCollector_Errors(t *testing.T) {
+	c := newTestExecCollector(t,
+		`bad=printf 'Good gauge 1\nbad line\n1Bad gauge 2\nType histogram 1\nValue counter 1.5\n'`+
+			`;fail=echo oops >&2 && echo err2 >&2 && exit 3`+
+			`;slow=sleep 5`,
+		1, 2)
+
+	start := time.Now()
+	err := c.Refresh()
+	require.Less(t, time.Since(start), 4*time.Second)
+
+	require.ErrorContains(t, err, "exec command bad: line 2")
+	require.ErrorContains(t, err, "exec command fail")
+	require.ErrorContains(t, err, "exec command slow: timeout")
+
+	metrics := c.GetMetrics()
+
+	// корректные строки сохраняются
+	require.NotNil(t, findMetric(metrics, "Good", nil))
+	require.Equal(t, int64(4), *execSelfMetric(t, metrics, "ExecParseErrors", "bad").Delta)
+
+	require.Equal(t, 3.0, *execSelfMetric(t, metrics, "ExecExitCode", "fail").Value)
+	require.Equal(t, int64(2), *execSelfMetric(t, metrics, "ExecStderrLines", "fail").Delta)
+
+	require.Equal(t, -1.0, *execSelfMetric(t, metrics, "ExecExitCode", "slow").Value)
+	require.Equal(t, int64(1), *execSelfMetric(t, metrics, "ExecTimeouts", "slow").Delta)
+	require.GreaterOrEqual(t, *execSelfMetric(t, metrics, "ExecDurationSeconds", "slow").Value, 1.0)
+}
+
+func TestExecCollector_NotFinite(t *testing.T) {
+	c := newTestExecCollector(t, `nan=printf 'Good gauge 1\nNan gauge NaN\nInf gauge +Inf\nNegInf gauge -inf\n'`, 1, 1)
+
+	err := c.Refresh()
+	require.ErrorContains(t, err, "exec command nan: line 2: wrong gauge value NaN")
+
+	metrics := c.GetMetrics()
+	require.Equal(t, 1.0, *findMetric(metrics, "Good", nil).Value)
+	require.Nil(t, findMetric(metrics, "Nan", nil))
+	require.Nil(t, findMetric(metrics, "Inf", nil))
+	require.Nil(t, findMetric(metrics, "NegInf", nil))
+	require.Equal(t, int64(3), *execSelfMetric(t, metrics, "ExecParseErrors", "nan").Delta)
+}
+
+func TestExecCollector_Duplicates(t *testing.T) {
+	c := newTestExecCollector(t,
+		`a=printf 'Shared gauge 1\nJobs counter 1\n'`+
+			`;b=printf 'Shared gauge 2\nJobs counter 5\nJobs gauge 3\nOwn gauge 4\n'`,
+		1, 2)
+
+	// метрика принадлежит первой по списку команде
+	err := c.Refresh()
+	require.ErrorContains(t, err, "exec command b: metrics are reported by other commands: Jobs (command a), Shared (command a)")
+
+	metrics := c.GetMetrics()
+	require.Equal(t, 1.0, *findMetric(metrics, "Shared", nil).Value)
+	require.Equal(t, int64(1), *findMetric(metrics, "Jobs", nil).Delta)
+	require.Equal(t, 4.0, *findMetric(metrics, "Own", nil).Value)
+	require.Equal(t, int64(2), *execSelfMetric(t, metrics, "ExecParseErrors", "b").Delta)
+	require.Equal(t, int64(0), *execSelfMetric(t, metrics, "ExecParseErrors", "a").Delta)
+
+	// gauge и counter с одним именем - разные метрики
+	var jobs []Metrics
+	for _, m := range metrics {
+		if m.ID == "Jobs" {
+			jobs = append(jobs, m)
+		}
+	}
+	require.Len(t, jobs, 2)
+}
+
+func TestExecCollector_Concurrency(t *testing.T) {
+	sleep := "sleep 0.3"
+	commands := "a=" + sleep + ";b=" + sleep + ";c=" + sleep + ";d=" + sleep
+
+	start := time.Now()
+	require.NoError(t, newTestExecCollector(t, commands, 5, 4).Refresh())
+	parallel := time.Since(start)
+
+	start = time.Now()
+	require.NoError(t, newTestExecCollector(t, commands, 5, 1).Refresh())
+	sequential := time.Since(start)
+
+	require.Less(t, parallel, 900*time.Millisecond)
+	require.GreaterOrEqual(t, sequential, 1200*time.Millisecond)
+}
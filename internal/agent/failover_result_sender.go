@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	endpointFirstBackoff = time.Second
+	endpointMaxBackoff   = time.Minute
+)
+
+// ResultSenderFactory создает отправителя для одного адреса сервера conf.ServerAddr.
+type ResultSenderFactory func(conf *config.AgentConfiguration) ResultSender
+
+// serverEndpoint сервер и его доступность
+type serverEndpoint struct {
+	addr     string
+	sender   ResultSender
+	failures int       // ошибки соединения подряд
+	retryAt  time.Time // до этого времени сервер считается недоступным
+}
+
+func NewFailoverResultSender(conf *config.AgentConfiguration, factory ResultSenderFactory) (*failoverResultSender, error) {
+	addrs, err := config.ParseServerAddrs(conf.ServerAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := &failoverResultSender{
+		roundRobin:   conf.ServerSelection == config.ServerSelectionRoundRobin,
+		firstBackoff: endpointFirstBackoff,
+		maxBackoff:   endpointMaxBackoff,
+		now:          time.Now,
+	}
+	for _, addr := range addrs {
+		endpointConf := *conf
+		endpointConf.ServerAddr = addr
+		rs.endpoints = append(rs.endpoints, &serverEndpoint{
+			addr:   addr,
+			sender: factory(&endpointConf),
+		})
+	}
+	return rs, nil
+}
+
+// failoverResultSender отправляет метрики на один из нескольких серверов.
+//
+// failover - используется первый по списку доступный сервер, round-robin - доступные серверы по очереди.
+// При ошибке соединения пакет сразу отправляется на следующий сервер, а сервер с ошибкой пропускается
+// с экспоненциально растущей задержкой. Если недоступны все серверы, они проверяются в порядке
+// окончания задержки; ошибка, не связанная с соединением, возвращается без переключения.
+type failoverResultSender struct {
+	endpoints    []*serverEndpoint
+	roundRobin   bool
+	firstBackoff time.Duration
+	maxBackoff   time.Duration
+	now          func() time.Time
+
+	mu   sync.Mutex
+	next int // первый сервер для round-robin
+}
+
+func (rs *failoverResultSender) Stop() {
+	for _, endpoint := range rs.endpoints {
+		endpoint.sender.Stop()
+	}
+}
+
+func (rs *failoverResultSender) SendMetrics(ctx context.Context, metrics []Metrics) error {
+	var errs []error
+	for _, endpoint := range rs.candidates() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := endpoint.sender.SendMetrics(ctx, metrics)
+		if err == nil {
+			rs.markHealthy(endpoint)
+		}
+		if err == nil || !isConnectionError(err) || ctx.Err() != nil {
+			return err
+		}
+
+		rs.markFailed(endpoint, err)
+		errs = append(errs, fmt.Errorf("server %v: %w", endpoint.addr, err))
+	}
+	return errors.Join(errs...)
+}
+
+// candidates возвращает серверы в порядке попыток отправки.
+func (rs *failoverResultSender) candidates() []*serverEndpoint {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := rs.now()
+	start := 0
+	if rs.roundRobin {
+		start = rs.next
+		rs.next = (rs.next + 1) % len(rs.endpoints)
+	}
+
+	var healthy, failed []*serverEndpoint
+	for i := range rs.endpoints {
+		endpoint := rs.endpoints[(start+i)%len(rs.endpoints)]
+		if endpoint.retryAt.After(now) {
+			failed = append(failed, endpoint)
+		} else {
+			healthy = append(healthy, endpoint)
+		}
+	}
+	sort.SliceStable(failed, func(i, j int) bool {
+		return failed[i].retryAt.Before(failed[j].retryAt)
+	})
+	return append(healthy, failed...)
+}
+
+func (rs *failoverResultSender) markHealthy(endpoint *serverEndpoint) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if endpoint.failures > 0 {
+		logrus.Infof("server %v is available", endpoint.addr)
+	}
+	endpoint.failures = 0
+	endpoint.retryAt = time.Time{}
+}
+
+func (rs *failoverResultSender) markFailed(endpoint *serverEndpoint, err error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	backoff := rs.firstBackoff << min(endpoint.failures, 16)
+	backoff = min(backoff, rs.maxBackoff)
+	endpoint.failures++
+	endpoint.retryAt = rs.now().Add(backoff)
+	logrus.Warnf("server %v is unavailable: %v, skipped for %v", endpoint.addr, err, backoff)
+}
+
+// isConnectionError проверяет, что сервер недоступен, а не отклонил запрос.
+func isConnectionError(err error) bool {
+	if st, ok := status.FromError(err); ok && st.Code() == codes.Unavailable {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, syscall.ECONNREFUSED)
+}
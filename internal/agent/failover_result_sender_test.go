@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// endpointSender сервер с управляемой доступностью
+type endpointSender struct {
+	addr  string
+	err   error
+	calls *[]string
+}
+
+func (s *endpointSender) SendMetrics(ctx context.Context, metrics []Metrics) error {
+	*s.calls = append(*s.calls, s.addr)
+	return s.err
+}
+
+func (s *endpointSender) Stop() {}
+
+func newTestFailover(t *testing.T, selection string, addrs ...string) (*failoverResultSender, map[string]*endpointSender, *[]string, *time.Time) {
+	t.Helper()
+	calls := &[]string{}
+	senders := make(map[string]*endpointSender)
+
+	conf := &config.AgentConfiguration{ServerSelection: selection}
+	for i, addr := range addrs {
+		if i > 0 {
+			conf.ServerAddr += ","
+		}
+		conf.ServerAddr += addr
+	}
+
+	rs, err := NewFailoverResultSender(conf, func(conf *config.AgentConfiguration) ResultSender {
+		sender := &endpointSender{addr: conf.ServerAddr, calls: calls}
+		senders[conf.ServerAddr] = sender
+		return sender
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	rs.now = func() time.Time { return now }
+	return rs, senders, calls, &now
+}
+
+func TestFailoverResultSender_Failover(t *testing.T) {
+	rs, senders, calls, now := newTestFailover(t, config.ServerSelectionFailover, "a", "b")
+	ctx := context.Background()
+
+	require.NoError(t, rs.SendMetrics(ctx, nil))
+	require.Equal(t, []string{"a"}, *calls)
+
+	// a недоступен - пакет отправляется на b, a пропускается на время задержки
+	senders["a"].err = fmt.Errorf("dial: %w", syscall.ECONNREFUSED)
+	*calls = nil
+	require.NoError(t, rs.SendMetrics(ctx, nil))
+	require.NoError(t, rs.SendMetrics(ctx, nil))
+	require.Equal(t, []string{"a", "b", "b"}, *calls)
+
+	// по истечении задержки a проверяется снова, задержка растет
+	*now = now.Add(time.Second)
+	*calls = nil
+	require.NoError(t, rs.SendMetrics(ctx, nil))
+	require.Equal(t, []string{"a", "b"}, *calls)
+	require.Equal(t, now.Add(2*time.Second), rs.endpoints[0].retryAt)
+
+	// a снова доступен
+	senders["a"].err = nil
+	*now = now.Add(2 * time.Second)
+	*calls = nil
+	require.NoError(t, rs.SendMetrics(ctx, nil))
+	require.NoError(t, rs.SendMetrics(ctx, nil))
+	require.Equal(t, []string{"a", "a"}, *calls)
+	require.Equal(t, 0, rs.endpoints[0].failures)
+}
+
+func TestFailoverResultSender_RoundRobin(t *testing.T) {
+	rs, senders, calls, _ := newTestFailover(t, config.ServerSelectionRoundRobin, "a", "b", "c")
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, rs.SendMetrics(ctx, nil))
+	}
+	require.Equal(t, []string{"a", "b", "c", "a"}, *calls)
+
+	senders["c"].err = status.Error(codes.Unavailable, "connection refused")
+	*calls = nil
+	for i := 0; i < 3; i++ {
+		require.NoError(t, rs.SendMetrics(ctx, nil))
+	}
+	require.Equal(t, []string{"b", "c", "a", "a"}, *calls)
+}
+
+func TestFailoverResultSender_Errors(t *testing.T) {
+	rs, senders, calls, _ := newTestFailover(t, config.ServerSelectionFailover, "a", "b")
+	ctx := context.Background()
+
+	// сервер отклонил запрос - повтор на другом сервере не поможет
+	testErr := errors.New("unexpected server http response code: 400")
+	senders["a"].err = testErr
+	require.ErrorIs(t, rs.SendMetrics(ctx, nil), testErr)
+	require.Equal(t, []string{"a"}, *calls)
+
+	// недоступны все серверы - ошибка соединения передается для повтора отправки
+	senders["a"].err = syscall.ECONNREFUSED
+	senders["b"].err = syscall.ECONNREFUSED
+	*calls = nil
+	require.ErrorIs(t, rs.SendMetrics(ctx, nil), syscall.ECONNREFUSED)
+	require.ErrorIs(t, rs.SendMetrics(ctx, nil), syscall.ECONNREFUSED)
+	require.Equal(t, []string{"a", "b", "a", "b"}, *calls)
+}
@@ -9,11 +9,18 @@ import (
 	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
 func NewGRPCResultSender(conf *config.AgentConfiguration) *grpcResultSender {
 
-	conn, err := grpc.NewClient(conf.ServerAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if conf.ServerSelection == config.ServerSelectionRoundRobin {
+		// адреса, полученные резолвером (например, dns:///), используются по очереди; по-умолчанию - первый доступный
+		opts = append(opts, grpc.WithDefaultServiceConfig(`{"loadBalancingConfig": [{"round_robin": {}}]}`))
+	}
+
+	conn, err := grpc.NewClient(conf.ServerAddr, opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -40,16 +47,31 @@ func (h *grpcResultSender) SendMetrics(ctx context.Context, metrics []Metrics) e
 		switch m.MType {
 		case MetricType(domain.CounterType):
 			pbM := &pb.Metric{
-				Name:  m.ID,
-				Type:  pb.Metric_COUNTER,
-				Delta: *m.Delta,
+				Name:   m.ID,
+				Type:   pb.Metric_COUNTER,
+				Delta:  *m.Delta,
+				Labels: m.Labels,
 			}
 			pbMetrics = append(pbMetrics, pbM)
 		case MetricType(domain.GaugeType):
 			pbM := &pb.Metric{
-				Name:  m.ID,
-				Type:  pb.Metric_GAUGE,
-				Value: *m.Value,
+				Name:   m.ID,
+				Type:   pb.Metric_GAUGE,
+				Value:  *m.Value,
+				Labels: m.Labels,
+			}
+			pbMetrics = append(pbMetrics, pbM)
+		case MetricType(domain.HistogramType):
+			pbM := &pb.Metric{
+				Name: m.ID,
+				Type: pb.Metric_HISTOGRAM,
+				Histogram: &pb.Histogram{
+					Bounds: m.Histogram.Bounds,
+					Counts: m.Histogram.Counts,
+					Sum:    m.Histogram.Sum,
+					Count:  m.Histogram.Count,
+				},
+				Labels: m.Labels,
 			}
 			pbMetrics = append(pbMetrics, pbM)
 		}
@@ -59,6 +81,10 @@ func (h *grpcResultSender) SendMetrics(ctx context.Context, metrics []Metrics) e
 		Metrics: pbMetrics,
 	}
 
+	if key := BatchKey(ctx); key != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, IdempotencyKeyMD, key)
+	}
+
 	_, err := h.client.Update(ctx, mr)
 	return err
 }
@@ -174,6 +174,10 @@ func (h *httpResultSender) SendMetrics(ctx context.Context, metrics []Metrics) e
 		SetHeader("Content-Encoding", "gzip").
 		SetHeader("X-Real-IP", clientIP)
 
+	if key := BatchKey(ctx); key != "" {
+		request.SetHeader(IdempotencyKeyHeader, key)
+	}
+
 	if hsr, ok := wc.(*hashWriter); ok {
 		hexValue := hex.EncodeToString(hsr.Sum())
 		request.SetHeader("HashSHA256", hexValue)
@@ -191,12 +195,21 @@ func (h *httpResultSender) SendMetrics(ctx context.Context, metrics []Metrics) e
 	if resp.StatusCode() != http.StatusOK {
 		errStr := fmt.Sprintf("unexpected server http response code: %v", resp.StatusCode())
 		logrus.Errorf(errStr)
+		if isRejectedStatus(resp.StatusCode()) {
+			return fmt.Errorf("%w: %v", ErrServerRejected, errStr)
+		}
 		return errors.New(errStr)
 	}
 
 	return err
 }
 
+// isRejectedStatus проверяет, что сервер отклонил сам запрос и повтор того же запроса не поможет.
+func isRejectedStatus(code int) bool {
+	return code >= http.StatusBadRequest && code < http.StatusInternalServerError &&
+		code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
+
 type encryptedWriter struct {
 	key *rsa.PublicKey
 	io.WriteCloser
@@ -208,7 +221,7 @@ func (ew *encryptedWriter) Write(p []byte) (int, error) {
 }
 
 func (ew *encryptedWriter) Close() error {
-	encrypted, err := keygen.EncryptWithPublicKey(ew.buf.Bytes(), ew.key)
+	encrypted, err := keygen.EncryptEnvelope(ew.buf.Bytes(), ew.key)
 	if err != nil {
 		return err
 	}
@@ -3,6 +3,8 @@ package agent_test
 import (
 	"compress/gzip"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -101,8 +103,12 @@ func TestHash256Header_Encrypt(t *testing.T) {
 		}
 		defer r.Body.Close()
 
-		_, err = keygen.DecryptWithPrivateKey(encrypted, privKey)
+		decrypted, err := keygen.DecryptEnvelope(encrypted, privKey)
 		require.NoError(t, err)
+
+		var metrics []agent.Metrics
+		require.NoError(t, json.Unmarshal(decrypted, &metrics))
+		require.Len(t, metrics, 100)
 	})
 
 	srv := httptest.NewServer(mux)
@@ -116,14 +122,18 @@ func TestHash256Header_Encrypt(t *testing.T) {
 
 	sender := agent.NewHTTPResultSender(&clntConf)
 
+	// пакет больше, чем RSA-OAEP может зашифровать напрямую
 	value := 1.
-	sender.SendMetrics(context.Background(), []agent.Metrics{
-		{
-			ID:    "HeapReleased",
+	metrics := make([]agent.Metrics, 100)
+	for i := range metrics {
+		metrics[i] = agent.Metrics{
+			ID:    fmt.Sprintf("Metric%d", i),
 			MType: agent.GaugeType,
 			Value: &value,
-		},
-	})
+		}
+	}
+	err = sender.SendMetrics(context.Background(), metrics)
+	require.NoError(t, err)
 }
 
 func TestXRealIPHeader(t *testing.T) {
@@ -153,6 +163,43 @@ func TestXRealIPHeader(t *testing.T) {
 	})
 }
 
+func TestIdempotencyKeyHeader(t *testing.T) {
+
+	var keys []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/updates/", func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(agent.IdempotencyKeyHeader))
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	clntConf := config.AgentConfiguration{
+		ServerAddr: srv.URL,
+	}
+
+	sender := agent.NewHTTPRetryableResultSender(agent.RetriableInvokerConf{}, agent.NewHTTPResultSender(&clntConf))
+
+	value := 1.
+	metrics := []agent.Metrics{
+		{
+			ID:    "HeapReleased",
+			MType: agent.GaugeType,
+			Value: &value,
+		},
+	}
+
+	require.NoError(t, sender.SendMetrics(context.Background(), metrics))
+	require.NoError(t, sender.SendMetrics(context.Background(), metrics))
+	require.NoError(t, sender.SendMetrics(agent.WithBatchKey(context.Background(), "batch-1"), metrics))
+
+	require.Equal(t, 3, len(keys))
+	require.NotEmpty(t, keys[0])
+	require.NotEmpty(t, keys[1])
+	require.NotEqual(t, keys[0], keys[1])
+	require.Equal(t, "batch-1", keys[2])
+}
+
 func TestParserServerAddr(t *testing.T) {
 	testCases := []struct {
 		input  string
@@ -191,3 +238,41 @@ func TestParserServerAddr(t *testing.T) {
 		})
 	}
 }
+
+func TestFailoverHTTP(t *testing.T) {
+	var requests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/updates/", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// первый сервер недоступен
+	down := httptest.NewServer(mux)
+	down.Close()
+
+	clntConf := config.AgentConfiguration{
+		ServerAddr:      down.URL + "," + srv.URL,
+		ServerSelection: config.ServerSelectionFailover,
+	}
+
+	sender, err := agent.NewFailoverResultSender(&clntConf, func(conf *config.AgentConfiguration) agent.ResultSender {
+		return agent.NewHTTPResultSender(conf)
+	})
+	require.NoError(t, err)
+
+	value := 1.
+	metrics := []agent.Metrics{
+		{
+			ID:    "HeapReleased",
+			MType: agent.GaugeType,
+			Value: &value,
+		},
+	}
+
+	require.NoError(t, sender.SendMetrics(context.Background(), metrics))
+	require.NoError(t, sender.SendMetrics(context.Background(), metrics))
+	require.Equal(t, 2, requests)
+}
@@ -3,28 +3,41 @@ package agent
 import (
 	"math/rand"
 	"runtime"
-	"sync/atomic"
+	"sync"
+	"time"
 
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
 )
 
-func NewMemStatsStorage() *memStatsSource {
+func newRuntimeCollector(conf *config.AgentConfiguration) (Collector, error) {
+	return NewMemStatsStorage(conf), nil
+}
+
+// NewMemStatsStorage создает сборщик runtime.MemStats.
+func NewMemStatsStorage(conf *config.AgentConfiguration) *memStatsSource {
 	return &memStatsSource{
-		poolCounter:    0,
-		memStatStorage: nil,
+		memStatStorage:   nil,
+		histogramBuckets: conf.HistogramBuckets,
 	}
 }
 
 type memStatsSource struct {
-	poolCounter    int64
-	memStatStorage map[string]float64
+	pollCount        counterDelta // опросы, еще не подтвержденные сервером
+	memStatStorage   map[string]float64
+	histogramBuckets []float64
+
+	// паузы GC, еще не подтвержденные сервером
+	histogramMu sync.Mutex
+	gcPause     *histogramDelta
+	lastNumGC   uint32
 }
 
 func (m *memStatsSource) Refresh() error {
-	defer atomic.AddInt64(&m.poolCounter, 1)
+	defer m.pollCount.Add(1)
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
+
+	m.observeGCPauses(&memStats)
 	m.memStatStorage = map[string]float64{
 		"Alloc":         float64(memStats.Alloc),
 		"BuckHashSys":   float64(memStats.BuckHashSys),
@@ -55,38 +68,61 @@ func (m *memStatsSource) Refresh() error {
 		"TotalAlloc":    float64(memStats.TotalAlloc),
 		"RandomValue":   rand.Float64(),
 	}
+	return nil
+}
 
-	v, err := mem.VirtualMemory()
-	if err != nil {
-		return err
+// observeGCPauses добавляет в гистограмму паузы GC, случившиеся с момента предыдущего опроса
+func (m *memStatsSource) observeGCPauses(memStats *runtime.MemStats) {
+	m.histogramMu.Lock()
+	defer m.histogramMu.Unlock()
+
+	if m.gcPause == nil {
+		m.gcPause = m.newHistogramDelta()
 	}
-	m.memStatStorage["TotalMemory"] = float64(v.Total)
-	m.memStatStorage["FreeMemory"] = float64(v.Free)
 
-	res, err := cpu.Percent(0, false)
-	if err != nil {
-		return err
+	newGC := memStats.NumGC - m.lastNumGC
+	if newGC > uint32(len(memStats.PauseNs)) {
+		newGC = uint32(len(memStats.PauseNs)) // старые значения циклического буфера уже перезаписаны
 	}
-	m.memStatStorage["CPUutilization1"] = float64(res[0])
-	return nil
+
+	for i := uint32(0); i < newGC; i++ {
+		idx := (memStats.NumGC - i + uint32(len(memStats.PauseNs)) - 1) % uint32(len(memStats.PauseNs))
+		m.gcPause.Observe(time.Duration(memStats.PauseNs[idx]).Seconds())
+	}
+	m.lastNumGC = memStats.NumGC
 }
 
-func (m *memStatsSource) GetMetrics() []Metrics {
-	var metrics []Metrics
-	for k, v := range m.memStatStorage {
-		value := v
-		metrics = append(metrics, Metrics{
-			ID:    k,
-			MType: GaugeType,
-			Value: &value,
-		})
+func (m *memStatsSource) newHistogramDelta() *histogramDelta {
+	buckets := m.histogramBuckets
+	if len(buckets) == 0 {
+		buckets = config.AgentDefaultHistogramBuckets
 	}
+	return newHistogramDelta(buckets)
+}
+
+func (m *memStatsSource) GetMetrics() []Metrics {
+	metrics := gaugeMetrics(m.memStatStorage)
 
-	poolCount := m.poolCounter
+	// counter и гистограмма передаются приращением, сервер суммирует значения
+	pollCount, sent := m.pollCount.Take()
 	metrics = append(metrics, Metrics{
 		ID:    "PollCount",
 		MType: CounterType,
-		Delta: &poolCount,
+		Delta: &pollCount,
+		sent:  sent,
 	})
+
+	m.histogramMu.Lock()
+	if m.gcPause != nil {
+		gcPause, sent := m.gcPause.Take()
+		metrics = append(metrics, Metrics{
+			ID:        "GCPause",
+			MType:     HistogramType,
+			Histogram: gcPause,
+			sent:      sent,
+		})
+	}
+	m.histogramMu.Unlock()
+
 	return metrics
 }
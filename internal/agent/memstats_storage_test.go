@@ -10,38 +10,36 @@ import (
 func TestMemStatsSource(t *testing.T) {
 	mm := &memStatsSource{}
 	expectedKeys := map[string]MetricType{
-		"Alloc":           GaugeType,
-		"BuckHashSys":     GaugeType,
-		"Frees":           GaugeType,
-		"GCCPUFraction":   GaugeType,
-		"GCSys":           GaugeType,
-		"HeapAlloc":       GaugeType,
-		"HeapIdle":        GaugeType,
-		"HeapInuse":       GaugeType,
-		"HeapObjects":     GaugeType,
-		"HeapReleased":    GaugeType,
-		"HeapSys":         GaugeType,
-		"LastGC":          GaugeType,
-		"Lookups":         GaugeType,
-		"MCacheInuse":     GaugeType,
-		"MCacheSys":       GaugeType,
-		"MSpanInuse":      GaugeType,
-		"MSpanSys":        GaugeType,
-		"Mallocs":         GaugeType,
-		"NextGC":          GaugeType,
-		"NumForcedGC":     GaugeType,
-		"NumGC":           GaugeType,
-		"OtherSys":        GaugeType,
-		"PauseTotalNs":    GaugeType,
-		"StackInuse":      GaugeType,
-		"StackSys":        GaugeType,
-		"Sys":             GaugeType,
-		"TotalAlloc":      GaugeType,
-		"RandomValue":     GaugeType,
-		"TotalMemory":     GaugeType,
-		"FreeMemory":      GaugeType,
-		"CPUutilization1": GaugeType,
-		"PollCount":       CounterType,
+		"Alloc":         GaugeType,
+		"BuckHashSys":   GaugeType,
+		"Frees":         GaugeType,
+		"GCCPUFraction": GaugeType,
+		"GCSys":         GaugeType,
+		"HeapAlloc":     GaugeType,
+		"HeapIdle":      GaugeType,
+		"HeapInuse":     GaugeType,
+		"HeapObjects":   GaugeType,
+		"HeapReleased":  GaugeType,
+		"HeapSys":       GaugeType,
+		"LastGC":        GaugeType,
+		"Lookups":       GaugeType,
+		"MCacheInuse":   GaugeType,
+		"MCacheSys":     GaugeType,
+		"MSpanInuse":    GaugeType,
+		"MSpanSys":      GaugeType,
+		"Mallocs":       GaugeType,
+		"NextGC":        GaugeType,
+		"NumForcedGC":   GaugeType,
+		"NumGC":         GaugeType,
+		"OtherSys":      GaugeType,
+		"PauseTotalNs":  GaugeType,
+		"StackInuse":    GaugeType,
+		"StackSys":      GaugeType,
+		"Sys":           GaugeType,
+		"TotalAlloc":    GaugeType,
+		"RandomValue":   GaugeType,
+		"PollCount":     CounterType,
+		"GCPause":       HistogramType,
 	}
 	err := mm.Refresh()
 	require.NoError(t, err)
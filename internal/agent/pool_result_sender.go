@@ -2,95 +2,346 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/StasMerzlyakov/go-metrics/internal/config"
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	ErrPoolBufferFull = errors.New("pool buffer is full")
+	errPoolStopped    = errors.New("pool is stopped")
+)
+
 func NewPoolResultSender(conf *config.AgentConfiguration, resultSender ResultSender) *poolResultSender {
+	linger := conf.BatchLinger
+	if linger <= 0 {
+		linger = config.AgentDefaultBatchLinger
+	}
+	bufferSize := conf.PoolBufferSize
+	if bufferSize <= 0 {
+		bufferSize = config.AgentDefaultPoolBufferSize
+	}
+	flushTimeout := conf.ShutdownTimeout
+	if flushTimeout <= 0 {
+		flushTimeout = config.AgentDefaultShutdownTime
+	}
+
+	workers := make([]*poolWorkerStats, conf.RateLimit)
+	for i := range workers {
+		workers[i] = &poolWorkerStats{name: strconv.Itoa(i)}
+	}
+
 	return &poolResultSender{
-		sender:    resultSender,
-		batchSize: conf.BatchSize,
-		rateLimit: conf.RateLimit,
-		batchChan: make(chan Metrics),
+		sender:       resultSender,
+		batchSize:    conf.BatchSize,
+		linger:       time.Duration(linger) * time.Second,
+		dropWhenFull: conf.PoolBufferPolicy == config.PoolBufferDrop,
+		flushTimeout: time.Duration(flushTimeout) * time.Second,
+		batchChan:    make(chan Metrics, bufferSize),
+		flushing:     make(chan struct{}),
+		stopping:     make(chan struct{}),
+		workers:      workers,
 	}
 }
 
+// poolWorkerStats статистика рабочего пула
+type poolWorkerStats struct {
+	name         string
+	batches      counterDelta
+	errors       counterDelta
+	metrics      counterDelta
+	mu           sync.Mutex
+	lastDuration time.Duration
+}
+
+// poolResultSender собирает метрики в пакеты и отправляет их несколькими рабочими.
+//
+// Пакет отправляется, когда набрано BatchSize метрик или неполный пакет ждет дольше BatchLinger.
+// Метрики ждут формирования пакета в буфере ограниченного размера; при заполнении буфера SendMetrics
+// ждет освобождения места (block) или возвращает метрики источнику (drop).
+// При отмене контекста буфер и неполный пакет отправляются с ожиданием не дольше flushTimeout;
+// Wait ожидает завершения рабочих. Flush отправляет буфер без отмены контекста.
 type poolResultSender struct {
 	sender           ResultSender
 	batchSize        int
-	rateLimit        int
+	linger           time.Duration
+	dropWhenFull     bool
+	flushTimeout     time.Duration
 	batchChan        chan Metrics
 	startBatcherOnce sync.Once
+	cancelSend       context.CancelFunc // прерывает отправку
+	wg               sync.WaitGroup
+
+	flushing  chan struct{} // закрывается Flush
+	flushOnce sync.Once
+	stopping  chan struct{} // закрывается перед отправкой буфера при остановке
+	stopOnce  sync.Once
+	mu        sync.RWMutex
+	stopped   bool // буфер больше не читается
+
+	workers []*poolWorkerStats
+	dropped counterDelta
 }
 
+// settlesMetrics результат отправки метрик сообщают рабочие пула.
+func (rs *poolResultSender) settlesMetrics() {}
+
 func (rs *poolResultSender) Stop() {
 	rs.sender.Stop()
 }
 
+// Wait ожидает завершения отправки после отмены контекста.
+func (rs *poolResultSender) Wait() {
+	rs.wg.Wait()
+}
+
 func (rs *poolResultSender) SendMetrics(ctx context.Context, metrics []Metrics) error {
 	logrus.Infof("SendMetrics start")
 
 	rs.startBatcherOnce.Do(func() {
-		go rs.batcher(ctx)
+		// отправка продолжается после отмены ctx, пока не истечет flushTimeout
+		sendCtx, cancelSend := context.WithCancel(context.WithoutCancel(ctx))
+		context.AfterFunc(ctx, func() {
+			time.AfterFunc(rs.flushTimeout, cancelSend)
+		})
+		rs.cancelSend = cancelSend
+
+		rs.wg.Add(1)
+		go rs.batcher(ctx, sendCtx)
 	})
 
-	for _, m := range metrics {
+	// батчер не завершится, пока метрики добавляются в буфер
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	if rs.stopped {
+		settleAll(metrics, errPoolStopped)
+		return errPoolStopped
+	}
+
+	var dropped int64
+	for i, m := range metrics {
+		if rs.dropWhenFull {
+			select {
+			case rs.batchChan <- m:
+			default:
+				m.settle(ErrPoolBufferFull)
+				dropped++
+			}
+			continue
+		}
+
 		select {
 		case <-ctx.Done():
+			settleAll(metrics[i:], ctx.Err())
 			return ctx.Err()
+		case <-rs.stopping:
+			settleAll(metrics[i:], errPoolStopped)
+			return errPoolStopped
 		case rs.batchChan <- m:
-			continue
 		}
 	}
 
+	if dropped > 0 {
+		rs.dropped.Add(dropped)
+		return fmt.Errorf("%w: %d metrics returned", ErrPoolBufferFull, dropped)
+	}
 	return nil
 }
 
-func (rs *poolResultSender) batcher(ctx context.Context) error {
-	var batch []Metrics
+// Flush отправляет буфер и неполный пакет и ожидает завершения рабочих; при отмене ctx отправка прерывается.
+// Метрики, переданные в SendMetrics после Flush, возвращаются источнику.
+func (rs *poolResultSender) Flush(ctx context.Context) error {
+	started := true
+	rs.startBatcherOnce.Do(func() {
+		started = false
+	})
+	if !started {
+		rs.stop()
+		return rs.flushNext(ctx)
+	}
 
-	batchPool := make(chan []Metrics)
-	defer close(batchPool)
+	rs.flushOnce.Do(func() {
+		close(rs.flushing)
+	})
+	// отправка, в том числе из очереди следующего отправителя, прерывается по истечении ctx
+	context.AfterFunc(ctx, rs.cancelSend)
+
+	done := make(chan struct{})
+	go func() {
+		rs.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+	}
+	return rs.flushNext(ctx)
+}
+
+func (rs *poolResultSender) flushNext(ctx context.Context) error {
+	if f, ok := rs.sender.(flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
 
-	workerCount := rs.rateLimit
-	for i := 0; i < workerCount; i++ {
-		go rs.worker(ctx, fmt.Sprintf("worker %d", i), batchPool)
+func (rs *poolResultSender) batcher(ctx, sendCtx context.Context) {
+	defer rs.wg.Done()
+
+	batchPool := make(chan []Metrics)
+	var workersWg sync.WaitGroup
+	for _, stats := range rs.workers {
+		workersWg.Add(1)
+		go func(stats *poolWorkerStats) {
+			defer workersWg.Done()
+			rs.worker(sendCtx, stats, batchPool)
+		}(stats)
 	}
 
+	var batch []Metrics
+	var lingerC <-chan time.Time
+
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		case m, ok := <-rs.batchChan:
-			if !ok {
-				return fmt.Errorf("channel is close")
+			rs.flush(sendCtx, batch, batchPool, &workersWg)
+			return
+		case <-rs.flushing:
+			rs.flush(sendCtx, batch, batchPool, &workersWg)
+			return
+		case m := <-rs.batchChan:
+			if len(batch) == 0 {
+				lingerC = time.After(rs.linger)
 			}
 			batch = append(batch, m)
-			if len(batch) == rs.batchSize {
-				logrus.Infof("send batch")
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case batchPool <- batch:
-					batch = batch[:0]
-				}
+			if len(batch) < rs.batchSize {
+				continue
 			}
+		case <-lingerC:
+		}
+
+		logrus.Infof("send batch")
+		select {
+		case <-ctx.Done():
+			// пакет отправляется вместе с буфером
+			continue
+		case <-rs.flushing:
+			continue
+		case batchPool <- batch:
+			// пакет принадлежит рабочему, следующий собирается в новом массиве
+			batch = nil
+			lingerC = nil
+		}
+	}
+}
+
+// stop прекращает прием метрик.
+func (rs *poolResultSender) stop() {
+	rs.stopOnce.Do(func() {
+		close(rs.stopping)
+		rs.mu.Lock()
+		rs.stopped = true
+		rs.mu.Unlock()
+	})
+}
+
+// flush отправляет неполный пакет и метрики из буфера и ожидает завершения рабочих.
+func (rs *poolResultSender) flush(sendCtx context.Context, batch []Metrics, batchPool chan<- []Metrics, workersWg *sync.WaitGroup) {
+	defer func() {
+		close(batchPool)
+		workersWg.Wait()
+		logrus.Info("pool DONE")
+	}()
+
+	rs.stop()
+	close(rs.batchChan)
+	for m := range rs.batchChan {
+		batch = append(batch, m)
+	}
+
+	for len(batch) > 0 {
+		n := min(len(batch), max(rs.batchSize, 1))
+		select {
+		case <-sendCtx.Done():
+			settleAll(batch, sendCtx.Err())
+			return
+		case batchPool <- batch[:n:n]:
+			batch = batch[n:]
 		}
 	}
 }
 
-func (rs *poolResultSender) worker(ctx context.Context, name string, batchPool <-chan []Metrics) error {
-	logrus.Infof("worker %v started", name)
+func (rs *poolResultSender) worker(ctx context.Context, stats *poolWorkerStats, batchPool <-chan []Metrics) {
+	logrus.Infof("worker %v started", stats.name)
 	for metrics := range batchPool {
-		logrus.Infof("worker %v send start", name)
-		if err := rs.sender.SendMetrics(ctx, metrics); err != nil {
-			logrus.Warnf("worker %v send error %v", name, err.Error())
+		if err := ctx.Err(); err != nil {
+			settleAll(metrics, err)
+			continue
+		}
+
+		logrus.Infof("worker %v send start", stats.name)
+		start := time.Now()
+		err := rs.sender.SendMetrics(ctx, metrics)
+		stats.observe(len(metrics), time.Since(start), err)
+		if err != nil {
+			logrus.Warnf("worker %v send error %v", stats.name, err.Error())
 		} else {
-			logrus.Infof("worker %v send success", name)
+			logrus.Infof("worker %v send success", stats.name)
 		}
+		settleAll(metrics, err)
+	}
+}
+
+func (s *poolWorkerStats) observe(metrics int, duration time.Duration, err error) {
+	s.mu.Lock()
+	s.lastDuration = duration
+	s.mu.Unlock()
+
+	if err != nil {
+		s.errors.Add(1)
+		return
 	}
+	s.batches.Add(1)
+	s.metrics.Add(int64(metrics))
+}
+
+func (rs *poolResultSender) Refresh() error {
 	return nil
 }
+
+// GetMetrics возвращает статистику пула: по рабочим (метка worker) - отправленные пакеты и метрики,
+// ошибки отправки и длительность последней отправки; по пулу - заполнение буфера и метрики,
+// возвращенные источнику из-за заполнения буфера.
+func (rs *poolResultSender) GetMetrics() []Metrics {
+	metrics := make([]Metrics, 0, 4*len(rs.workers)+2)
+	for _, stats := range rs.workers {
+		labels := map[string]string{"worker": stats.name}
+		metrics = append(metrics,
+			takeCounter("PoolBatchesSent", labels, &stats.batches),
+			takeCounter("PoolMetricsSent", labels, &stats.metrics),
+			takeCounter("PoolSendErrors", labels, &stats.errors),
+		)
+
+		stats.mu.Lock()
+		metrics = append(metrics, labeledGauge("PoolSendDurationSeconds", labels, stats.lastDuration.Seconds()))
+		stats.mu.Unlock()
+	}
+
+	metrics = append(metrics,
+		takeCounter("PoolDroppedMetrics", nil, &rs.dropped),
+		labeledGauge("PoolBufferedMetrics", nil, float64(len(rs.batchChan))),
+	)
+	return metrics
+}
+
+func takeCounter(id string, labels map[string]string, counter *counterDelta) Metrics {
+	delta, sent := counter.Take()
+	return Metrics{ID: id, MType: CounterType, Delta: &delta, Labels: labels, sent: sent}
+}
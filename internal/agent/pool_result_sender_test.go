@@ -3,12 +3,14 @@ package agent_test
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/StasMerzlyakov/go-metrics/internal/agent"
 	"github.com/StasMerzlyakov/go-metrics/internal/config"
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPoolResultSenderCancellation_1(t *testing.T) {
@@ -17,11 +19,13 @@ func TestPoolResultSenderCancellation_1(t *testing.T) {
 
 	mockSender := NewMockResultSender(ctrl)
 
-	mockSender.EXPECT().SendMetrics(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	// неполный пакет отправляется при остановке
+	mockSender.EXPECT().SendMetrics(gomock.Any(), gomock.Any()).Return(nil).Times(2)
 
 	cnf := &config.AgentConfiguration{
-		BatchSize: 5,
-		RateLimit: 1,
+		BatchSize:   5,
+		RateLimit:   1,
+		BatchLinger: 10,
 	}
 
 	sender := agent.NewPoolResultSender(cnf, mockSender)
@@ -42,6 +46,7 @@ func TestPoolResultSenderCancellation_1(t *testing.T) {
 	sender.SendMetrics(ctx, metrics)
 	time.Sleep(1 * time.Second)
 	cancelFn()
+	sender.Wait()
 }
 
 func TestPoolResultSenderCancellation_2(t *testing.T) {
@@ -75,6 +80,7 @@ func TestPoolResultSenderCancellation_2(t *testing.T) {
 	sender.SendMetrics(ctx, metrics)
 	time.Sleep(1 * time.Second)
 	cancelFn()
+	sender.Wait()
 }
 
 func TestPoolResultSenderCancellation_3(t *testing.T) {
@@ -158,3 +164,206 @@ func TestPoolResultSenderCancellation_4(t *testing.T) {
 	time.Sleep(1 * time.Second)
 
 }
+
+func gaugeMetrics(n int) []agent.Metrics {
+	var metrics []agent.Metrics
+	value := 1.0
+	for i := 0; i < n; i++ {
+		metrics = append(metrics, agent.Metrics{
+			ID:    fmt.Sprintf("HeapReleased_%v", i),
+			MType: agent.GaugeType,
+			Value: &value,
+		})
+	}
+	return metrics
+}
+
+func TestPoolResultSenderLinger(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSender := NewMockResultSender(ctrl)
+
+	sent := make(chan int, 1)
+	mockSender.EXPECT().SendMetrics(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ms []agent.Metrics) error {
+			sent <- len(ms)
+			return nil
+		},
+	).Times(1)
+
+	cnf := &config.AgentConfiguration{
+		BatchSize:   5,
+		RateLimit:   1,
+		BatchLinger: 1,
+	}
+
+	sender := agent.NewPoolResultSender(cnf, mockSender)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer func() {
+		cancelFn()
+		sender.Wait()
+	}()
+
+	// неполный пакет отправляется по истечении BatchLinger
+	start := time.Now()
+	require.NoError(t, sender.SendMetrics(ctx, gaugeMetrics(2)))
+
+	select {
+	case n := <-sent:
+		require.Equal(t, 2, n)
+		require.GreaterOrEqual(t, time.Since(start), time.Second)
+	case <-time.After(3 * time.Second):
+		t.Fatal("batch is not sent")
+	}
+}
+
+func TestPoolResultSenderDrop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSender := NewMockResultSender(ctrl)
+
+	release := make(chan struct{})
+	mockSender.EXPECT().SendMetrics(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ms []agent.Metrics) error {
+			<-release
+			return nil
+		},
+	).AnyTimes()
+
+	cnf := &config.AgentConfiguration{
+		BatchSize:        1,
+		RateLimit:        1,
+		PoolBufferSize:   2,
+		PoolBufferPolicy: config.PoolBufferDrop,
+	}
+
+	sender := agent.NewPoolResultSender(cnf, mockSender)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer func() {
+		close(release)
+		cancelFn()
+		sender.Wait()
+	}()
+
+	// рабочий занят первой метрикой, батчер ждет рабочего со второй, две метрики в буфере
+	require.NoError(t, sender.SendMetrics(ctx, gaugeMetrics(2)))
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, sender.SendMetrics(ctx, gaugeMetrics(2)))
+
+	err := sender.SendMetrics(ctx, gaugeMetrics(3))
+	require.ErrorIs(t, err, agent.ErrPoolBufferFull)
+
+	var dropped, buffered float64
+	for _, m := range sender.GetMetrics() {
+		switch m.ID {
+		case "PoolDroppedMetrics":
+			dropped = float64(*m.Delta)
+		case "PoolBufferedMetrics":
+			buffered = *m.Value
+		}
+	}
+	require.Equal(t, 3., dropped)
+	require.Equal(t, 2., buffered)
+}
+
+func TestPoolResultSenderFlush(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSender := NewMockResultSender(ctrl)
+
+	var mu sync.Mutex
+	var sent int
+	mockSender.EXPECT().SendMetrics(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ms []agent.Metrics) error {
+			// отправка после отмены контекста агента
+			time.Sleep(50 * time.Millisecond)
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			sent += len(ms)
+			return nil
+		},
+	).AnyTimes()
+
+	cnf := &config.AgentConfiguration{
+		BatchSize:   3,
+		RateLimit:   2,
+		BatchLinger: 10,
+	}
+
+	sender := agent.NewPoolResultSender(cnf, mockSender)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	require.NoError(t, sender.SendMetrics(ctx, gaugeMetrics(10)))
+	cancelFn()
+	sender.Wait()
+
+	// все метрики отправлены до возврата из Wait
+	require.Equal(t, 10, sent)
+
+	err := sender.SendMetrics(ctx, gaugeMetrics(1))
+	require.Error(t, err)
+
+	var batches, errs int64
+	for _, m := range sender.GetMetrics() {
+		switch m.ID {
+		case "PoolBatchesSent":
+			batches += *m.Delta
+		case "PoolSendErrors":
+			errs += *m.Delta
+		}
+	}
+	require.Equal(t, int64(4), batches)
+	require.Equal(t, int64(0), errs)
+}
+
+func TestPoolResultSenderShutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSender := NewMockResultSender(ctrl)
+
+	var mu sync.Mutex
+	var sent int
+	mockSender.EXPECT().SendMetrics(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ms []agent.Metrics) error {
+			mu.Lock()
+			defer mu.Unlock()
+			sent += len(ms)
+			return nil
+		},
+	).Times(2)
+	mockSender.EXPECT().Stop()
+
+	cnf := &config.AgentConfiguration{
+		BatchSize:      3,
+		RateLimit:      1,
+		BatchLinger:    10,
+		PollInterval:   10,
+		ReportInterval: 10,
+	}
+
+	sender := agent.NewPoolResultSender(cnf, mockSender)
+	mockStorage := NewMockMetricStorage(ctrl)
+	mockStorage.EXPECT().Refresh().Return(nil)
+	mockStorage.EXPECT().GetMetrics().Return(gaugeMetrics(4))
+
+	client := agent.Create(cnf, sender, mockStorage)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	client.Start(ctx)
+
+	// последний отчет отправляется после отмены контекста, неполный пакет - без ожидания BatchLinger
+	cancelFn()
+	require.NoError(t, client.Shutdown())
+	require.Equal(t, 4, sent)
+
+	require.Error(t, sender.SendMetrics(ctx, gaugeMetrics(1)))
+}
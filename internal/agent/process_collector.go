@@ -0,0 +1,213 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// processInstance экземпляр процесса: pid может быть использован повторно, поэтому учитывается и время запуска
+type processInstance struct {
+	pid        int32
+	createTime int64
+}
+
+// watchedProcess отслеживаемый процесс и его состояние между опросами
+type watchedProcess struct {
+	config.ProcessConf
+	cmdline *regexp.Regexp
+
+	observed  bool                       // процесс уже опрашивался
+	cpuTimeMs map[processInstance]uint64 // время процессора экземпляров на момент предыдущего опроса
+	cpuTime   counterDelta
+	restarts  counterDelta
+}
+
+// processCollector собирает данные отслеживаемых процессов; метрики помечаются меткой process с именем из настроек.
+//
+//   - ProcessCount - количество найденных экземпляров;
+//   - ProcessRSS, ProcessOpenFDs, ProcessThreads - сумма по экземплярам, gauge;
+//   - ProcessCPUTimeMs - время процессора (user + system) в миллисекундах, counter;
+//   - ProcessRestarts - количество перезапусков (появлений нового pid), counter.
+//
+// Если экземпляров несколько (поиск по имени или командной строке), значения суммируются.
+type processCollector struct {
+	processes []*watchedProcess
+	gauges    []Metrics
+}
+
+func newProcessCollector(conf *config.AgentConfiguration) (Collector, error) {
+	processes, err := config.ParseProcesses(conf.Processes)
+	if err != nil {
+		return nil, err
+	}
+	if len(processes) == 0 {
+		return nil, nil
+	}
+
+	c := &processCollector{}
+	for _, p := range processes {
+		wp := &watchedProcess{ProcessConf: p}
+		if p.Match == config.ProcessMatchCmdline {
+			wp.cmdline = regexp.MustCompile(p.Pattern) // выражение проверено при разборе настроек
+		}
+		c.processes = append(c.processes, wp)
+	}
+	return c, nil
+}
+
+func (c *processCollector) Refresh() error {
+	var all []*process.Process
+	for _, wp := range c.processes {
+		if wp.Match != config.ProcessMatchPidfile {
+			var err error
+			if all, err = process.Processes(); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	var errs []error
+	var gauges []Metrics
+	for _, wp := range c.processes {
+		found, err := wp.find(all)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("process %v: %w", wp.Name, err))
+		}
+		gauges = append(gauges, wp.observe(found)...)
+	}
+	c.gauges = gauges
+
+	return errors.Join(errs...)
+}
+
+func (c *processCollector) GetMetrics() []Metrics {
+	metrics := cloneMetrics(c.gauges)
+	for _, wp := range c.processes {
+		labels := wp.labels()
+
+		cpuTime, sent := wp.cpuTime.Take()
+		metrics = append(metrics, Metrics{ID: "ProcessCPUTimeMs", MType: CounterType, Delta: &cpuTime, Labels: labels, sent: sent})
+
+		restarts, sent := wp.restarts.Take()
+		metrics = append(metrics, Metrics{ID: "ProcessRestarts", MType: CounterType, Delta: &restarts, Labels: labels, sent: sent})
+	}
+	return metrics
+}
+
+func (wp *watchedProcess) labels() map[string]string {
+	return map[string]string{"process": wp.Name}
+}
+
+// find возвращает экземпляры процесса; отсутствующий pid-файл или процесс - не ошибка
+func (wp *watchedProcess) find(all []*process.Process) ([]*process.Process, error) {
+	if wp.Match == config.ProcessMatchPidfile {
+		data, err := os.ReadFile(wp.Pattern)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		pid, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("wrong pidfile %v content", wp.Pattern)
+		}
+		if exists, err := process.PidExists(int32(pid)); err != nil || !exists {
+			return nil, err
+		}
+		p, err := process.NewProcess(int32(pid))
+		if err != nil {
+			return nil, nil
+		}
+		return []*process.Process{p}, nil
+	}
+
+	var found []*process.Process
+	for _, p := range all {
+		switch wp.Match {
+		case config.ProcessMatchName:
+			if name, err := p.Name(); err == nil && name == wp.Pattern {
+				found = append(found, p)
+			}
+		case config.ProcessMatchCmdline:
+			if cmdline, err := p.Cmdline(); err == nil && wp.cmdline.MatchString(cmdline) {
+				found = append(found, p)
+			}
+		}
+	}
+	return found, nil
+}
+
+// observe учитывает найденные экземпляры и возвращает gauge процесса.
+//
+// Данные экземпляра, завершившегося во время опроса, пропускаются.
+func (wp *watchedProcess) observe(found []*process.Process) []Metrics {
+	labels := wp.labels()
+
+	var rss, fds, threads float64
+	cpuTimeMs := make(map[processInstance]uint64, len(found))
+	var cpuDelta, started int64
+
+	for _, p := range found {
+		createTime, err := p.CreateTime()
+		if err != nil {
+			continue
+		}
+		mem, err := p.MemoryInfo()
+		if err != nil {
+			continue
+		}
+		times, err := p.Times()
+		if err != nil {
+			continue
+		}
+		threadCount, err := p.NumThreads()
+		if err != nil {
+			continue
+		}
+		// открытые файлы чужих процессов могут быть недоступны
+		fdCount, _ := p.NumFDs()
+
+		inst := processInstance{pid: p.Pid, createTime: createTime}
+		ms := uint64(math.Round((times.User + times.System) * 1000))
+		cpuTimeMs[inst] = ms
+
+		rss += float64(mem.RSS)
+		fds += float64(fdCount)
+		threads += float64(threadCount)
+
+		prev, ok := wp.cpuTimeMs[inst]
+		switch {
+		case ok && ms >= prev:
+			cpuDelta += int64(ms - prev)
+		case !ok && wp.observed:
+			// экземпляр запущен после предыдущего опроса
+			started++
+			cpuDelta += int64(ms)
+		}
+	}
+
+	wp.cpuTimeMs = cpuTimeMs
+	wp.observed = true
+	wp.cpuTime.Add(cpuDelta)
+	wp.restarts.Add(started)
+
+	gauges := []Metrics{labeledGauge("ProcessCount", labels, float64(len(cpuTimeMs)))}
+	if len(cpuTimeMs) > 0 {
+		gauges = append(gauges,
+			labeledGauge("ProcessRSS", labels, rss),
+			labeledGauge("ProcessOpenFDs", labels, fds),
+			labeledGauge("ProcessThreads", labels, threads),
+		)
+	}
+	return gauges
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func startSleep(t *testing.T) *exec.Cmd {
+	cmd := exec.Command("sleep", "30")
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	return cmd
+}
+
+func writePidfile(t *testing.T, path string, pid int) {
+	require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(pid)+"\n"), 0o644))
+}
+
+func processGauge(t *testing.T, metrics []Metrics, id, name string) float64 {
+	m := findMetric(metrics, id, map[string]string{"process": name})
+	require.NotNil(t, m, id)
+	return *m.Value
+}
+
+func processCounter(t *testing.T, metrics []Metrics, id, name string) int64 {
+	m := findMetric(metrics, id, map[string]string{"process": name})
+	require.NotNil(t, m, id)
+	return *m.Delta
+}
+
+func TestProcessCollector_Disabled(t *testing.T) {
+	c, err := newProcessCollector(&config.AgentConfiguration{})
+	require.NoError(t, err)
+	require.Nil(t, c)
+}
+
+func TestProcessCollector(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "self.pid")
+	writePidfile(t, pidfile, os.Getpid())
+
+	c, err := newProcessCollector(&config.AgentConfiguration{
+		Processes: "self=pidfile:" + pidfile +
+			";cmd=cmdline:" + regexp.QuoteMeta(os.Args[0]) +
+			";missing=name:no-such-process-name",
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Refresh())
+
+	metrics := c.GetMetrics()
+	for _, name := range []string{"self", "cmd"} {
+		require.Equal(t, 1.0, processGauge(t, metrics, "ProcessCount", name))
+		require.Greater(t, processGauge(t, metrics, "ProcessRSS", name), 0.0)
+		require.Greater(t, processGauge(t, metrics, "ProcessThreads", name), 0.0)
+		require.Greater(t, processGauge(t, metrics, "ProcessOpenFDs", name), 0.0)
+		require.Equal(t, int64(0), processCounter(t, metrics, "ProcessRestarts", name))
+	}
+
+	require.Equal(t, 0.0, processGauge(t, metrics, "ProcessCount", "missing"))
+	require.Nil(t, findMetric(metrics, "ProcessRSS", map[string]string{"process": "missing"}))
+
+	// нагружаем процессор, чтобы время процессора выросло
+	for sum, i := 0, 0; i < 50_000_000; i++ {
+		sum += i
+	}
+	require.NoError(t, c.Refresh())
+	metrics = c.GetMetrics()
+	require.Greater(t, processCounter(t, metrics, "ProcessCPUTimeMs", "self"), int64(0))
+}
+
+func TestProcessCollector_Restarts(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "sleep.pid")
+
+	first := startSleep(t)
+	writePidfile(t, pidfile, first.Process.Pid)
+
+	c, err := newProcessCollector(&config.AgentConfiguration{
+		Processes: "sleep=pidfile:" + pidfile,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Refresh())
+	require.Equal(t, int64(0), processCounter(t, c.GetMetrics(), "ProcessRestarts", "sleep"))
+
+	// процесс завершился
+	first.Process.Kill()
+	first.Wait()
+	require.NoError(t, c.Refresh())
+
+	metrics := c.GetMetrics()
+	require.Equal(t, 0.0, processGauge(t, metrics, "ProcessCount", "sleep"))
+	require.Equal(t, int64(0), processCounter(t, metrics, "ProcessRestarts", "sleep"))
+
+	// процесс запущен заново
+	second := startSleep(t)
+	writePidfile(t, pidfile, second.Process.Pid)
+	require.NoError(t, c.Refresh())
+
+	metrics = c.GetMetrics()
+	require.Equal(t, 1.0, processGauge(t, metrics, "ProcessCount", "sleep"))
+	require.Equal(t, int64(1), processCounter(t, metrics, "ProcessRestarts", "sleep"))
+
+	require.NoError(t, c.Refresh())
+	require.Equal(t, int64(0), processCounter(t, c.GetMetrics(), "ProcessRestarts", "sleep"))
+}
+
+func TestProcessCollector_Name(t *testing.T) {
+	cmd := startSleep(t)
+	startSleep(t)
+
+	c, err := newProcessCollector(&config.AgentConfiguration{
+		Processes: "sleep=name:sleep",
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Refresh())
+
+	// экземпляры суммируются
+	require.GreaterOrEqual(t, processGauge(t, c.GetMetrics(), "ProcessCount", "sleep"), 2.0)
+
+	cmd.Process.Kill()
+	cmd.Wait()
+	require.NoError(t, c.Refresh())
+	require.Equal(t, int64(0), processCounter(t, c.GetMetrics(), "ProcessRestarts", "sleep"))
+}
@@ -0,0 +1,277 @@
+package agent
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	pushMaxBodySize     = 10 << 20
+	pushReadTimeout     = time.Minute
+	pushShutdownTimeout = 5 * time.Second
+)
+
+// pushSeries накопленные значения ряда, полученные от приложений
+type pushSeries struct {
+	id        string
+	mType     MetricType
+	labels    map[string]string
+	value     float64         // gauge - последнее значение
+	delta     counterDelta    // counter - сумма приращений
+	histogram *histogramDelta // histogram - сумма наблюдений
+}
+
+// pushReceiver принимает метрики от приложений на том же хосте (режим sidecar).
+//
+// POST /updates/ принимает тот же JSON, что и сервер; тело может быть сжато gzip.
+// Полученные метрики отправляются на сервер вместе с собранными агентом: для gauge - последнее значение,
+// для counter и histogram - накопленные приращения. Поэтому на них распространяются пакетная отправка,
+// повторы, подпись и шифрование агента.
+type pushReceiver struct {
+	address string // TCP-адрес
+	socket  string // путь к unix-сокету
+
+	mu     sync.Mutex
+	series map[string]*pushSeries
+
+	listeners []net.Listener
+	server    *http.Server
+}
+
+func NewPushReceiver(conf *config.AgentConfiguration) *pushReceiver {
+	pr := &pushReceiver{
+		address: conf.PushAddress,
+		socket:  conf.PushSocket,
+		series:  make(map[string]*pushSeries),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /updates/", pr.Updates)
+	pr.server = &http.Server{
+		Handler:     mux,
+		ReadTimeout: pushReadTimeout,
+	}
+	return pr
+}
+
+// Listen открывает TCP-адрес и unix-сокет из настроек.
+func (pr *pushReceiver) Listen() error {
+	if pr.address != "" {
+		l, err := net.Listen("tcp", pr.address)
+		if err != nil {
+			return fmt.Errorf("push listen %v: %w", pr.address, err)
+		}
+		pr.listeners = append(pr.listeners, l)
+	}
+
+	if pr.socket != "" {
+		// сокет мог остаться после аварийного завершения агента
+		if err := os.Remove(pr.socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+			pr.Close()
+			return fmt.Errorf("push socket %v: %w", pr.socket, err)
+		}
+		l, err := net.Listen("unix", pr.socket)
+		if err != nil {
+			pr.Close()
+			return fmt.Errorf("push listen %v: %w", pr.socket, err)
+		}
+		pr.listeners = append(pr.listeners, l)
+	}
+	return nil
+}
+
+// Addrs возвращает открытые адреса.
+func (pr *pushReceiver) Addrs() []net.Addr {
+	var addrs []net.Addr
+	for _, l := range pr.listeners {
+		addrs = append(addrs, l.Addr())
+	}
+	return addrs
+}
+
+func (pr *pushReceiver) Close() {
+	for _, l := range pr.listeners {
+		l.Close()
+	}
+}
+
+// Serve принимает запросы до отмены ctx.
+func (pr *pushReceiver) Serve(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, l := range pr.listeners {
+		wg.Add(1)
+		go func(l net.Listener) {
+			defer wg.Done()
+			if err := pr.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logrus.Errorf("push receiver %v error: %v", l.Addr(), err)
+			}
+		}(l)
+	}
+
+	<-ctx.Done()
+	shutdownCtx, cancelFn := context.WithTimeout(context.Background(), pushShutdownTimeout)
+	defer cancelFn()
+	if err := pr.server.Shutdown(shutdownCtx); err != nil {
+		logrus.Errorf("push receiver shutdown error: %v", err)
+	}
+	wg.Wait()
+	logrus.Info("push receiver DONE")
+}
+
+// Updates принимает массив метрик; при ошибке в любой метрике запрос отклоняется целиком.
+func (pr *pushReceiver) Updates(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		http.Error(w, "only application/json supported", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, req.Body, pushMaxBodySize)
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	var metrics []Metrics
+	if err := json.NewDecoder(body).Decode(&metrics); err != nil {
+		http.Error(w, fmt.Sprintf("json decode error - %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := pr.add(metrics); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (pr *pushReceiver) add(metrics []Metrics) error {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	// границы новых рядов сравниваются и с предыдущими метриками запроса
+	bounds := make(map[string][]float64)
+	for i := range metrics {
+		if err := pr.check(&metrics[i], bounds); err != nil {
+			return fmt.Errorf("metric %d: %w", i, err)
+		}
+	}
+
+	for _, m := range metrics {
+		series := pr.getSeries(&m)
+		switch m.MType {
+		case GaugeType:
+			series.value = *m.Value
+		case CounterType:
+			series.delta.Add(*m.Delta)
+		case HistogramType:
+			series.histogram.add(m.Histogram)
+		}
+	}
+	return nil
+}
+
+func (pr *pushReceiver) check(m *Metrics, bounds map[string][]float64) error {
+	if !metricNameRegexp.MatchString(m.ID) {
+		return fmt.Errorf("wrong metric ID %q", m.ID)
+	}
+	for k := range m.Labels {
+		if !labelNameRegexp.MatchString(k) {
+			return fmt.Errorf("wrong label name %q", k)
+		}
+	}
+
+	switch m.MType {
+	case GaugeType:
+		if m.Value == nil {
+			return fmt.Errorf("gauge %v without value", m.ID)
+		}
+	case CounterType:
+		if m.Delta == nil {
+			return fmt.Errorf("counter %v without delta", m.ID)
+		}
+	case HistogramType:
+		h := m.Histogram
+		if h == nil {
+			return fmt.Errorf("histogram %v without value", m.ID)
+		}
+		if err := h.Check(); err != nil {
+			return fmt.Errorf("wrong histogram %v: %w", m.ID, err)
+		}
+
+		key := seriesKey(string(m.MType)+":"+m.ID, m.Labels)
+		seriesBounds, ok := bounds[key]
+		if !ok {
+			if series, exists := pr.series[key]; exists {
+				seriesBounds, ok = series.histogram.bounds, true
+			}
+		}
+		if ok && !slices.Equal(seriesBounds, h.Bounds) {
+			return fmt.Errorf("histogram %v bounds changed", m.ID)
+		}
+		bounds[key] = h.Bounds
+	default:
+		return fmt.Errorf("unknown metric type %q", m.MType)
+	}
+	return nil
+}
+
+func (pr *pushReceiver) getSeries(m *Metrics) *pushSeries {
+	key := seriesKey(string(m.MType)+":"+m.ID, m.Labels)
+	series, ok := pr.series[key]
+	if !ok {
+		series = &pushSeries{id: m.ID, mType: m.MType, labels: m.Labels}
+		if m.MType == HistogramType {
+			series.histogram = newHistogramDelta(m.Histogram.Bounds)
+		}
+		pr.series[key] = series
+	}
+	return series
+}
+
+func (pr *pushReceiver) Refresh() error {
+	return nil
+}
+
+func (pr *pushReceiver) GetMetrics() []Metrics {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	metrics := make([]Metrics, 0, len(pr.series))
+	for _, series := range pr.series {
+		m := Metrics{ID: series.id, MType: series.mType, Labels: series.labels}
+		switch series.mType {
+		case GaugeType:
+			value := series.value
+			m.Value = &value
+		case CounterType:
+			delta, sent := series.delta.Take()
+			m.Delta, m.sent = &delta, sent
+		case HistogramType:
+			m.Histogram, m.sent = series.histogram.Take()
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func startPushReceiver(t *testing.T, conf *config.AgentConfiguration) *pushReceiver {
+	t.Helper()
+	receiver := NewPushReceiver(conf)
+	require.NoError(t, receiver.Listen())
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		receiver.Serve(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancelFn()
+		<-done
+	})
+	return receiver
+}
+
+func pushPost(t *testing.T, client *http.Client, url string, body []byte, gz bool) int {
+	t.Helper()
+	if gz {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write(body)
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if gz {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestPushReceiver(t *testing.T) {
+	receiver := startPushReceiver(t, &config.AgentConfiguration{PushAddress: "127.0.0.1:0"})
+	url := "http://" + receiver.Addrs()[0].String() + "/updates/"
+
+	body := `[
+		{"id":"Jobs","type":"gauge","value":1.5,"labels":{"queue":"a"}},
+		{"id":"Jobs","type":"gauge","value":2.5,"labels":{"queue":"a"}},
+		{"id":"Processed","type":"counter","delta":3},
+		{"id":"Latency","type":"histogram","histogram":{"bounds":[1,2],"counts":[1,0,1],"sum":3.5,"count":2}}
+	]`
+	require.Equal(t, http.StatusOK, pushPost(t, http.DefaultClient, url, []byte(body), false))
+	require.Equal(t, http.StatusOK, pushPost(t, http.DefaultClient, url, []byte(`[{"id":"Processed","type":"counter","delta":4}]`), true))
+
+	metrics := receiver.GetMetrics()
+	require.Len(t, metrics, 3)
+
+	gauge := findMetric(metrics, "Jobs", map[string]string{"queue": "a"})
+	require.NotNil(t, gauge)
+	require.Equal(t, 2.5, *gauge.Value)
+
+	counter := findMetric(metrics, "Processed", nil)
+	require.NotNil(t, counter)
+	require.Equal(t, int64(7), *counter.Delta)
+
+	histogram := findMetric(metrics, "Latency", nil)
+	require.NotNil(t, histogram)
+	require.Equal(t, int64(2), histogram.Histogram.Count)
+
+	// неотправленные приращения возвращаются в следующий отчет
+	settleAll(metrics, errors.New("test err"))
+	require.Equal(t, http.StatusOK, pushPost(t, http.DefaultClient, url, []byte(`[{"id":"Processed","type":"counter","delta":1}]`), false))
+
+	metrics = receiver.GetMetrics()
+	require.Equal(t, int64(8), *findMetric(metrics, "Processed", nil).Delta)
+	require.Equal(t, int64(2), findMetric(metrics, "Latency", nil).Histogram.Count)
+
+	settleAll(metrics, nil)
+	metrics = receiver.GetMetrics()
+	require.Equal(t, int64(0), *findMetric(metrics, "Processed", nil).Delta)
+	require.Equal(t, 2.5, *findMetric(metrics, "Jobs", map[string]string{"queue": "a"}).Value)
+}
+
+func TestPushReceiver_BadRequest(t *testing.T) {
+	receiver := startPushReceiver(t, &config.AgentConfiguration{PushAddress: "127.0.0.1:0"})
+	url := "http://" + receiver.Addrs()[0].String() + "/updates/"
+
+	testCases := []struct {
+		name string
+		body string
+	}{
+		{"json", `{`},
+		{"id", `[{"id":"1Jobs","type":"gauge","value":1}]`},
+		{"label", `[{"id":"Jobs","type":"gauge","value":1,"labels":{"a-b":"c"}}]`},
+		{"type", `[{"id":"Jobs","type":"summary","value":1}]`},
+		{"value", `[{"id":"Jobs","type":"gauge"}]`},
+		{"delta", `[{"id":"Jobs","type":"counter"}]`},
+		{"histogram", `[{"id":"Latency","type":"histogram","histogram":{"bounds":[1,2],"counts":[1],"count":1}}]`},
+		{"histogram without value", `[{"id":"Latency","type":"histogram"}]`},
+		{"histogram repeated bounds", `[{"id":"Latency","type":"histogram","histogram":{"bounds":[1,1],"counts":[1,0,0],"sum":1,"count":1}}]`},
+		{"histogram unsorted bounds", `[{"id":"Latency","type":"histogram","histogram":{"bounds":[2,1],"counts":[1,0,0],"sum":1,"count":1}}]`},
+		{"histogram negative count", `[{"id":"Latency","type":"histogram","histogram":{"bounds":[1],"counts":[2,-1],"sum":1,"count":1}}]`},
+		{"histogram count", `[{"id":"Latency","type":"histogram","histogram":{"bounds":[1],"counts":[1,1],"sum":1,"count":3}}]`},
+		{"histogram bounds in request", `[
+			{"id":"Latency","type":"histogram","histogram":{"bounds":[1],"counts":[1,0],"sum":1,"count":1}},
+			{"id":"Latency","type":"histogram","histogram":{"bounds":[2],"counts":[1,0],"sum":1,"count":1}}
+		]`},
+		{"partial", `[{"id":"Ok","type":"counter","delta":1},{"id":"Jobs","type":"gauge"}]`},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, http.StatusBadRequest, pushPost(t, http.DefaultClient, url, []byte(test.body), false))
+		})
+	}
+
+	// границы гистограммы ряда не меняются
+	require.Equal(t, http.StatusOK, pushPost(t, http.DefaultClient, url,
+		[]byte(`[{"id":"Latency","type":"histogram","histogram":{"bounds":[1],"counts":[1,0],"sum":1,"count":1}}]`), false))
+	require.Equal(t, http.StatusBadRequest, pushPost(t, http.DefaultClient, url,
+		[]byte(`[{"id":"Latency","type":"histogram","histogram":{"bounds":[2],"counts":[1,0],"sum":1,"count":1}}]`), false))
+
+	metrics := receiver.GetMetrics()
+	require.Len(t, metrics, 1)
+	require.Equal(t, "Latency", metrics[0].ID)
+}
+
+func TestPushReceiver_Socket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	receiver := startPushReceiver(t, &config.AgentConfiguration{PushSocket: socket})
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	body := strings.NewReader(`[{"id":"Jobs","type":"gauge","value":1}]`)
+	resp, err := client.Post("http://agent/updates/", "application/json", body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.NotNil(t, findMetric(receiver.GetMetrics(), "Jobs", nil))
+}
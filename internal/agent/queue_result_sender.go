@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queueSegmentSize     = 4 << 20
+	queueFirstRetryDelay = time.Second
+	queueMaxRetryDelay   = 30 * time.Second
+	queueFlushInterval   = 100 * time.Millisecond
+)
+
+// queuedBatch пакет в очереди; ключ пакета сохраняется, чтобы сервер не учел повтор после перезапуска агента дважды
+type queuedBatch struct {
+	Key     string    `json:"key"`
+	Metrics []Metrics `json:"metrics"`
+}
+
+func NewQueueResultSender(conf *config.AgentConfiguration, resultSender ResultSender) (*queueResultSender, error) {
+	queue, err := openDiskQueue(conf.QueueDir, int64(conf.QueueMaxSize)<<20, queueSegmentSize)
+	if err != nil {
+		return nil, err
+	}
+	return newQueueResultSender(queue, resultSender), nil
+}
+
+func newQueueResultSender(queue *diskQueue, resultSender ResultSender) *queueResultSender {
+	return &queueResultSender{
+		queue:           queue,
+		sender:          resultSender,
+		notify:          make(chan struct{}, 1),
+		firstRetryDelay: queueFirstRetryDelay,
+		maxRetryDelay:   queueMaxRetryDelay,
+	}
+}
+
+// queueResultSender сохраняет пакеты в очередь на диске и отправляет их по порядку.
+//
+// SendMetrics возвращает управление после сохранения пакета на диск: приращения counter и histogram
+// считаются отправленными. Если очередь заполнена, возвращается ErrQueueFull и приращения остаются у источника.
+// Пакет удаляется из очереди после успешной отправки; при ошибке отправка первого пакета повторяется
+// с увеличивающейся задержкой. Пакет, отклоненный сервером, удаляется из очереди без повтора,
+// чтобы не задерживать следующие пакеты; количество таких пакетов возвращает GetMetrics.
+// Пакеты, не отправленные до завершения агента, отправляются после перезапуска.
+type queueResultSender struct {
+	queue           *diskQueue
+	sender          ResultSender
+	notify          chan struct{}
+	startDrainOnce  sync.Once
+	firstRetryDelay time.Duration
+	maxRetryDelay   time.Duration
+	rejected        counterDelta
+}
+
+func (rs *queueResultSender) Stop() {
+	rs.sender.Stop()
+	if err := rs.queue.Close(); err != nil {
+		logrus.Errorf("send queue close error: %v", err)
+	}
+}
+
+// Flush ожидает отправки пакетов из очереди; неотправленные пакеты остаются на диске.
+func (rs *queueResultSender) Flush(ctx context.Context) error {
+	// очередь, оставшаяся от предыдущего запуска, отправляется и без новых пакетов
+	rs.startDrainOnce.Do(func() {
+		go rs.drain(ctx)
+	})
+
+	ticker := time.NewTicker(queueFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		count := rs.queue.Len()
+		if count == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d batches are left in send queue: %w", count, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func (rs *queueResultSender) SendMetrics(ctx context.Context, metrics []Metrics) error {
+	rs.startDrainOnce.Do(func() {
+		go rs.drain(ctx)
+	})
+
+	key := BatchKey(ctx)
+	if key == "" {
+		key = uuid.NewString()
+	}
+	data, err := json.Marshal(queuedBatch{Key: key, Metrics: metrics})
+	if err != nil {
+		return err
+	}
+
+	if err := rs.queue.Push(data); err != nil {
+		logrus.Errorf("send queue push error: %v", err)
+		return err
+	}
+
+	select {
+	case rs.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (rs *queueResultSender) drain(ctx context.Context) {
+	delay := rs.firstRetryDelay
+	for {
+		err := rs.sendFirst(ctx)
+		switch {
+		case err == nil:
+			delay = rs.firstRetryDelay
+			continue
+		case errors.Is(err, errQueueEmpty):
+			select {
+			case <-ctx.Done():
+				logrus.Info("send queue DONE")
+				return
+			case <-rs.notify:
+				continue
+			}
+		case errors.Is(err, errQueueClosed):
+			return
+		}
+
+		logrus.Warnf("send queue error: %v, retry in %v, queued batches %d", err, delay, rs.queue.Len())
+		select {
+		case <-ctx.Done():
+			logrus.Info("send queue DONE")
+			return
+		case <-time.After(delay):
+			delay = min(2*delay, rs.maxRetryDelay)
+		}
+	}
+}
+
+// sendFirst отправляет первый пакет очереди.
+func (rs *queueResultSender) sendFirst(ctx context.Context) error {
+	data, err := rs.queue.Peek()
+	if err != nil {
+		return err
+	}
+
+	var batch queuedBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		// запись прошла проверку контрольной суммы, повтор не поможет
+		logrus.Errorf("send queue drop batch: %v", err)
+		return rs.queue.Ack()
+	}
+
+	err = rs.sender.SendMetrics(WithBatchKey(ctx, batch.Key), batch.Metrics)
+	if isRejected(err) {
+		logrus.Errorf("send queue drop batch %v of %d metrics rejected by server: %v", batch.Key, len(batch.Metrics), err)
+		rs.rejected.Add(1)
+		return rs.queue.Ack()
+	}
+	if err != nil {
+		return err
+	}
+	return rs.queue.Ack()
+}
+
+func (rs *queueResultSender) Refresh() error {
+	return nil
+}
+
+// GetMetrics возвращает количество пакетов, отклоненных сервером, и количество пакетов в очереди.
+func (rs *queueResultSender) GetMetrics() []Metrics {
+	return []Metrics{
+		takeCounter("QueueRejectedBatches", nil, &rs.rejected),
+		labeledGauge("QueueBatches", nil, float64(rs.queue.Len())),
+	}
+}
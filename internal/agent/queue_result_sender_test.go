@@ -0,0 +1,244 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recordingSender запоминает отправленные пакеты
+type recordingSender struct {
+	mu      sync.Mutex
+	fail    bool
+	ids     []string
+	keys    []string
+	stopped bool
+}
+
+func (s *recordingSender) setFail(fail bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = fail
+}
+
+func (s *recordingSender) SendMetrics(ctx context.Context, metrics []Metrics) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("test err")
+	}
+	for _, m := range metrics {
+		s.ids = append(s.ids, m.ID)
+	}
+	s.keys = append(s.keys, BatchKey(ctx))
+	return nil
+}
+
+func (s *recordingSender) sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ids...)
+}
+
+func (s *recordingSender) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+}
+
+func gaugeBatch(ids ...string) []Metrics {
+	var metrics []Metrics
+	for _, id := range ids {
+		value := 1.
+		metrics = append(metrics, Metrics{ID: id, MType: GaugeType, Value: &value})
+	}
+	return metrics
+}
+
+func TestQueueResultSender(t *testing.T) {
+	dir := t.TempDir()
+
+	queue, err := openDiskQueue(dir, 1<<20, queueSegmentSize)
+	require.NoError(t, err)
+
+	sender := &recordingSender{fail: true}
+	rs := newQueueResultSender(queue, sender)
+	rs.firstRetryDelay = 10 * time.Millisecond
+	rs.maxRetryDelay = 20 * time.Millisecond
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	// сервер недоступен - пакеты накапливаются в очереди
+	require.NoError(t, rs.SendMetrics(WithBatchKey(ctx, "batch-1"), gaugeBatch("A", "B")))
+	require.NoError(t, rs.SendMetrics(ctx, gaugeBatch("C")))
+	time.Sleep(50 * time.Millisecond)
+	require.Empty(t, sender.sent())
+	require.Equal(t, 2, queue.Len())
+
+	// сервер доступен - пакеты отправляются по порядку
+	sender.setFail(false)
+	require.Eventually(t, func() bool {
+		return queue.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"A", "B", "C"}, sender.sent())
+	require.Equal(t, "batch-1", sender.keys[0])
+	require.NotEmpty(t, sender.keys[1])
+
+	rs.Stop()
+	require.True(t, sender.stopped)
+}
+
+func TestQueueResultSender_Restart(t *testing.T) {
+	dir := t.TempDir()
+
+	queue, err := openDiskQueue(dir, 1<<20, queueSegmentSize)
+	require.NoError(t, err)
+	sender := &recordingSender{fail: true}
+	rs := newQueueResultSender(queue, sender)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	require.NoError(t, rs.SendMetrics(WithBatchKey(ctx, "batch-1"), gaugeBatch("A")))
+	require.NoError(t, rs.SendMetrics(WithBatchKey(ctx, "batch-2"), gaugeBatch("B")))
+	cancelFn()
+	rs.Stop()
+
+	// пакеты, не отправленные до завершения агента, отправляются после перезапуска
+	queue, err = openDiskQueue(dir, 1<<20, queueSegmentSize)
+	require.NoError(t, err)
+	require.Equal(t, 2, queue.Len())
+
+	sender = &recordingSender{}
+	rs = newQueueResultSender(queue, sender)
+	ctx, cancelFn = context.WithCancel(context.Background())
+	defer cancelFn()
+
+	require.NoError(t, rs.SendMetrics(ctx, gaugeBatch("C")))
+	require.Eventually(t, func() bool {
+		return queue.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"A", "B", "C"}, sender.sent())
+	require.Equal(t, []string{"batch-1", "batch-2"}, sender.keys[:2])
+	rs.Stop()
+}
+
+func TestQueueResultSender_Full(t *testing.T) {
+	queue, err := openDiskQueue(t.TempDir(), 100, queueSegmentSize)
+	require.NoError(t, err)
+
+	sender := &recordingSender{fail: true}
+	rs := newQueueResultSender(queue, sender)
+	defer rs.Stop()
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	// очередь заполнена - приращения остаются у источника
+	var pollCount counterDelta
+	pollCount.Add(5)
+	delta, sent := pollCount.Take()
+	metrics := []Metrics{{ID: "PollCount", MType: CounterType, Delta: &delta, sent: sent}}
+
+	err = rs.SendMetrics(ctx, metrics)
+	require.ErrorIs(t, err, ErrQueueFull)
+	settleAll(metrics, err)
+
+	delta, _ = pollCount.Take()
+	require.Equal(t, int64(5), delta)
+}
+
+func TestQueueResultSender_Flush(t *testing.T) {
+	queue, err := openDiskQueue(t.TempDir(), 1<<20, queueSegmentSize)
+	require.NoError(t, err)
+
+	sender := &recordingSender{fail: true}
+	rs := newQueueResultSender(queue, sender)
+	rs.firstRetryDelay = 10 * time.Millisecond
+	rs.maxRetryDelay = 20 * time.Millisecond
+	defer rs.Stop()
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	require.NoError(t, rs.SendMetrics(ctx, gaugeBatch("A")))
+
+	// сервер недоступен - пакет остается в очереди
+	flushCtx, flushCancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer flushCancel()
+	err = rs.Flush(flushCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, 1, queue.Len())
+
+	sender.setFail(false)
+	require.NoError(t, rs.Flush(ctx))
+	require.Equal(t, []string{"A"}, sender.sent())
+}
+
+// rejectingSender отклоняет пакеты с метрикой Bad
+type rejectingSender struct {
+	recordingSender
+}
+
+func (s *rejectingSender) SendMetrics(ctx context.Context, metrics []Metrics) error {
+	for _, m := range metrics {
+		if m.ID == "Bad" {
+			return fmt.Errorf("%w: unexpected server http response code: 400", ErrServerRejected)
+		}
+	}
+	return s.recordingSender.SendMetrics(ctx, metrics)
+}
+
+func TestQueueResultSender_Rejected(t *testing.T) {
+	queue, err := openDiskQueue(t.TempDir(), 1<<20, queueSegmentSize)
+	require.NoError(t, err)
+
+	sender := &rejectingSender{}
+	rs := newQueueResultSender(queue, sender)
+	rs.firstRetryDelay = time.Hour
+	defer rs.Stop()
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	// отклоненный пакет удаляется из очереди и не задерживает следующие
+	require.NoError(t, rs.SendMetrics(ctx, gaugeBatch("A")))
+	require.NoError(t, rs.SendMetrics(ctx, gaugeBatch("Bad")))
+	require.NoError(t, rs.SendMetrics(ctx, gaugeBatch("C")))
+	require.Eventually(t, func() bool {
+		return queue.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"A", "C"}, sender.sent())
+
+	metrics := rs.GetMetrics()
+	require.Equal(t, int64(1), *findMetric(metrics, "QueueRejectedBatches", nil).Delta)
+	require.Equal(t, 0., *findMetric(metrics, "QueueBatches", nil).Value)
+}
+
+func TestIsRejected(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		rejected bool
+	}{
+		{"http", fmt.Errorf("%w: code 400", ErrServerRejected), true},
+		{"wrapped", fmt.Errorf("server a: %w", fmt.Errorf("%w: code 400", ErrServerRejected)), true},
+		{"grpc invalid argument", status.Error(codes.InvalidArgument, ""), true},
+		{"grpc unavailable", status.Error(codes.Unavailable, ""), false},
+		{"connection", syscall.ECONNREFUSED, false},
+		{"other", errors.New("unexpected server http response code: 500"), false},
+		{"nil", nil, false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.rejected, isRejected(test.err))
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -34,7 +35,12 @@ type httpRetriableResultSender struct {
 	sender  ResultSender
 }
 
+// SendMetrics отправляет пакет с повторами; все попытки используют один ключ пакета,
+// поэтому пакет, принятый сервером до разрыва соединения, не будет учтен дважды.
 func (h *httpRetriableResultSender) SendMetrics(ctx context.Context, metrics []Metrics) error {
+	if BatchKey(ctx) == "" {
+		ctx = WithBatchKey(ctx, uuid.NewString())
+	}
 	fn := func(ctx context.Context) error {
 		return h.sender.SendMetrics(ctx, metrics)
 	}
@@ -0,0 +1,244 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/config"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// memCollector собирает данные о памяти системы.
+type memCollector struct {
+	gauges map[string]float64
+}
+
+func newMemCollector(conf *config.AgentConfiguration) (Collector, error) {
+	return &memCollector{}, nil
+}
+
+func (c *memCollector) Refresh() error {
+	v, err := mem.VirtualMemory()
+	if err != nil {
+		return err
+	}
+	c.gauges = map[string]float64{
+		"TotalMemory": float64(v.Total),
+		"FreeMemory":  float64(v.Free),
+	}
+	return nil
+}
+
+func (c *memCollector) GetMetrics() []Metrics {
+	return gaugeMetrics(c.gauges)
+}
+
+// cpuCollector собирает загрузку процессора: CPUutilizationN - по ядрам (с 1), CPUutilizationTotal - общую.
+type cpuCollector struct {
+	gauges map[string]float64
+}
+
+func newCPUCollector(conf *config.AgentConfiguration) (Collector, error) {
+	return &cpuCollector{}, nil
+}
+
+func (c *cpuCollector) Refresh() error {
+	perCPU, err := cpu.Percent(0, true)
+	if err != nil {
+		return err
+	}
+	total, err := cpu.Percent(0, false)
+	if err != nil {
+		return err
+	}
+
+	c.gauges = make(map[string]float64, len(perCPU)+1)
+	for i, v := range perCPU {
+		c.gauges["CPUutilization"+strconv.Itoa(i+1)] = v
+	}
+	if len(total) > 0 {
+		c.gauges["CPUutilizationTotal"] = total[0]
+	}
+	return nil
+}
+
+func (c *cpuCollector) GetMetrics() []Metrics {
+	return gaugeMetrics(c.gauges)
+}
+
+// diskCollector собирает заполнение файловых систем (метка mount) и ввод-вывод их устройств (метки mount и device).
+// Ошибка получения данных одной файловой системы или устройства не мешает сбору остальных.
+type diskCollector struct {
+	gauges     []Metrics
+	counters   cumulativeCounters
+	partitions func(all bool) ([]disk.PartitionStat, error)
+	usage      func(path string) (*disk.UsageStat, error)
+	ioCounters func(names ...string) (map[string]disk.IOCountersStat, error)
+}
+
+func newDiskCollector(conf *config.AgentConfiguration) (Collector, error) {
+	return &diskCollector{
+		partitions: disk.Partitions,
+		usage:      disk.Usage,
+		ioCounters: disk.IOCounters,
+	}, nil
+}
+
+func (c *diskCollector) Refresh() error {
+	partitions, err := c.partitions(false)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	var gauges []Metrics
+	for _, p := range partitions {
+		labels := map[string]string{"mount": p.Mountpoint}
+		if usage, err := c.usage(p.Mountpoint); err != nil {
+			errs = append(errs, fmt.Errorf("mount %v: %w", p.Mountpoint, err))
+		} else {
+			gauges = append(gauges,
+				labeledGauge("DiskTotal", labels, float64(usage.Total)),
+				labeledGauge("DiskUsed", labels, float64(usage.Used)),
+				labeledGauge("DiskFree", labels, float64(usage.Free)),
+				labeledGauge("DiskUsedPercent", labels, usage.UsedPercent),
+			)
+		}
+
+		device := filepath.Base(p.Device)
+		ioCounters, err := c.ioCounters(device)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("device %v: %w", device, err))
+			continue
+		}
+		io, ok := ioCounters[device]
+		if !ok {
+			continue
+		}
+		ioLabels := map[string]string{"mount": p.Mountpoint, "device": device}
+		c.counters.Observe("DiskReadBytes", ioLabels, io.ReadBytes)
+		c.counters.Observe("DiskWriteBytes", ioLabels, io.WriteBytes)
+		c.counters.Observe("DiskReadCount", ioLabels, io.ReadCount)
+		c.counters.Observe("DiskWriteCount", ioLabels, io.WriteCount)
+	}
+	c.gauges = gauges
+
+	return errors.Join(errs...)
+}
+
+func (c *diskCollector) GetMetrics() []Metrics {
+	return append(cloneMetrics(c.gauges), c.counters.GetMetrics()...)
+}
+
+// netCollector собирает счетчики сетевых интерфейсов (метка interface).
+type netCollector struct {
+	counters cumulativeCounters
+}
+
+func newNetCollector(conf *config.AgentConfiguration) (Collector, error) {
+	return &netCollector{}, nil
+}
+
+func (c *netCollector) Refresh() error {
+	ioCounters, err := net.IOCounters(true)
+	if err != nil {
+		return err
+	}
+
+	for _, io := range ioCounters {
+		labels := map[string]string{"interface": io.Name}
+		c.counters.Observe("NetBytesSent", labels, io.BytesSent)
+		c.counters.Observe("NetBytesRecv", labels, io.BytesRecv)
+		c.counters.Observe("NetPacketsSent", labels, io.PacketsSent)
+		c.counters.Observe("NetPacketsRecv", labels, io.PacketsRecv)
+		c.counters.Observe("NetErrIn", labels, io.Errin)
+		c.counters.Observe("NetErrOut", labels, io.Errout)
+		c.counters.Observe("NetDropIn", labels, io.Dropin)
+		c.counters.Observe("NetDropOut", labels, io.Dropout)
+	}
+	return nil
+}
+
+func (c *netCollector) GetMetrics() []Metrics {
+	return c.counters.GetMetrics()
+}
+
+// loadCollector собирает среднюю загрузку системы.
+type loadCollector struct {
+	gauges map[string]float64
+}
+
+func newLoadCollector(conf *config.AgentConfiguration) (Collector, error) {
+	return &loadCollector{}, nil
+}
+
+func (c *loadCollector) Refresh() error {
+	avg, err := load.Avg()
+	if err != nil {
+		return err
+	}
+	c.gauges = map[string]float64{
+		"LoadAverage1":  avg.Load1,
+		"LoadAverage5":  avg.Load5,
+		"LoadAverage15": avg.Load15,
+	}
+	return nil
+}
+
+func (c *loadCollector) GetMetrics() []Metrics {
+	return gaugeMetrics(c.gauges)
+}
+
+// uptimeCollector собирает время работы системы в секундах.
+type uptimeCollector struct {
+	gauges map[string]float64
+}
+
+func newUptimeCollector(conf *config.AgentConfiguration) (Collector, error) {
+	return &uptimeCollector{}, nil
+}
+
+func (c *uptimeCollector) Refresh() error {
+	uptime, err := host.Uptime()
+	if err != nil {
+		return err
+	}
+	c.gauges = map[string]float64{
+		"Uptime": float64(uptime),
+	}
+	return nil
+}
+
+func (c *uptimeCollector) GetMetrics() []Metrics {
+	return gaugeMetrics(c.gauges)
+}
+
+// gaugeMetrics преобразует значения gauge в метрики.
+func gaugeMetrics(gauges map[string]float64) []Metrics {
+	metrics := make([]Metrics, 0, len(gauges))
+	for k, v := range gauges {
+		value := v
+		metrics = append(metrics, Metrics{
+			ID:    k,
+			MType: GaugeType,
+			Value: &value,
+		})
+	}
+	return metrics
+}
+
+func labeledGauge(id string, labels map[string]string, value float64) Metrics {
+	return Metrics{
+		ID:     id,
+		MType:  GaugeType,
+		Value:  &value,
+		Labels: labels,
+	}
+}
@@ -5,7 +5,13 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"math"
 	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env"
@@ -32,22 +38,58 @@ func (d *Duration) UnmarshalJSON(b []byte) error {
 }
 
 type agentFileConf struct {
-	Address        string   `json:"address"`
-	ReportInterval Duration `json:"report_interval"`
-	PoolInterval   Duration `json:"poll_interval"`
-	CryptoKey      string   `json:"crypto_key"`
-	UseGRPC        bool     `json:"use_grpc"`
+	Address          string    `json:"address"`
+	ReportInterval   Duration  `json:"report_interval"`
+	PoolInterval     Duration  `json:"poll_interval"`
+	CryptoKey        string    `json:"crypto_key"`
+	UseGRPC          bool      `json:"use_grpc"`
+	HistogramBuckets []float64 `json:"histogram_buckets"`
+	Labels           string    `json:"labels"`
+	Collectors       string    `json:"collectors"`
+	CgroupPath       string    `json:"cgroup_path"`
+	Processes        string    `json:"processes"`
+	ExecCommands     string    `json:"exec_commands"`
+	ExecTimeout      Duration  `json:"exec_timeout"`
+	ExecConcurrency  int       `json:"exec_concurrency"`
+	PushAddress      string    `json:"push_address"`
+	PushSocket       string    `json:"push_socket"`
+	QueueDir         string    `json:"queue_dir"`
+	QueueMaxSize     int       `json:"queue_max_size"`
+	Aggregates       string    `json:"aggregates"`
+	BatchLinger      Duration  `json:"batch_linger"`
+	PoolBufferSize   int       `json:"pool_buffer_size"`
+	PoolBufferPolicy string    `json:"pool_buffer_policy"`
+	ShutdownTimeout  Duration  `json:"shutdown_timeout"`
+	ServerSelection  string    `json:"server_selection"`
 }
 
 type AgentConfiguration struct {
-	ServerAddr     string `env:"ADDRESS"`
-	PollInterval   int    `env:"POLL_INTERVAL"`
-	ReportInterval int    `env:"REPORT_INTERVAL"`
-	Key            string `env:"KEY"`
-	BatchSize      int    `env:"BATCH_SIZE"`
-	RateLimit      int    `env:"RATE_LIMIT"`
-	CryptoKey      string `env:"CRYPTO_KEY"`
-	UseGRPC        bool   `env:"USE_GRPC"`
+	ServerAddr       string    `env:"ADDRESS"` // адреса серверов через запятую, например "host1:8080,host2:8080"
+	PollInterval     int       `env:"POLL_INTERVAL"`
+	ReportInterval   int       `env:"REPORT_INTERVAL"`
+	Key              string    `env:"KEY"`
+	BatchSize        int       `env:"BATCH_SIZE"`
+	RateLimit        int       `env:"RATE_LIMIT"`
+	CryptoKey        string    `env:"CRYPTO_KEY"`
+	UseGRPC          bool      `env:"USE_GRPC"`
+	HistogramBuckets []float64 `env:"HISTOGRAM_BUCKETS" envSeparator:","`
+	Labels           string    `env:"LABELS"`             // метки, добавляемые ко всем метрикам агента, в формате "host=h1,zone=eu"
+	Collectors       string    `env:"COLLECTORS"`         // настройки сборщиков метрик в формате "runtime,mem=10s,cpu=off"
+	CgroupPath       string    `env:"CGROUP_PATH"`        // каталог cgroup v2 агента для сборщика cgroup
+	Processes        string    `env:"PROCESSES"`          // процессы сборщика process в формате "web=name:nginx;api=cmdline:^/usr/bin/api;db=pidfile:/run/db.pid"
+	ExecCommands     string    `env:"EXEC_COMMANDS"`      // команды сборщика exec в формате "queue=/opt/bin/queue-size.sh;jobs=echo jobs gauge 1"
+	ExecTimeout      int       `env:"EXEC_TIMEOUT"`       // время выполнения команды сборщика exec в секундах
+	ExecConcurrency  int       `env:"EXEC_CONCURRENCY"`   // количество одновременно выполняемых команд сборщика exec
+	PushAddress      string    `env:"PUSH_ADDRESS"`       // адрес приема метрик от локальных приложений, например "localhost:8090"; пустой - прием отключен
+	PushSocket       string    `env:"PUSH_SOCKET"`        // unix-сокет приема метрик от локальных приложений; пустой - прием отключен
+	QueueDir         string    `env:"QUEUE_DIR"`          // каталог очереди отправки на диске; пустой - очередь отключена
+	QueueMaxSize     int       `env:"QUEUE_MAX_SIZE"`     // максимальный размер очереди отправки в мегабайтах
+	Aggregates       string    `env:"AGGREGATES"`         // агрегаты gauge за интервал отчета в формате "HeapAlloc=min,max;CPUutilization*"
+	BatchLinger      int       `env:"BATCH_LINGER"`       // максимальное время ожидания неполного пакета в секундах
+	PoolBufferSize   int       `env:"POOL_BUFFER_SIZE"`   // количество метрик, ожидающих формирования пакета
+	PoolBufferPolicy string    `env:"POOL_BUFFER_POLICY"` // действие при заполнении буфера: block - ждать, drop - вернуть метрики источнику
+	ShutdownTimeout  int       `env:"SHUTDOWN_TIMEOUT"`   // время на отправку метрик при завершении агента в секундах
+	ServerSelection  string    `env:"SERVER_SELECTION"`   // выбор сервера из нескольких адресов: failover - первый доступный, round-robin - по очереди
 }
 
 func LoadAgentConfigFromFile(fileName string) *agentFileConf {
@@ -74,8 +116,28 @@ const (
 	AgentDefaultReportInterval = 10
 	AgentDefaultCryptoKey      = ""
 	AgentDefaultUseGRPCValue   = false
+	AgentDefaultLabels         = ""
+	AgentDefaultCollectors     = ""
+	AgentDefaultCgroupPath     = "/sys/fs/cgroup"
+	AgentDefaultProcesses      = ""
+	AgentDefaultExecCommands   = ""
+	AgentDefaultExecTimeout    = 10
+	AgentDefaultExecConcurrent = 4
+	AgentDefaultPushAddress    = ""
+	AgentDefaultPushSocket     = ""
+	AgentDefaultQueueDir       = ""
+	AgentDefaultQueueMaxSize   = 64
+	AgentDefaultAggregates     = ""
+	AgentDefaultBatchLinger    = 1
+	AgentDefaultPoolBufferSize = 1000
+	AgentDefaultPoolPolicy     = PoolBufferBlock
+	AgentDefaultShutdownTime   = 10
+	AgentDefaultSelection      = ServerSelectionFailover
 )
 
+// AgentDefaultHistogramBuckets границы корзин гистограмм по-умолчанию (в секундах)
+var AgentDefaultHistogramBuckets = []float64{0.00001, 0.0001, 0.001, 0.01, 0.1}
+
 func UpdateAgentDefaultValues(aFileConf *agentFileConf, aConf *AgentConfiguration) {
 	if aConf.ServerAddr == AgentDefaultServerAddr && aFileConf.Address != "" {
 		aConf.ServerAddr = aFileConf.Address
@@ -100,19 +162,119 @@ func UpdateAgentDefaultValues(aFileConf *agentFileConf, aConf *AgentConfiguratio
 	if !aConf.UseGRPC && aFileConf.UseGRPC {
 		aConf.UseGRPC = aFileConf.UseGRPC
 	}
+
+	if len(aConf.HistogramBuckets) == 0 && len(aFileConf.HistogramBuckets) != 0 {
+		aConf.HistogramBuckets = aFileConf.HistogramBuckets
+	}
+
+	if aConf.Labels == AgentDefaultLabels && aFileConf.Labels != "" {
+		aConf.Labels = aFileConf.Labels
+	}
+
+	if aConf.Collectors == AgentDefaultCollectors && aFileConf.Collectors != "" {
+		aConf.Collectors = aFileConf.Collectors
+	}
+
+	if aConf.CgroupPath == AgentDefaultCgroupPath && aFileConf.CgroupPath != "" {
+		aConf.CgroupPath = aFileConf.CgroupPath
+	}
+
+	if aConf.Processes == AgentDefaultProcesses && aFileConf.Processes != "" {
+		aConf.Processes = aFileConf.Processes
+	}
+
+	if aConf.ExecCommands == AgentDefaultExecCommands && aFileConf.ExecCommands != "" {
+		aConf.ExecCommands = aFileConf.ExecCommands
+	}
+
+	if aConf.ExecTimeout == AgentDefaultExecTimeout && aFileConf.ExecTimeout != 0 {
+		dur := time.Duration(aFileConf.ExecTimeout)
+		aConf.ExecTimeout = int(dur.Seconds())
+	}
+
+	if aConf.ExecConcurrency == AgentDefaultExecConcurrent && aFileConf.ExecConcurrency != 0 {
+		aConf.ExecConcurrency = aFileConf.ExecConcurrency
+	}
+
+	if aConf.PushAddress == AgentDefaultPushAddress && aFileConf.PushAddress != "" {
+		aConf.PushAddress = aFileConf.PushAddress
+	}
+
+	if aConf.PushSocket == AgentDefaultPushSocket && aFileConf.PushSocket != "" {
+		aConf.PushSocket = aFileConf.PushSocket
+	}
+
+	if aConf.QueueDir == AgentDefaultQueueDir && aFileConf.QueueDir != "" {
+		aConf.QueueDir = aFileConf.QueueDir
+	}
+
+	if aConf.QueueMaxSize == AgentDefaultQueueMaxSize && aFileConf.QueueMaxSize != 0 {
+		aConf.QueueMaxSize = aFileConf.QueueMaxSize
+	}
+
+	if aConf.Aggregates == AgentDefaultAggregates && aFileConf.Aggregates != "" {
+		aConf.Aggregates = aFileConf.Aggregates
+	}
+
+	if aConf.BatchLinger == AgentDefaultBatchLinger && aFileConf.BatchLinger != 0 {
+		dur := time.Duration(aFileConf.BatchLinger)
+		aConf.BatchLinger = int(dur.Seconds())
+	}
+
+	if aConf.PoolBufferSize == AgentDefaultPoolBufferSize && aFileConf.PoolBufferSize != 0 {
+		aConf.PoolBufferSize = aFileConf.PoolBufferSize
+	}
+
+	if aConf.PoolBufferPolicy == AgentDefaultPoolPolicy && aFileConf.PoolBufferPolicy != "" {
+		aConf.PoolBufferPolicy = aFileConf.PoolBufferPolicy
+	}
+
+	if aConf.ShutdownTimeout == AgentDefaultShutdownTime && aFileConf.ShutdownTimeout != 0 {
+		dur := time.Duration(aFileConf.ShutdownTimeout)
+		aConf.ShutdownTimeout = int(dur.Seconds())
+	}
+
+	if aConf.ServerSelection == AgentDefaultSelection && aFileConf.ServerSelection != "" {
+		aConf.ServerSelection = aFileConf.ServerSelection
+	}
 }
 
 func LoadAgentConfig() (*AgentConfiguration, error) {
 
 	agentCfg := &AgentConfiguration{}
 
-	flag.StringVar(&agentCfg.ServerAddr, "a", AgentDefaultServerAddr, "serverAddress")
+	flag.StringVar(&agentCfg.ServerAddr, "a", AgentDefaultServerAddr, "server addresses, comma separated")
 	flag.IntVar(&agentCfg.PollInterval, "p", AgentDefautlPollInterval, "poolInterval in seconds")
 	flag.IntVar(&agentCfg.ReportInterval, "r", AgentDefaultReportInterval, "reportInterval in seconds")
 	flag.IntVar(&agentCfg.BatchSize, "b", 5, "metric count of metrics per update request")
 	flag.IntVar(&agentCfg.RateLimit, "l", 1, "max update simultaneous request count")
 	flag.StringVar(&agentCfg.CryptoKey, "crypto-key", AgentDefaultCryptoKey, "rsa public key file name")
 	flag.BoolVar(&agentCfg.UseGRPC, "grpc", false, "use grpc")
+	flag.StringVar(&agentCfg.Labels, "labels", AgentDefaultLabels, "metric labels, format \"host=h1,zone=eu\"")
+	flag.StringVar(&agentCfg.Collectors, "collectors", AgentDefaultCollectors, "metric collectors (runtime, mem, cpu, disk, net, load, uptime, cgroup, process, exec), format \"runtime,mem=10s,cpu=off\"")
+	flag.StringVar(&agentCfg.CgroupPath, "cgroup-path", AgentDefaultCgroupPath, "cgroup v2 directory for cgroup collector")
+	flag.StringVar(&agentCfg.Processes, "processes", AgentDefaultProcesses, "watched processes, format \"web=name:nginx;api=cmdline:^/usr/bin/api;db=pidfile:/run/db.pid\"")
+	flag.StringVar(&agentCfg.ExecCommands, "exec", AgentDefaultExecCommands, "exec collector commands, format \"queue=/opt/bin/queue-size.sh;jobs=echo jobs gauge 1\"")
+	flag.IntVar(&agentCfg.ExecTimeout, "exec-timeout", AgentDefaultExecTimeout, "exec collector command timeout in seconds")
+	flag.IntVar(&agentCfg.ExecConcurrency, "exec-concurrency", AgentDefaultExecConcurrent, "exec collector max simultaneous commands")
+	flag.StringVar(&agentCfg.PushAddress, "push", AgentDefaultPushAddress, "local push endpoint address, e.g. localhost:8090")
+	flag.StringVar(&agentCfg.PushSocket, "push-socket", AgentDefaultPushSocket, "local push endpoint unix socket path")
+	flag.StringVar(&agentCfg.QueueDir, "queue-dir", AgentDefaultQueueDir, "on-disk send queue directory")
+	flag.IntVar(&agentCfg.QueueMaxSize, "queue-max-size", AgentDefaultQueueMaxSize, "on-disk send queue max size in megabytes")
+	flag.StringVar(&agentCfg.Aggregates, "aggregates", AgentDefaultAggregates, "gauge aggregates over report interval (min, max, mean, last, count), format \"HeapAlloc=min,max;CPUutilization*\"")
+	flag.IntVar(&agentCfg.BatchLinger, "batch-linger", AgentDefaultBatchLinger, "max wait for incomplete batch in seconds")
+	flag.IntVar(&agentCfg.PoolBufferSize, "pool-buffer-size", AgentDefaultPoolBufferSize, "max metrics waiting for batch")
+	flag.StringVar(&agentCfg.PoolBufferPolicy, "pool-buffer-policy", AgentDefaultPoolPolicy, "full pool buffer policy (block, drop)")
+	flag.IntVar(&agentCfg.ShutdownTimeout, "shutdown-timeout", AgentDefaultShutdownTime, "max time to send remaining metrics on shutdown in seconds")
+	flag.StringVar(&agentCfg.ServerSelection, "server-selection", AgentDefaultSelection, "server selection for multiple addresses (failover, round-robin)")
+	flag.Func("hb", "histogram buckets, comma separated (default 0.00001,0.0001,0.001,0.01,0.1)", func(s string) error {
+		buckets, err := ParseBuckets(s)
+		if err != nil {
+			return err
+		}
+		agentCfg.HistogramBuckets = buckets
+		return nil
+	})
 
 	var configFileName string
 
@@ -154,5 +316,320 @@ func LoadAgentConfig() (*AgentConfiguration, error) {
 		agentCfg.RateLimit = 1
 	}
 
+	if err := CheckBuckets(agentCfg.HistogramBuckets); err != nil {
+		return nil, err
+	}
+
+	if _, err := ParseLabels(agentCfg.Labels); err != nil {
+		return nil, err
+	}
+
+	if _, err := ParseCollectors(agentCfg.Collectors); err != nil {
+		return nil, err
+	}
+
+	if _, err := ParseProcesses(agentCfg.Processes); err != nil {
+		return nil, err
+	}
+
+	if _, err := ParseExecCommands(agentCfg.ExecCommands); err != nil {
+		return nil, err
+	}
+
+	if agentCfg.ExecTimeout <= 0 {
+		agentCfg.ExecTimeout = AgentDefaultExecTimeout
+	}
+
+	if agentCfg.ExecConcurrency <= 0 {
+		agentCfg.ExecConcurrency = AgentDefaultExecConcurrent
+	}
+
+	if _, err := ParseAggregates(agentCfg.Aggregates); err != nil {
+		return nil, err
+	}
+
+	if agentCfg.BatchLinger <= 0 {
+		agentCfg.BatchLinger = AgentDefaultBatchLinger
+	}
+
+	if agentCfg.PoolBufferSize <= 0 {
+		agentCfg.PoolBufferSize = AgentDefaultPoolBufferSize
+	}
+
+	if agentCfg.ShutdownTimeout <= 0 {
+		agentCfg.ShutdownTimeout = AgentDefaultShutdownTime
+	}
+
+	if _, err := ParseServerAddrs(agentCfg.ServerAddr); err != nil {
+		return nil, err
+	}
+
+	switch agentCfg.ServerSelection {
+	case ServerSelectionFailover, ServerSelectionRoundRobin:
+	default:
+		return nil, fmt.Errorf("wrong server selection %q, expected %v or %v", agentCfg.ServerSelection, ServerSelectionFailover, ServerSelectionRoundRobin)
+	}
+
+	switch agentCfg.PoolBufferPolicy {
+	case PoolBufferBlock, PoolBufferDrop:
+	default:
+		return nil, fmt.Errorf("wrong pool buffer policy %q, expected %v or %v", agentCfg.PoolBufferPolicy, PoolBufferBlock, PoolBufferDrop)
+	}
+
+	if agentCfg.QueueMaxSize <= 0 {
+		agentCfg.QueueMaxSize = AgentDefaultQueueMaxSize
+	}
+
 	return agentCfg, nil
 }
+
+// ParseBuckets разбирает список границ корзин гистограммы, разделенных запятой
+func ParseBuckets(s string) ([]float64, error) {
+	var buckets []float64
+	for _, v := range strings.Split(s, ",") {
+		bound, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("wrong histogram bucket %q: %w", v, err)
+		}
+		buckets = append(buckets, bound)
+	}
+	if err := CheckBuckets(buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// CheckBuckets проверяет границы корзин гистограммы: конечные значения без повторов.
+// Порядок границ не важен - гистограмма агента их сортирует.
+func CheckBuckets(buckets []float64) error {
+	sorted := slices.Clone(buckets)
+	slices.Sort(sorted)
+	for i, bound := range sorted {
+		if math.IsNaN(bound) || math.IsInf(bound, 0) {
+			return fmt.Errorf("wrong histogram bucket %v, expected finite value", bound)
+		}
+		if i > 0 && bound == sorted[i-1] {
+			return fmt.Errorf("repeated histogram bucket %v", bound)
+		}
+	}
+	return nil
+}
+
+// ParseLabels разбирает метки в формате "host=h1,zone=eu"
+func ParseLabels(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	labels := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("wrong label %q, expected name=value", kv)
+		}
+		labels[k] = strings.TrimSpace(v)
+	}
+	return labels, nil
+}
+
+// CollectorConf настройки сборщика метрик
+type CollectorConf struct {
+	Enabled      bool
+	PollInterval time.Duration // 0 - интервал опроса агента
+}
+
+// ParseCollectors разбирает настройки сборщиков в формате "runtime,mem=10s,cpu=off":
+// имя включает сборщик, имя=интервал включает сборщик с собственным интервалом опроса, имя=off - выключает.
+// Сборщики, не указанные в настройках, работают по-умолчанию.
+func ParseCollectors(s string) (map[string]CollectorConf, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	collectors := make(map[string]CollectorConf)
+	for _, item := range strings.Split(s, ",") {
+		name, value, hasValue := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if name == "" {
+			return nil, fmt.Errorf("wrong collector %q, expected name[=interval|off]", item)
+		}
+
+		conf := CollectorConf{Enabled: true}
+		switch {
+		case !hasValue:
+		case value == "off":
+			conf.Enabled = false
+		default:
+			interval, err := time.ParseDuration(value)
+			if err != nil || interval <= 0 {
+				return nil, fmt.Errorf("wrong collector %v poll interval %q", name, value)
+			}
+			conf.PollInterval = interval
+		}
+		collectors[name] = conf
+	}
+	return collectors, nil
+}
+
+// Способы поиска отслеживаемого процесса
+const (
+	ProcessMatchName    = "name"    // по имени процесса
+	ProcessMatchCmdline = "cmdline" // по регулярному выражению для командной строки
+	ProcessMatchPidfile = "pidfile" // по pid-файлу
+)
+
+// ProcessConf отслеживаемый процесс
+type ProcessConf struct {
+	Name    string // имя в метках метрик
+	Match   string // способ поиска: ProcessMatchName, ProcessMatchCmdline или ProcessMatchPidfile
+	Pattern string // имя процесса, регулярное выражение или путь к pid-файлу
+}
+
+// ParseProcesses разбирает отслеживаемые процессы в формате "web=name:nginx;api=cmdline:^/usr/bin/api;db=pidfile:/run/db.pid"
+func ParseProcesses(s string) ([]ProcessConf, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var processes []ProcessConf
+	names := make(map[string]bool)
+	for _, item := range strings.Split(s, ";") {
+		name, spec, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("wrong process %q, expected name=match:pattern", item)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate process %v", name)
+		}
+		names[name] = true
+
+		match, pattern, ok := strings.Cut(strings.TrimSpace(spec), ":")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("wrong process %v match %q, expected match:pattern", name, spec)
+		}
+
+		switch match {
+		case ProcessMatchName, ProcessMatchPidfile:
+		case ProcessMatchCmdline:
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("wrong process %v cmdline regexp: %w", name, err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown process %v match %q", name, match)
+		}
+
+		processes = append(processes, ProcessConf{Name: name, Match: match, Pattern: pattern})
+	}
+	return processes, nil
+}
+
+// ExecCommandConf команда сборщика exec
+type ExecCommandConf struct {
+	Name    string // имя в метках метрик
+	Command string // команда, выполняется через sh -c
+}
+
+// ParseExecCommands разбирает команды в формате "queue=/opt/bin/queue-size.sh;jobs=echo jobs gauge 1";
+// символ ';' в командах недопустим
+func ParseExecCommands(s string) ([]ExecCommandConf, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var commands []ExecCommandConf
+	names := make(map[string]bool)
+	for _, item := range strings.Split(s, ";") {
+		name, command, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		command = strings.TrimSpace(command)
+		if !ok || name == "" || command == "" {
+			return nil, fmt.Errorf("wrong exec command %q, expected name=command", item)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate exec command %v", name)
+		}
+		names[name] = true
+		commands = append(commands, ExecCommandConf{Name: name, Command: command})
+	}
+	return commands, nil
+}
+
+// Выбор сервера из нескольких адресов
+const (
+	ServerSelectionFailover   = "failover"
+	ServerSelectionRoundRobin = "round-robin"
+)
+
+// ParseServerAddrs разбирает адреса серверов, разделенные запятой
+func ParseServerAddrs(s string) ([]string, error) {
+	var addrs []string
+	for _, addr := range strings.Split(s, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			return nil, fmt.Errorf("wrong server address list %q", s)
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// Действия при заполнении буфера пула отправки
+const (
+	PoolBufferBlock = "block"
+	PoolBufferDrop  = "drop"
+)
+
+// Агрегаты gauge за интервал отчета
+const (
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+	AggregateMean  = "mean"
+	AggregateLast  = "last"
+	AggregateCount = "count"
+)
+
+// AggregateConf агрегаты gauge, имя которых соответствует шаблону
+type AggregateConf struct {
+	Pattern string   // шаблон имени метрики в формате path.Match, например "CPUutilization*"
+	Stats   []string // вычисляемые агрегаты
+}
+
+// ParseAggregates разбирает агрегаты в формате "HeapAlloc=min,max;CPUutilization*";
+// шаблон без списка агрегатов - все агрегаты
+func ParseAggregates(s string) ([]AggregateConf, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var aggregates []AggregateConf
+	for _, item := range strings.Split(s, ";") {
+		pattern, list, hasList := strings.Cut(item, "=")
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return nil, fmt.Errorf("wrong aggregate %q, expected pattern[=stat,...]", item)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("wrong aggregate pattern %q: %w", pattern, err)
+		}
+
+		conf := AggregateConf{Pattern: pattern}
+		if !hasList {
+			conf.Stats = []string{AggregateMin, AggregateMax, AggregateMean, AggregateLast, AggregateCount}
+			aggregates = append(aggregates, conf)
+			continue
+		}
+
+		for _, stat := range strings.Split(list, ",") {
+			stat = strings.TrimSpace(stat)
+			switch stat {
+			case AggregateMin, AggregateMax, AggregateMean, AggregateLast, AggregateCount:
+				conf.Stats = append(conf.Stats, stat)
+			default:
+				return nil, fmt.Errorf("unknown aggregate %q for %v", stat, pattern)
+			}
+		}
+		aggregates = append(aggregates, conf)
+	}
+	return aggregates, nil
+}
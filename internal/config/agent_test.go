@@ -1,8 +1,10 @@
 package config_test
 
 import (
+	"math"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/StasMerzlyakov/go-metrics/internal/config"
 	"gotest.tools/v3/assert"
@@ -23,12 +25,179 @@ func TestLoadAgentConfig(t *testing.T) {
 	aFileConf := config.LoadAgentConfigFromFile(agentConfFileName)
 
 	aConf := &config.AgentConfiguration{
-		ServerAddr:   "localhost:8082",
-		PollInterval: config.AgentDefautlPollInterval,
+		ServerAddr:       "localhost:8082",
+		PollInterval:     config.AgentDefautlPollInterval,
+		CgroupPath:       config.AgentDefaultCgroupPath,
+		ExecTimeout:      config.AgentDefaultExecTimeout,
+		QueueMaxSize:     config.AgentDefaultQueueMaxSize,
+		BatchLinger:      config.AgentDefaultBatchLinger,
+		PoolBufferSize:   config.AgentDefaultPoolBufferSize,
+		PoolBufferPolicy: config.AgentDefaultPoolPolicy,
+		ShutdownTimeout:  config.AgentDefaultShutdownTime,
+		ServerSelection:  config.AgentDefaultSelection,
 	}
 
 	config.UpdateAgentDefaultValues(aFileConf, aConf)
 
 	assert.Equal(t, "localhost:8082", aConf.ServerAddr)
 	assert.Equal(t, 1, aConf.PollInterval)
+	assert.Equal(t, "runtime,cpu=5s", aConf.Collectors)
+	assert.Equal(t, "/host/sys/fs/cgroup", aConf.CgroupPath)
+	assert.Equal(t, "jobs=echo jobs gauge 1", aConf.ExecCommands)
+	assert.Equal(t, 3, aConf.ExecTimeout)
+	assert.Equal(t, "localhost:8090", aConf.PushAddress)
+	assert.Equal(t, "/run/go-metrics/agent.sock", aConf.PushSocket)
+	assert.Equal(t, "/var/lib/go-metrics/queue", aConf.QueueDir)
+	assert.Equal(t, 16, aConf.QueueMaxSize)
+	assert.Equal(t, "HeapAlloc=min,max", aConf.Aggregates)
+	assert.Equal(t, 2, aConf.BatchLinger)
+	assert.Equal(t, 500, aConf.PoolBufferSize)
+	assert.Equal(t, config.PoolBufferDrop, aConf.PoolBufferPolicy)
+	assert.Equal(t, 30, aConf.ShutdownTimeout)
+	assert.Equal(t, config.ServerSelectionRoundRobin, aConf.ServerSelection)
+}
+
+func TestParseBuckets(t *testing.T) {
+	buckets, err := config.ParseBuckets("0.001, 0.01,1")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []float64{0.001, 0.01, 1}, buckets)
+
+	for _, s := range []string{"0.001,a", "", "0.1,0.1", "1,0.1,1", "0.1,NaN", "0.1,Inf", "-Inf,0.1"} {
+		_, err = config.ParseBuckets(s)
+		assert.Assert(t, err != nil, s)
+	}
+}
+
+func TestCheckBuckets(t *testing.T) {
+	testCases := []struct {
+		name    string
+		buckets []float64
+		ok      bool
+	}{
+		{"empty", nil, true},
+		{"sorted", []float64{0.1, 1, 10}, true},
+		{"unsorted", []float64{10, 0.1, 1}, true},
+		{"repeated", []float64{0.1, 0.1}, false},
+		{"repeated unsorted", []float64{1, 0.1, 1}, false},
+		{"nan", []float64{0.1, math.NaN()}, false},
+		{"inf", []float64{0.1, math.Inf(1)}, false},
+		{"negative inf", []float64{math.Inf(-1), 0.1}, false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			err := config.CheckBuckets(test.buckets)
+			assert.Equal(t, test.ok, err == nil)
+		})
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := config.ParseLabels("host=h1, zone = eu")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]string{"host": "h1", "zone": "eu"}, labels)
+
+	labels, err = config.ParseLabels("")
+	assert.NilError(t, err)
+	assert.Assert(t, labels == nil)
+
+	_, err = config.ParseLabels("host")
+	assert.Assert(t, err != nil)
+}
+
+func TestParseCollectors(t *testing.T) {
+	collectors, err := config.ParseCollectors("runtime, mem = 10s,cpu=off")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]config.CollectorConf{
+		"runtime": {Enabled: true},
+		"mem":     {Enabled: true, PollInterval: 10 * time.Second},
+		"cpu":     {Enabled: false},
+	}, collectors)
+
+	collectors, err = config.ParseCollectors("")
+	assert.NilError(t, err)
+	assert.Assert(t, collectors == nil)
+
+	_, err = config.ParseCollectors("mem=abc")
+	assert.Assert(t, err != nil)
+
+	_, err = config.ParseCollectors("mem=-1s")
+	assert.Assert(t, err != nil)
+
+	_, err = config.ParseCollectors("=1s")
+	assert.Assert(t, err != nil)
+}
+
+func TestParseProcesses(t *testing.T) {
+	processes, err := config.ParseProcesses("web=name:nginx; api = cmdline:^/usr/bin/api (a|b);db=pidfile:/run/db.pid")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []config.ProcessConf{
+		{Name: "web", Match: config.ProcessMatchName, Pattern: "nginx"},
+		{Name: "api", Match: config.ProcessMatchCmdline, Pattern: "^/usr/bin/api (a|b)"},
+		{Name: "db", Match: config.ProcessMatchPidfile, Pattern: "/run/db.pid"},
+	}, processes)
+
+	processes, err = config.ParseProcesses("")
+	assert.NilError(t, err)
+	assert.Assert(t, processes == nil)
+
+	for _, s := range []string{
+		"web",
+		"web=nginx",
+		"web=exe:nginx",
+		"web=cmdline:(",
+		"web=name:nginx;web=name:httpd",
+	} {
+		_, err = config.ParseProcesses(s)
+		assert.Assert(t, err != nil, s)
+	}
+}
+
+func TestParseExecCommands(t *testing.T) {
+	commands, err := config.ParseExecCommands("queue = /opt/bin/queue-size.sh --all;jobs=echo jobs gauge 1")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []config.ExecCommandConf{
+		{Name: "queue", Command: "/opt/bin/queue-size.sh --all"},
+		{Name: "jobs", Command: "echo jobs gauge 1"},
+	}, commands)
+
+	commands, err = config.ParseExecCommands("")
+	assert.NilError(t, err)
+	assert.Assert(t, commands == nil)
+
+	for _, s := range []string{"queue", "queue=", "=echo", "a=echo;a=echo"} {
+		_, err = config.ParseExecCommands(s)
+		assert.Assert(t, err != nil, s)
+	}
+}
+
+func TestParseAggregates(t *testing.T) {
+	aggregates, err := config.ParseAggregates("HeapAlloc = min, max;CPUutilization*")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []config.AggregateConf{
+		{Pattern: "HeapAlloc", Stats: []string{config.AggregateMin, config.AggregateMax}},
+		{Pattern: "CPUutilization*", Stats: []string{
+			config.AggregateMin, config.AggregateMax, config.AggregateMean, config.AggregateLast, config.AggregateCount,
+		}},
+	}, aggregates)
+
+	aggregates, err = config.ParseAggregates("")
+	assert.NilError(t, err)
+	assert.Assert(t, aggregates == nil)
+
+	for _, s := range []string{"=min", "HeapAlloc=", "HeapAlloc=median", "Heap[=min"} {
+		_, err = config.ParseAggregates(s)
+		assert.Assert(t, err != nil, s)
+	}
+}
+
+func TestParseServerAddrs(t *testing.T) {
+	addrs, err := config.ParseServerAddrs("localhost:8080, http://host2:8080")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"localhost:8080", "http://host2:8080"}, addrs)
+
+	for _, s := range []string{"", "localhost:8080,", "a,,b"} {
+		_, err = config.ParseServerAddrs(s)
+		assert.Assert(t, err != nil, s)
+	}
 }
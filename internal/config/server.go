@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -12,25 +13,49 @@ import (
 )
 
 type serverFileConf struct {
-	Address       string   `json:"address"`
-	Restore       bool     `json:"restore"`
-	StoreInterval Duration `json:"store_interval"`
-	StoreFile     string   `json:"store_file"`
-	DatabaseDSN   string   `json:"database_dsn"`
-	CryptoKey     string   `json:"crypto_key"`
-	TrustedSubnet string   `json:"trusted_subnet"`
-	GRPCAddress   string   `json:"grpc_address"`
+	Address            string   `json:"address"`
+	Restore            bool     `json:"restore"`
+	StoreInterval      Duration `json:"store_interval"`
+	StoreFile          string   `json:"store_file"`
+	DatabaseDSN        string   `json:"database_dsn"`
+	CryptoKey          string   `json:"crypto_key"`
+	TrustedSubnet      string   `json:"trusted_subnet"`
+	GRPCAddress        string   `json:"grpc_address"`
+	HistoryRetention   Duration `json:"history_retention"`
+	AlertRulesFile     string   `json:"alert_rules_file"`
+	AlertInterval      Duration `json:"alert_interval"`
+	NotifyWebhookURL   string   `json:"notify_webhook_url"`
+	NotifySMTPAddr     string   `json:"notify_smtp_addr"`
+	NotifySMTPFrom     string   `json:"notify_smtp_from"`
+	NotifySMTPTo       string   `json:"notify_smtp_to"`
+	NotifySMTPUser     string   `json:"notify_smtp_user"`
+	NotifySMTPPassword string   `json:"notify_smtp_password"`
+	NotifyGroupWait    Duration `json:"notify_group_wait"`
+	StatsDAddress      string   `json:"statsd_address"`
+	StatsDFlush        Duration `json:"statsd_flush_interval"`
+	GraphiteAddress    string   `json:"graphite_address"`
+	GraphiteMaxConns   uint     `json:"graphite_max_conns"`
+	IdempotencyWindow  Duration `json:"idempotency_window"`
 }
 
 const (
-	ServerDefaultAddr          = "localhost:8080"
-	ServerDefaultRestore       = true
-	ServerDefaultStoreInterval = 300
-	ServerDefaultStoreFile     = "/tmp/metrics-db.json"
-	ServerDefaultDatabaseDSN   = ""
-	ServerDefaultCryptoKey     = ""
-	ServerDefaultTrustedSubnet = ""
-	ServerDefaultGRPCAddr      = ""
+	ServerDefaultAddr             = "localhost:8080"
+	ServerDefaultRestore          = true
+	ServerDefaultStoreInterval    = 300
+	ServerDefaultStoreFile        = "/tmp/metrics-db.json"
+	ServerDefaultDatabaseDSN      = ""
+	ServerDefaultCryptoKey        = ""
+	ServerDefaultTrustedSubnet    = ""
+	ServerDefaultGRPCAddr         = ""
+	ServerDefaultHistoryRetention = 0
+	ServerDefaultAlertRulesFile   = ""
+	ServerDefaultAlertInterval    = 10
+	ServerDefaultNotifyGroupWait  = 5
+	ServerDefaultStatsDAddr       = ""
+	ServerDefaultStatsDFlush      = 10
+	ServerDefaultGraphiteAddr     = ""
+	ServerDefaultGraphiteMaxConns = 100
+	ServerDefaultIdempotencyWin   = 3600
 )
 
 func LoadServerConfigFromFile(fileName string) *serverFileConf {
@@ -88,18 +113,98 @@ func UpdateServerDefaultValues(sFileConf *serverFileConf, sConf *ServerConfigura
 	if sConf.GRPCAddress == ServerDefaultGRPCAddr && sFileConf.GRPCAddress != ServerDefaultGRPCAddr {
 		sConf.GRPCAddress = sFileConf.GRPCAddress
 	}
+
+	if sConf.HistoryRetention == ServerDefaultHistoryRetention && sFileConf.HistoryRetention != 0 {
+		dur := time.Duration(sFileConf.HistoryRetention)
+		sConf.HistoryRetention = uint(dur.Seconds())
+	}
+
+	if sConf.AlertRulesFile == ServerDefaultAlertRulesFile && sFileConf.AlertRulesFile != "" {
+		sConf.AlertRulesFile = sFileConf.AlertRulesFile
+	}
+
+	if sConf.AlertInterval == ServerDefaultAlertInterval && sFileConf.AlertInterval != 0 {
+		dur := time.Duration(sFileConf.AlertInterval)
+		sConf.AlertInterval = uint(dur.Seconds())
+	}
+
+	if sConf.NotifyWebhookURL == "" && sFileConf.NotifyWebhookURL != "" {
+		sConf.NotifyWebhookURL = sFileConf.NotifyWebhookURL
+	}
+
+	if sConf.NotifySMTPAddr == "" && sFileConf.NotifySMTPAddr != "" {
+		sConf.NotifySMTPAddr = sFileConf.NotifySMTPAddr
+	}
+
+	if sConf.NotifySMTPFrom == "" && sFileConf.NotifySMTPFrom != "" {
+		sConf.NotifySMTPFrom = sFileConf.NotifySMTPFrom
+	}
+
+	if sConf.NotifySMTPTo == "" && sFileConf.NotifySMTPTo != "" {
+		sConf.NotifySMTPTo = sFileConf.NotifySMTPTo
+	}
+
+	if sConf.NotifySMTPUser == "" && sFileConf.NotifySMTPUser != "" {
+		sConf.NotifySMTPUser = sFileConf.NotifySMTPUser
+	}
+
+	if sConf.NotifySMTPPassword == "" && sFileConf.NotifySMTPPassword != "" {
+		sConf.NotifySMTPPassword = sFileConf.NotifySMTPPassword
+	}
+
+	if sConf.NotifyGroupWait == ServerDefaultNotifyGroupWait && sFileConf.NotifyGroupWait != 0 {
+		dur := time.Duration(sFileConf.NotifyGroupWait)
+		sConf.NotifyGroupWait = uint(dur.Seconds())
+	}
+
+	if sConf.StatsDAddress == ServerDefaultStatsDAddr && sFileConf.StatsDAddress != "" {
+		sConf.StatsDAddress = sFileConf.StatsDAddress
+	}
+
+	if sConf.StatsDFlush == ServerDefaultStatsDFlush && sFileConf.StatsDFlush != 0 {
+		dur := time.Duration(sFileConf.StatsDFlush)
+		sConf.StatsDFlush = uint(dur.Seconds())
+	}
+
+	if sConf.GraphiteAddress == ServerDefaultGraphiteAddr && sFileConf.GraphiteAddress != "" {
+		sConf.GraphiteAddress = sFileConf.GraphiteAddress
+	}
+
+	if sConf.GraphiteMaxConns == ServerDefaultGraphiteMaxConns && sFileConf.GraphiteMaxConns != 0 {
+		sConf.GraphiteMaxConns = sFileConf.GraphiteMaxConns
+	}
+
+	if sConf.IdempotencyWindow == ServerDefaultIdempotencyWin && sFileConf.IdempotencyWindow != 0 {
+		dur := time.Duration(sFileConf.IdempotencyWindow)
+		sConf.IdempotencyWindow = uint(dur.Seconds())
+	}
 }
 
 type ServerConfiguration struct {
-	URL             string `env:"ADDRESS"`
-	Restore         bool   `env:"RESTORE"`
-	StoreInterval   uint   `env:"STORE_INTERVAL"`
-	FileStoragePath string `env:"FILE_STORAGE_PATH"`
-	DatabaseDSN     string `env:"DATABASE_DSN"`
-	Key             string `env:"KEY"`
-	CryptoKey       string `env:"CRYPTO_KEY"`
-	TrustedSubnet   string `env:"TRUSTED_SUBNET"`
-	GRPCAddress     string `env:"GRPC_ADDRESS"`
+	URL                string `env:"ADDRESS"`
+	Restore            bool   `env:"RESTORE"`
+	StoreInterval      uint   `env:"STORE_INTERVAL"`
+	FileStoragePath    string `env:"FILE_STORAGE_PATH"`
+	DatabaseDSN        string `env:"DATABASE_DSN"`
+	Key                string `env:"KEY"`
+	CryptoKey          string `env:"CRYPTO_KEY"`
+	TrustedSubnet      string `env:"TRUSTED_SUBNET"`
+	GRPCAddress        string `env:"GRPC_ADDRESS"`
+	HistoryRetention   uint   `env:"HISTORY_RETENTION"` // время хранения истории метрик в секундах; 0 - история не ведется
+	AlertRulesFile     string `env:"ALERT_RULES_FILE"`
+	AlertInterval      uint   `env:"ALERT_INTERVAL"` // интервал проверки правил оповещения в секундах
+	NotifyWebhookURL   string `env:"NOTIFY_WEBHOOK_URL"`
+	NotifySMTPAddr     string `env:"NOTIFY_SMTP_ADDR"`
+	NotifySMTPFrom     string `env:"NOTIFY_SMTP_FROM"`
+	NotifySMTPTo       string `env:"NOTIFY_SMTP_TO"` // адреса получателей через запятую
+	NotifySMTPUser     string `env:"NOTIFY_SMTP_USER"`
+	NotifySMTPPassword string `env:"NOTIFY_SMTP_PASSWORD"`  // задается только через переменную окружения или файл конфигурации
+	NotifyGroupWait    uint   `env:"NOTIFY_GROUP_WAIT"`     // время накопления оповещений перед отправкой в секундах
+	StatsDAddress      string `env:"STATSD_ADDRESS"`        // UDP-адрес приема StatsD; пустой - прием отключен
+	StatsDFlush        uint   `env:"STATSD_FLUSH_INTERVAL"` // интервал сохранения агрегированных значений StatsD в секундах
+	GraphiteAddress    string `env:"GRAPHITE_ADDRESS"`      // TCP-адрес приема Graphite plaintext; пустой - прием отключен
+	GraphiteMaxConns   uint   `env:"GRAPHITE_MAX_CONNS"`    // максимальное количество одновременных соединений Graphite
+	IdempotencyWindow  uint   `env:"IDEMPOTENCY_WINDOW"`    // время хранения ключей примененных пакетов в секундах; 0 - ключи не проверяются
 }
 
 type RestoreConfiguration struct {
@@ -130,6 +235,20 @@ func LoadServerConfig() (*ServerConfiguration, error) {
 	flag.StringVar(&srvConf.CryptoKey, "crypto-key", ServerDefaultCryptoKey, "rsa public key file name")
 	flag.StringVar(&srvConf.TrustedSubnet, "t", ServerDefaultTrustedSubnet, "trusted agent subnet")
 	flag.StringVar(&srvConf.GRPCAddress, "g", ServerDefaultGRPCAddr, "grpc endpoint address")
+	flag.StringVar(&srvConf.AlertRulesFile, "alert-rules", ServerDefaultAlertRulesFile, "alerting rules file")
+	flag.UintVar(&srvConf.AlertInterval, "alert-interval", ServerDefaultAlertInterval, "alerting rules evaluation interval in seconds")
+	flag.StringVar(&srvConf.NotifyWebhookURL, "notify-webhook", "", "alert notification webhook url")
+	flag.StringVar(&srvConf.NotifySMTPAddr, "notify-smtp-addr", "", "alert notification smtp server address")
+	flag.StringVar(&srvConf.NotifySMTPFrom, "notify-smtp-from", "", "alert notification email sender")
+	flag.StringVar(&srvConf.NotifySMTPTo, "notify-smtp-to", "", "alert notification email recipients, comma separated")
+	flag.StringVar(&srvConf.NotifySMTPUser, "notify-smtp-user", "", "alert notification smtp user")
+	flag.UintVar(&srvConf.NotifyGroupWait, "notify-group-wait", ServerDefaultNotifyGroupWait, "alert notification group wait in seconds")
+	flag.StringVar(&srvConf.StatsDAddress, "statsd", ServerDefaultStatsDAddr, "statsd udp listen address")
+	flag.UintVar(&srvConf.StatsDFlush, "statsd-flush", ServerDefaultStatsDFlush, "statsd flush interval in seconds")
+	flag.StringVar(&srvConf.GraphiteAddress, "graphite", ServerDefaultGraphiteAddr, "graphite plaintext tcp listen address")
+	flag.UintVar(&srvConf.GraphiteMaxConns, "graphite-max-conns", ServerDefaultGraphiteMaxConns, "graphite max concurrent connections")
+	flag.UintVar(&srvConf.IdempotencyWindow, "idempotency-window", ServerDefaultIdempotencyWin, "batch idempotency key retention in seconds (0 - disable deduplication)")
+	flag.UintVar(&srvConf.HistoryRetention, "hr", ServerDefaultHistoryRetention, "metric history retention in seconds (0 - disable history)")
 
 	var configFileName string
 
@@ -159,5 +278,25 @@ func LoadServerConfig() (*ServerConfiguration, error) {
 		return nil, err
 	}
 
+	if srvConf.AlertRulesFile != "" && srvConf.AlertInterval == 0 {
+		return nil, errors.New("alert interval must be positive")
+	}
+
+	if srvConf.AlertRulesFile != "" && srvConf.NotifyGroupWait == 0 {
+		return nil, errors.New("notify group wait must be positive")
+	}
+
+	if srvConf.StatsDAddress != "" && srvConf.StatsDFlush == 0 {
+		return nil, errors.New("statsd flush interval must be positive")
+	}
+
+	if srvConf.GraphiteAddress != "" && srvConf.GraphiteMaxConns == 0 {
+		return nil, errors.New("graphite max connections must be positive")
+	}
+
+	if srvConf.NotifySMTPAddr != "" && (srvConf.NotifySMTPFrom == "" || srvConf.NotifySMTPTo == "") {
+		return nil, errors.New("smtp notification requires sender and recipients")
+	}
+
 	return srvConf, nil
 }
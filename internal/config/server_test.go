@@ -21,12 +21,30 @@ func TestLoadServerConfig(t *testing.T) {
 	sFileConf := config.LoadServerConfigFromFile(serverConfFileName)
 
 	aConf := &config.ServerConfiguration{
-		URL:     "localhost:8082",
-		Restore: false,
+		URL:               "localhost:8082",
+		Restore:           false,
+		HistoryRetention:  config.ServerDefaultHistoryRetention,
+		AlertInterval:     config.ServerDefaultAlertInterval,
+		NotifyGroupWait:   config.ServerDefaultNotifyGroupWait,
+		StatsDFlush:       config.ServerDefaultStatsDFlush,
+		GraphiteMaxConns:  config.ServerDefaultGraphiteMaxConns,
+		IdempotencyWindow: config.ServerDefaultIdempotencyWin,
 	}
 
 	config.UpdateServerDefaultValues(sFileConf, aConf, true)
 
 	assert.Equal(t, aConf.URL, "localhost:8082")
 	assert.Equal(t, aConf.Restore, false)
+	assert.Equal(t, aConf.HistoryRetention, uint(7200))
+	assert.Equal(t, aConf.AlertRulesFile, "/path/to/rules.json")
+	assert.Equal(t, aConf.AlertInterval, uint(30))
+	assert.Equal(t, aConf.NotifyWebhookURL, "http://localhost:9093/hook")
+	assert.Equal(t, aConf.NotifySMTPAddr, "localhost:25")
+	assert.Equal(t, aConf.NotifySMTPTo, "ops@localhost")
+	assert.Equal(t, aConf.NotifyGroupWait, uint(1))
+	assert.Equal(t, aConf.StatsDAddress, "localhost:8125")
+	assert.Equal(t, aConf.StatsDFlush, uint(5))
+	assert.Equal(t, aConf.GraphiteAddress, "localhost:2003")
+	assert.Equal(t, aConf.GraphiteMaxConns, uint(20))
+	assert.Equal(t, aConf.IdempotencyWindow, uint(600))
 }
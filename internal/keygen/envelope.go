@@ -0,0 +1,138 @@
+package keygen
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Формат конверта:
+//
+//	magic (4 байта) | версия (1 байт) | длина ключа (2 байта, big-endian) | ключ AES, зашифрованный RSA-OAEP/SHA-512 |
+//	nonce AES-GCM (12 байт) | данные, зашифрованные AES-256-GCM
+//
+// Заголовок (все до nonce) защищен AES-GCM как дополнительные данные.
+const (
+	EnvelopeVersion1 byte = 1
+
+	envelopeKeySize = 32 // AES-256
+)
+
+var envelopeMagic = []byte("GMEV")
+
+var ErrEnvelopeFormat = errors.New("wrong envelope format")
+
+// EncryptEnvelope шифрует данные произвольного размера случайным ключом AES-GCM; ключ шифруется открытым ключом RSA.
+func EncryptEnvelope(msg []byte, pub *rsa.PublicKey) ([]byte, error) {
+	dataKey := make([]byte, envelopeKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("generate data key err %w", err)
+	}
+
+	wrappedKey, err := EncryptWithPublicKey(dataKey, pub)
+	if err != nil {
+		return nil, fmt.Errorf("wrap data key err %w", err)
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce err %w", err)
+	}
+
+	header := make([]byte, 0, len(envelopeMagic)+3+len(wrappedKey))
+	header = append(header, envelopeMagic...)
+	header = append(header, EnvelopeVersion1)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrappedKey)))
+	header = append(header, wrappedKey...)
+
+	res := make([]byte, 0, len(header)+len(nonce)+len(msg)+gcm.Overhead())
+	res = append(res, header...)
+	res = append(res, nonce...)
+	return gcm.Seal(res, nonce, msg, header), nil
+}
+
+// DecryptEnvelope расшифровывает данные, зашифрованные EncryptEnvelope.
+//
+// Данные без заголовка конверта расшифровываются как EncryptWithPublicKey - старый формат агентов.
+func DecryptEnvelope(data []byte, priv *rsa.PrivateKey) ([]byte, error) {
+	if !bytes.HasPrefix(data, envelopeMagic) {
+		return DecryptWithPrivateKey(data, priv)
+	}
+
+	msg, err := decryptEnvelopeV1(data, priv)
+	if err != nil && len(data) == priv.Size() {
+		// случайный шифротекст старого формата может начинаться с magic
+		if legacy, legacyErr := DecryptWithPrivateKey(data, priv); legacyErr == nil {
+			return legacy, nil
+		}
+	}
+	return msg, err
+}
+
+func decryptEnvelopeV1(data []byte, priv *rsa.PrivateKey) ([]byte, error) {
+	pos := len(envelopeMagic)
+	if len(data) < pos+3 {
+		return nil, fmt.Errorf("%w: header is too short", ErrEnvelopeFormat)
+	}
+
+	if version := data[pos]; version != EnvelopeVersion1 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrEnvelopeFormat, version)
+	}
+	pos++
+
+	keyLen := int(binary.BigEndian.Uint16(data[pos:]))
+	pos += 2
+	if len(data) < pos+keyLen {
+		return nil, fmt.Errorf("%w: wrapped key is too short", ErrEnvelopeFormat)
+	}
+	wrappedKey := data[pos : pos+keyLen]
+	pos += keyLen
+	header := data[:pos]
+
+	dataKey, err := DecryptWithPrivateKey(wrappedKey, priv)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key err %w", err)
+	}
+	if len(dataKey) != envelopeKeySize {
+		return nil, fmt.Errorf("%w: wrong data key size %d", ErrEnvelopeFormat, len(dataKey))
+	}
+
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < pos+gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: nonce is too short", ErrEnvelopeFormat)
+	}
+	nonce := data[pos : pos+gcm.NonceSize()]
+	pos += gcm.NonceSize()
+
+	msg, err := gcm.Open(nil, nonce, data[pos:], header)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt data err %w", err)
+	}
+	return msg, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher err %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm err %w", err)
+	}
+	return gcm, nil
+}
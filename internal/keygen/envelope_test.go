@@ -0,0 +1,62 @@
+package keygen_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/keygen"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvelope(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// данные больше, чем RSA-OAEP может зашифровать напрямую
+	msg := bytes.Repeat([]byte("metrics batch "), 100000)
+
+	encrypted, err := keygen.EncryptEnvelope(msg, &privateKey.PublicKey)
+	require.NoError(t, err)
+
+	decrypted, err := keygen.DecryptEnvelope(encrypted, privateKey)
+	require.NoError(t, err)
+	require.Equal(t, msg, decrypted)
+
+	t.Run("legacy", func(t *testing.T) {
+		legacyMsg := []byte("legacy agent payload")
+		legacy, err := keygen.EncryptWithPublicKey(legacyMsg, &privateKey.PublicKey)
+		require.NoError(t, err)
+
+		decrypted, err := keygen.DecryptEnvelope(legacy, privateKey)
+		require.NoError(t, err)
+		require.Equal(t, legacyMsg, decrypted)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		tampered := bytes.Clone(encrypted)
+		tampered[len(tampered)-1] ^= 0xff
+		_, err := keygen.DecryptEnvelope(tampered, privateKey)
+		require.Error(t, err)
+	})
+
+	t.Run("tampered_header", func(t *testing.T) {
+		tampered := bytes.Clone(encrypted)
+		tampered[4] = 2
+		_, err := keygen.DecryptEnvelope(tampered, privateKey)
+		require.ErrorIs(t, err, keygen.ErrEnvelopeFormat)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		_, err := keygen.DecryptEnvelope(encrypted[:10], privateKey)
+		require.ErrorIs(t, err, keygen.ErrEnvelopeFormat)
+	})
+
+	t.Run("wrong_key", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		_, err = keygen.DecryptEnvelope(encrypted, otherKey)
+		require.Error(t, err)
+	})
+}
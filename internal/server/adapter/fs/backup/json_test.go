@@ -25,6 +25,7 @@ func getLogger() *zap.SugaredLogger {
 var toWrite = []domain.Metrics{
 	{MType: domain.CounterType, ID: "PollCount", Delta: domain.DeltaPtr(1)},
 	{MType: domain.GaugeType, ID: "RandomValue", Value: domain.ValuePtr(1.123)},
+	{MType: domain.GaugeType, ID: "RandomValue", Value: domain.ValuePtr(2.5), Labels: domain.Labels{"host": "h1"}},
 	{MType: domain.GaugeType, ID: "Alloc", Value: domain.ValuePtr(1.123)},
 	{MType: domain.GaugeType, ID: "BuckHashSys", Value: domain.ValuePtr(1.123)},
 	{MType: domain.GaugeType, ID: "Frees", Value: domain.ValuePtr(1.123)},
@@ -52,6 +53,12 @@ var toWrite = []domain.Metrics{
 	{MType: domain.GaugeType, ID: "StackSys", Value: domain.ValuePtr(1.123)},
 	{MType: domain.GaugeType, ID: "Sys", Value: domain.ValuePtr(1.123)},
 	{MType: domain.GaugeType, ID: "TotalAlloc", Value: domain.ValuePtr(1.123)},
+	{MType: domain.HistogramType, ID: "GCPause", Histogram: &domain.Histogram{
+		Bounds: []float64{0.001, 0.01},
+		Counts: []int64{3, 1, 0},
+		Sum:    0.012,
+		Count:  4,
+	}},
 }
 
 func TestJsonFormatter(t *testing.T) {
@@ -0,0 +1,85 @@
+// Package rules отвечает за чтение правил оповещения
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+func NewJSON(fileName string) *jsonReader {
+	return &jsonReader{
+		fileName: fileName,
+	}
+}
+
+type jsonReader struct {
+	fileName string
+}
+
+// rulesFile формат файла правил.
+//
+//	{
+//	  "rules": [
+//	    {"name": "HighAlloc", "metric": "Alloc", "type": "gauge", "op": ">", "threshold": 1e9, "for": "1m"},
+//	    {"name": "HeapNearSys", "metric": "HeapAlloc", "type": "gauge", "op": ">=", "compare_to": "HeapSys"}
+//	  ]
+//	}
+type rulesFile struct {
+	Rules []rule `json:"rules"`
+}
+
+type rule struct {
+	Name      string            `json:"name"`
+	Metric    string            `json:"metric"`
+	Type      domain.MetricType `json:"type"`
+	Labels    domain.Labels     `json:"labels"`
+	Op        domain.CompareOp  `json:"op"`
+	Threshold *float64          `json:"threshold"`
+	CompareTo string            `json:"compare_to"`
+	For       string            `json:"for"`
+}
+
+func (jr *jsonReader) Read(ctx context.Context) ([]domain.AlertRule, error) {
+	logger := domain.GetCtxLogger(ctx)
+	action := domain.GetAction(1)
+
+	file, err := os.Open(jr.fileName)
+	if err != nil {
+		logger.Errorw(action, "error", fmt.Sprintf("can't open rules file %v", jr.fileName))
+		return nil, err
+	}
+	defer file.Close()
+
+	var rf rulesFile
+	if err := json.NewDecoder(file).Decode(&rf); err != nil {
+		return nil, fmt.Errorf("%w: rules file %v decode error - %v", domain.ErrDataFormat, jr.fileName, err.Error())
+	}
+
+	result := make([]domain.AlertRule, 0, len(rf.Rules))
+	for _, r := range rf.Rules {
+		var forDuration time.Duration
+		if r.For != "" {
+			if forDuration, err = time.ParseDuration(r.For); err != nil {
+				return nil, fmt.Errorf("%w: rule %v wrong for duration - %v", domain.ErrDataFormat, r.Name, err.Error())
+			}
+		}
+
+		result = append(result, domain.AlertRule{
+			Name:      r.Name,
+			Metric:    r.Metric,
+			MType:     r.Type,
+			Labels:    r.Labels.Copy(),
+			Op:        r.Op,
+			Threshold: r.Threshold,
+			CompareTo: r.CompareTo,
+			For:       forDuration,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package rules_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/fs/rules"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func getLogger() *zap.SugaredLogger {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		// вызываем панику, если ошибка
+		panic("cannot initialize zap")
+	}
+
+	return logger.Sugar()
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	fileName := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(fileName, []byte(content), 0600))
+	return fileName
+}
+
+func TestRead(t *testing.T) {
+	domain.SetMainLogger(getLogger())
+
+	fileName := writeFile(t, `{
+		"rules": [
+			{"name": "HighAlloc", "metric": "Alloc", "type": "gauge", "labels": {"zone": "eu"}, "op": ">", "threshold": 100, "for": "1m"},
+			{"name": "HeapNearSys", "metric": "HeapAlloc", "type": "gauge", "op": ">=", "compare_to": "HeapSys"}
+		]
+	}`)
+
+	res, err := rules.NewJSON(fileName).Read(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []domain.AlertRule{
+		{
+			Name:      "HighAlloc",
+			Metric:    "Alloc",
+			MType:     domain.GaugeType,
+			Labels:    domain.Labels{"zone": "eu"},
+			Op:        domain.OpGreater,
+			Threshold: domain.ValuePtr(100),
+			For:       time.Minute,
+		},
+		{
+			Name:      "HeapNearSys",
+			Metric:    "HeapAlloc",
+			MType:     domain.GaugeType,
+			Op:        domain.OpGreaterEqual,
+			CompareTo: "HeapSys",
+		},
+	}, res)
+}
+
+func TestReadErrors(t *testing.T) {
+	domain.SetMainLogger(getLogger())
+
+	_, err := rules.NewJSON(filepath.Join(t.TempDir(), "unknown.json")).Read(context.Background())
+	require.True(t, errors.Is(err, os.ErrNotExist))
+
+	_, err = rules.NewJSON(writeFile(t, `{"rules": [`)).Read(context.Background())
+	require.True(t, errors.Is(err, domain.ErrDataFormat))
+
+	_, err = rules.NewJSON(writeFile(t, `{"rules": [{"name": "r", "for": "1 minute"}]}`)).Read(context.Background())
+	require.True(t, errors.Is(err, domain.ErrDataFormat))
+}
@@ -0,0 +1,2 @@
+// Package graphite содержит TCP-приемник метрик в формате Graphite plaintext
+package graphite
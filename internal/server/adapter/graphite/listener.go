@@ -0,0 +1,204 @@
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+const (
+	readTimeout  = time.Minute // соединение без данных дольше readTimeout закрывается
+	maxBatchSize = 1000        // максимальное количество значений в одном вызове UpdateAll
+	maxLineSize  = 64 << 10    // соединение со строкой длиннее maxLineSize закрывается
+)
+
+func NewListener(address string, maxConns int, metricApp MetricApp) *listener {
+	return &listener{
+		address:   address,
+		maxConns:  maxConns,
+		metricApp: metricApp,
+		conns:     make(map[net.Conn]struct{}),
+	}
+}
+
+// listener принимает строки Graphite plaintext по TCP и сохраняет значения через UpdateAll.
+//
+// Одновременно обслуживается не более maxConns соединений; сверх лимита соединения закрываются сразу.
+// Значения сохраняются пачками - когда прочитаны все полученные данные или накоплено maxBatchSize значений.
+type listener struct {
+	address   string
+	maxConns  int
+	metricApp MetricApp
+	ln        net.Listener
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// Listen открывает TCP-порт.
+func (l *listener) Listen() error {
+	ln, err := net.Listen("tcp", l.address)
+	if err != nil {
+		return err
+	}
+	l.ln = ln
+	return nil
+}
+
+// Addr возвращает адрес открытого порта.
+func (l *listener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// Serve принимает соединения до отмены контекста.
+//
+// При остановке новые соединения не принимаются, чтение открытых соединений прерывается,
+// полученные значения сохраняются, после чего Serve завершается.
+func (l *listener) Serve(ctx context.Context) {
+	logger := domain.GetCtxLogger(ctx)
+	action := domain.GetAction(1)
+
+	go func() {
+		<-ctx.Done()
+		l.ln.Close()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for conn := range l.conns {
+			conn.SetReadDeadline(time.Now())
+		}
+	}()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, l.maxConns)
+
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				break
+			}
+			logger.Errorw(action, "status", "error", "msg", err.Error())
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		default:
+			logger.Infow(action, "status", "error", "msg", "connection limit reached", "remote", conn.RemoteAddr().String())
+			conn.Close()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			l.handle(ctx, conn)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (l *listener) handle(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	if !l.track(ctx, conn) {
+		return
+	}
+	defer l.untrack(conn)
+
+	logger := domain.GetCtxLogger(ctx)
+	action := domain.GetAction(1)
+
+	// полученные до остановки значения должны быть сохранены
+	flushCtx := context.WithoutCancel(ctx)
+
+	batch := make([]domain.Metrics, 0, maxBatchSize)
+	// сканер читает из соединения, только когда разобраны все полученные строки
+	reader := &connReader{conn: conn, beforeRead: func() error {
+		batch = l.flush(flushCtx, batch)
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		// проверка после установки ожидания: при остановке ожидание сбрасывается после отмены контекста
+		return ctx.Err()
+	}}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		m, parseErr := parseLine(line)
+		if parseErr != nil {
+			logger.Infow(action, "status", "error", "msg", parseErr.Error())
+		} else {
+			batch = append(batch, *m)
+		}
+		if len(batch) >= maxBatchSize {
+			batch = l.flush(flushCtx, batch)
+		}
+	}
+
+	var netErr net.Error
+	switch err := scanner.Err(); {
+	case errors.Is(err, bufio.ErrTooLong):
+		logger.Infow(action, "status", "error", "msg", "line is longer than "+strconv.Itoa(maxLineSize)+" bytes, connection closed")
+	case err != nil && ctx.Err() == nil && !(errors.As(err, &netErr) && netErr.Timeout()):
+		logger.Infow(action, "status", "error", "msg", err.Error())
+	}
+	l.flush(flushCtx, batch)
+}
+
+// connReader перед ожиданием данных соединения вызывает beforeRead; ошибка beforeRead прекращает чтение.
+type connReader struct {
+	conn       net.Conn
+	beforeRead func() error
+}
+
+func (r *connReader) Read(p []byte) (int, error) {
+	if err := r.beforeRead(); err != nil {
+		return 0, err
+	}
+	return r.conn.Read(p)
+}
+
+// track регистрирует соединение для прерывания при остановке; после отмены контекста соединение не регистрируется.
+func (l *listener) track(ctx context.Context, conn net.Conn) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ctx.Err() != nil {
+		return false
+	}
+	l.conns[conn] = struct{}{}
+	return true
+}
+
+func (l *listener) untrack(conn net.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.conns, conn)
+}
+
+// flush сохраняет значения и возвращает пустой буфер для следующей пачки.
+func (l *listener) flush(ctx context.Context, batch []domain.Metrics) []domain.Metrics {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := l.metricApp.UpdateAll(ctx, batch); err != nil {
+		logger := domain.GetCtxLogger(ctx)
+		action := domain.GetAction(1)
+		logger.Errorw(action, "status", "error", "msg", err.Error())
+	}
+	return make([]domain.Metrics, 0, maxBatchSize)
+}
@@ -0,0 +1,194 @@
+package graphite_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/graphite"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// collector сохраняет все значения, переданные в UpdateAll.
+type collector struct {
+	mu      sync.Mutex
+	metrics []domain.Metrics
+}
+
+func (c *collector) add(ctx context.Context, mtr []domain.Metrics) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = append(c.metrics, mtr...)
+	return nil
+}
+
+func (c *collector) get() []domain.Metrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]domain.Metrics(nil), c.metrics...)
+}
+
+func startListener(t *testing.T, maxConns int, metricApp graphite.MetricApp) (string, context.CancelFunc, chan struct{}) {
+	t.Helper()
+
+	logger, err := zap.NewDevelopment()
+	require.NoError(t, err)
+	domain.SetMainLogger(logger.Sugar())
+
+	l := graphite.NewListener("127.0.0.1:0", maxConns, metricApp)
+	require.NoError(t, l.Listen())
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Serve(ctx)
+	}()
+
+	return l.Addr().String(), cancelFn, done
+}
+
+func TestListener(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := &collector{}
+	m := NewMockMetricApp(ctrl)
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).DoAndReturn(c.add).MinTimes(1)
+
+	addr, cancelFn, done := startListener(t, 10, m)
+	defer func() {
+		cancelFn()
+		<-done
+	}()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte("servers.web-1.cpu.load 0.75 1700000000\n" +
+		"wrong line\n" +
+		"1servers.cpu 1 1700000000\n" +
+		"disk.free;host=h1;mount.point=/var 1024 -1\n" +
+		"temp 20.5 1700000000"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	expected := []domain.Metrics{
+		{ID: "servers_web_1_cpu_load", MType: domain.GaugeType, Value: domain.ValuePtr(0.75)},
+		{ID: "disk_free", MType: domain.GaugeType, Value: domain.ValuePtr(1024), Labels: domain.Labels{"host": "h1", "mount_point": "/var"}},
+		{ID: "temp", MType: domain.GaugeType, Value: domain.ValuePtr(20.5)},
+	}
+	require.Eventually(t, func() bool {
+		return len(c.get()) == len(expected)
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, expected, c.get())
+}
+
+func TestListener_ConnectionLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := &collector{}
+	m := NewMockMetricApp(ctrl)
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).DoAndReturn(c.add).AnyTimes()
+
+	addr, cancelFn, done := startListener(t, 1, m)
+	defer func() {
+		cancelFn()
+		<-done
+	}()
+
+	first, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer first.Close()
+
+	// дожидаемся, что первое соединение обслуживается
+	_, err = first.Write([]byte("first 1 1\n"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(c.get()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	second, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer second.Close()
+
+	// соединение сверх лимита закрывается сервером
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = second.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestListener_LongLine(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := &collector{}
+	m := NewMockMetricApp(ctrl)
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).DoAndReturn(c.add).AnyTimes()
+
+	addr, cancelFn, done := startListener(t, 10, m)
+	defer func() {
+		cancelFn()
+		<-done
+	}()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// данные без перевода строки не накапливаются - соединение закрывается сервером
+	_, err = conn.Write([]byte("value 1 1\n"))
+	require.NoError(t, err)
+	_, err = conn.Write(bytes.Repeat([]byte("a"), 100<<10))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = io.ReadAll(conn)
+	var netErr net.Error
+	require.False(t, errors.As(err, &netErr) && netErr.Timeout(), "connection is not closed")
+
+	require.Eventually(t, func() bool {
+		return len(c.get()) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestListener_Shutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	c := &collector{}
+	m := NewMockMetricApp(ctrl)
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).DoAndReturn(c.add).MinTimes(1)
+
+	addr, cancelFn, done := startListener(t, 10, m)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("value 1 1\n"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(c.get()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// открытое соединение не задерживает остановку
+	cancelFn()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("listener is not stopped")
+	}
+
+	_, err = net.Dial("tcp", addr)
+	require.Error(t, err)
+}
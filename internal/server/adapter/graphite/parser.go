@@ -0,0 +1,69 @@
+package graphite
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+// parseLine разбирает строку вида path[;tag=value...] value timestamp в gauge.
+//
+// Точки и другие недопустимые в имени метрики символы пути заменяются на '_': servers.web-1.cpu - servers_web_1_cpu.
+// Теги Graphite сохраняются как метки. Значение сохраняется на момент приема,
+// поэтому от временной метки требуется только быть числом.
+func parseLine(line string) (*domain.Metrics, error) {
+	parts := strings.Fields(line)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: graphite line '%v' must contain path, value and timestamp", domain.ErrDataFormat, line)
+	}
+
+	value, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("%w: graphite line '%v' wrong value", domain.ErrDataFormat, line)
+	}
+
+	if _, err := strconv.ParseFloat(parts[2], 64); err != nil {
+		return nil, fmt.Errorf("%w: graphite line '%v' wrong timestamp", domain.ErrDataFormat, line)
+	}
+
+	pathParts := strings.Split(parts[0], ";")
+	name, err := pathToID(pathParts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var labels domain.Labels
+	for _, tag := range pathParts[1:] {
+		key, tagValue, ok := strings.Cut(tag, "=")
+		if !ok || key == "" || tagValue == "" {
+			return nil, fmt.Errorf("%w: graphite line '%v' wrong tag '%v'", domain.ErrDataFormat, line, tag)
+		}
+		labelName, err := pathToID(key)
+		if err != nil {
+			return nil, err
+		}
+		if labels == nil {
+			labels = make(domain.Labels)
+		}
+		labels[labelName] = tagValue
+	}
+
+	return &domain.Metrics{
+		ID:     name,
+		MType:  domain.GaugeType,
+		Value:  domain.ValuePtr(value),
+		Labels: labels,
+	}, nil
+}
+
+// pathToID преобразует путь Graphite в имя метрики; путь должен начинаться с буквы.
+func pathToID(path string) (string, error) {
+	name := domain.SanitizeName(path)
+	if name == "" || name[0] == '_' || name[0] >= '0' && name[0] <= '9' {
+		return "", fmt.Errorf("%w: wrong graphite path '%v'", domain.ErrDataFormat, path)
+	}
+	return name, nil
+}
@@ -10,4 +10,5 @@ import (
 
 type MetricApp interface {
 	UpdateAll(ctx context.Context, mtr []domain.Metrics) error
+	GetHistory(ctx context.Context, q *domain.HistoryQuery) (*domain.MetricHistory, error)
 }
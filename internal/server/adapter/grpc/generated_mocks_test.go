@@ -35,6 +35,21 @@ func (m *MockMetricApp) EXPECT() *MockMetricAppMockRecorder {
 	return m.recorder
 }
 
+// GetHistory mocks base method.
+func (m *MockMetricApp) GetHistory(arg0 context.Context, arg1 *domain.HistoryQuery) (*domain.MetricHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistory", arg0, arg1)
+	ret0, _ := ret[0].(*domain.MetricHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistory indicates an expected call of GetHistory.
+func (mr *MockMetricAppMockRecorder) GetHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistory", reflect.TypeOf((*MockMetricApp)(nil).GetHistory), arg0, arg1)
+}
+
 // UpdateAll mocks base method.
 func (m *MockMetricApp) UpdateAll(arg0 context.Context, arg1 []domain.Metrics) error {
 	m.ctrl.T.Helper()
@@ -5,7 +5,9 @@ import (
 
 	pb "github.com/StasMerzlyakov/go-metrics/internal/proto"
 	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func NewGRPCAdapter(mApp MetricApp) *adapter {
@@ -14,6 +16,9 @@ func NewGRPCAdapter(mApp MetricApp) *adapter {
 	}
 }
 
+// IdempotencyKeyMD ключ метаданных с ключом пакета метрик
+const IdempotencyKeyMD = "x-idempotency-key"
+
 type adapter struct {
 	pb.UnimplementedMetricsServer // хак какой-то!!
 	mApp                          MetricApp
@@ -27,21 +32,37 @@ func (ad *adapter) Update(ctx context.Context, req *pb.MetricsRequest) (*pb.Metr
 		switch mtr.Type {
 		case pb.Metric_GAUGE:
 			metric := domain.Metrics{
-				ID:    mtr.Name,
-				MType: domain.GaugeType,
-				Value: domain.ValuePtr(mtr.Value),
+				ID:     mtr.Name,
+				MType:  domain.GaugeType,
+				Value:  domain.ValuePtr(mtr.Value),
+				Labels: domain.Labels(mtr.GetLabels()).Copy(),
+			}
+			metrics = append(metrics, metric)
+		case pb.Metric_HISTOGRAM:
+			metric := domain.Metrics{
+				ID:        mtr.Name,
+				MType:     domain.HistogramType,
+				Histogram: fromPBHistogram(mtr.GetHistogram()),
+				Labels:    domain.Labels(mtr.GetLabels()).Copy(),
 			}
 			metrics = append(metrics, metric)
 		default:
 			metric := domain.Metrics{
-				ID:    mtr.Name,
-				MType: domain.CounterType,
-				Delta: domain.DeltaPtr(mtr.Delta),
+				ID:     mtr.Name,
+				MType:  domain.CounterType,
+				Delta:  domain.DeltaPtr(mtr.Delta),
+				Labels: domain.Labels(mtr.GetLabels()).Copy(),
 			}
 			metrics = append(metrics, metric)
 		}
 	}
 
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if keys := md.Get(IdempotencyKeyMD); len(keys) > 0 && keys[0] != "" {
+			ctx = domain.EnrichWithIdempotencyKey(ctx, keys[0])
+		}
+	}
+
 	if err := ad.mApp.UpdateAll(ctx, metrics); err != nil {
 		code := MapDomainErrorToGRPCCodeErr(err)
 		return nil, status.Error(code, "")
@@ -49,3 +70,58 @@ func (ad *adapter) Update(ctx context.Context, req *pb.MetricsRequest) (*pb.Metr
 
 	return nil, nil
 }
+
+func (ad *adapter) History(ctx context.Context, req *pb.HistoryRequest) (*pb.HistoryResponse, error) {
+	query := &domain.HistoryQuery{
+		ID:     req.GetName(),
+		MType:  fromPBType(req.GetType()),
+		Labels: domain.Labels(req.GetLabels()).Copy(),
+	}
+
+	if req.GetFrom() != nil {
+		query.From = req.GetFrom().AsTime()
+	}
+
+	if req.GetTo() != nil {
+		query.To = req.GetTo().AsTime()
+	}
+
+	history, err := ad.mApp.GetHistory(ctx, query)
+	if err != nil {
+		code := MapDomainErrorToGRPCCodeErr(err)
+		return nil, status.Error(code, "")
+	}
+
+	resp := &pb.HistoryResponse{}
+	for _, sample := range history.Samples {
+		resp.Samples = append(resp.Samples, &pb.Sample{
+			Timestamp: timestamppb.New(sample.Timestamp),
+			Value:     sample.Value,
+		})
+	}
+
+	return resp, nil
+}
+
+func fromPBType(t pb.Metric_Type) domain.MetricType {
+	switch t {
+	case pb.Metric_GAUGE:
+		return domain.GaugeType
+	case pb.Metric_HISTOGRAM:
+		return domain.HistogramType
+	default:
+		return domain.CounterType
+	}
+}
+
+func fromPBHistogram(h *pb.Histogram) *domain.Histogram {
+	if h == nil {
+		return nil
+	}
+	return &domain.Histogram{
+		Bounds: h.GetBounds(),
+		Counts: h.GetCounts(),
+		Sum:    h.GetSum(),
+		Count:  h.GetCount(),
+	}
+}
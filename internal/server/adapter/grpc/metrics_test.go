@@ -0,0 +1,96 @@
+package grpc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/StasMerzlyakov/go-metrics/internal/proto"
+	gdpt "github.com/StasMerzlyakov/go-metrics/internal/server/adapter/grpc"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	to := time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC)
+	from := to.Add(-time.Minute)
+
+	m.EXPECT().GetHistory(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, q *domain.HistoryQuery) (*domain.MetricHistory, error) {
+			require.Equal(t, "PollCount", q.ID)
+			require.Equal(t, domain.CounterType, q.MType)
+			require.Equal(t, domain.Labels{"host": "h1"}, q.Labels)
+			require.True(t, from.Equal(q.From))
+			require.True(t, q.To.IsZero())
+			return &domain.MetricHistory{
+				ID:    q.ID,
+				MType: q.MType,
+				Samples: []domain.Sample{
+					{Timestamp: from, Value: 1},
+					{Timestamp: to, Value: 3},
+				},
+			}, nil
+		}).Times(1)
+
+	m.EXPECT().GetHistory(gomock.Any(), gomock.Any()).Return(nil, domain.ErrDataFormat).Times(1)
+
+	ad := gdpt.NewGRPCAdapter(m)
+
+	resp, err := ad.History(context.Background(), &pb.HistoryRequest{
+		Name:   "PollCount",
+		Type:   pb.Metric_COUNTER,
+		Labels: map[string]string{"host": "h1"},
+		From:   timestamppb.New(from),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(resp.GetSamples()))
+	require.True(t, to.Equal(resp.GetSamples()[1].GetTimestamp().AsTime()))
+	require.Equal(t, float64(3), resp.GetSamples()[1].GetValue())
+
+	_, err = ad.History(context.Background(), &pb.HistoryRequest{
+		Name: "0PollCount",
+		Type: pb.Metric_GAUGE,
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestUpdateIdempotencyKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	var keys []string
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ms []domain.Metrics) error {
+			keys = append(keys, domain.GetIdempotencyKey(ctx))
+			return nil
+		}).Times(2)
+
+	ad := gdpt.NewGRPCAdapter(m)
+
+	req := &pb.MetricsRequest{
+		Metrics: []*pb.Metric{
+			{Name: "PollCount", Type: pb.Metric_COUNTER, Delta: 1},
+		},
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(gdpt.IdempotencyKeyMD, "batch-1"))
+	_, err := ad.Update(ctx, req)
+	require.NoError(t, err)
+
+	_, err = ad.Update(context.Background(), req)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"batch-1", ""}, keys)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func AddAlertOperations(r *chi.Mux, alertApp AlertApp) {
+
+	adapter := &alertOperationAdapter{
+		alertApp: alertApp,
+	}
+
+	r.Get("/alerts", adapter.Alerts)
+}
+
+type alertOperationAdapter struct {
+	alertApp AlertApp
+}
+
+// Alerts возвращает список активных оповещений.
+//
+// GET /alerts
+//
+// В ответе: массив структур [domain.Alert] в состояниях pending и firing.
+func (h *alertOperationAdapter) Alerts(w http.ResponseWriter, req *http.Request) {
+	if _, err := io.ReadAll(req.Body); err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+	defer req.Body.Close()
+
+	alerts, err := h.alertApp.GetAlerts(req.Context())
+	if err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ApplicationJSON)
+	if err := json.NewEncoder(w).Encode(alerts); err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+}
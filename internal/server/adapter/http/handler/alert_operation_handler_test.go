@@ -0,0 +1,72 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/handler"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertOperation_Alerts(t *testing.T) {
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockAlertApp(ctrl)
+
+	activeAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	alerts := []domain.Alert{
+		{
+			Rule:      "HighAlloc",
+			Metric:    "Alloc",
+			MType:     domain.GaugeType,
+			Labels:    domain.Labels{"host": "h1"},
+			State:     domain.AlertFiring,
+			Value:     200,
+			Threshold: 100,
+			ActiveAt:  activeAt,
+			FiredAt:   activeAt.Add(time.Minute),
+		},
+	}
+
+	m.EXPECT().GetAlerts(gomock.Any()).Return(alerts, nil).Times(1)
+	m.EXPECT().GetAlerts(gomock.Any()).Return(nil, domain.ErrServerInternal).Times(1)
+
+	r := chi.NewRouter()
+
+	log := logger()
+	domain.SetMainLogger(log)
+	handler.AddAlertOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	req := resty.New().R()
+	req.Method = http.MethodGet
+	req.URL = srv.URL + "/alerts"
+	resp, err := req.Send()
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, handler.ApplicationJSON, resp.Header().Get("Content-Type"))
+
+	var respAlerts []domain.Alert
+	err = json.Unmarshal(resp.Body(), &respAlerts)
+	require.Nil(t, err)
+	require.Equal(t, alerts, respAlerts)
+
+	req = resty.New().R()
+	req.Method = http.MethodGet
+	req.URL = srv.URL + "/alerts"
+	resp, err = req.Send()
+	require.Nil(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+}
@@ -6,7 +6,7 @@ import (
 	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
 )
 
-//go:generate mockgen -destination "./generated_mocks_test.go" -package ${GOPACKAGE}_test . AdminApp,MetricApp
+//go:generate mockgen -destination "./generated_mocks_test.go" -package ${GOPACKAGE}_test . AdminApp,MetricApp,AlertApp
 
 type AdminApp interface {
 	Ping(ctx context.Context) error
@@ -14,7 +14,12 @@ type AdminApp interface {
 
 type MetricApp interface {
 	GetAllMetrics(ctx context.Context) ([]domain.Metrics, error)
-	Get(ctx context.Context, metricType domain.MetricType, name string) (*domain.Metrics, error)
+	Get(ctx context.Context, metricType domain.MetricType, name string, labels domain.Labels) (*domain.Metrics, error)
 	UpdateAll(ctx context.Context, mtr []domain.Metrics) error
 	Update(ctx context.Context, mtr *domain.Metrics) (*domain.Metrics, error)
+	GetHistory(ctx context.Context, q *domain.HistoryQuery) (*domain.MetricHistory, error)
+}
+
+type AlertApp interface {
+	GetAlerts(ctx context.Context) ([]domain.Alert, error)
 }
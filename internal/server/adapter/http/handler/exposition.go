@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+const (
+	PrometheusText = "text/plain; version=0.0.4; charset=utf-8"
+	OpenMetrics    = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Exposition возвращает все метрики в текстовом формате Prometheus.
+//
+// GET /metrics
+//
+// Если клиент предпочитает application/openmetrics-text (заголовок Accept), ответ формируется в формате OpenMetrics.
+func (h *metricOperationAdapter) Exposition(w http.ResponseWriter, req *http.Request) {
+	if _, err := io.ReadAll(req.Body); err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+	defer req.Body.Close()
+
+	metricses, err := h.metricApp.GetAllMetrics(req.Context())
+	if err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	openMetrics := acceptsOpenMetrics(req.Header.Get("Accept"))
+	if openMetrics {
+		w.Header().Set("Content-Type", OpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", PrometheusText)
+	}
+
+	bw := bufio.NewWriter(w)
+	writeExposition(bw, metricses, openMetrics, domain.GetCtxLogger(req.Context()))
+	bw.Flush()
+}
+
+// acceptsOpenMetrics разбирает заголовок Accept и проверяет, что формат OpenMetrics имеет приоритет не ниже текстового.
+func acceptsOpenMetrics(accept string) bool {
+	openMetricsQ, textQ := -1.0, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if qStr, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(qStr, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case "application/openmetrics-text":
+			openMetricsQ = math.Max(openMetricsQ, q)
+		case "text/plain", "text/*", "*/*":
+			textQ = math.Max(textQ, q)
+		}
+	}
+	return openMetricsQ > 0 && openMetricsQ >= textQ
+}
+
+// metricFamily метрики с одинаковым именем и типом.
+type metricFamily struct {
+	name    string // имя семейства в ответе
+	id      string // имя метрики в хранилище
+	mType   domain.MetricType
+	metrics []domain.Metrics
+}
+
+func newMetricFamily(m *domain.Metrics, openMetrics bool) *metricFamily {
+	name := m.ID
+	// в OpenMetrics имя семейства counter не содержит суффикс _total, а имя значения - содержит
+	if m.MType == domain.CounterType && openMetrics {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	return &metricFamily{name: name, id: m.ID, mType: m.MType}
+}
+
+// names возвращает имя семейства и имена его значений.
+func (f *metricFamily) names(openMetrics bool) []string {
+	switch {
+	case f.mType == domain.CounterType && openMetrics:
+		return []string{f.name, f.name + "_total"}
+	case f.mType == domain.HistogramType:
+		return []string{f.name, f.name + "_bucket", f.name + "_sum", f.name + "_count"}
+	default:
+		return []string{f.name}
+	}
+}
+
+// writeExposition выводит метрики по семействам.
+//
+// Хранилище допускает метрики разных типов с одним именем, а имена значений histogram (_bucket, _sum, _count)
+// и counter в OpenMetrics (_total) могут совпасть с именами других метрик; Prometheus отклоняет такой ответ целиком.
+// Поэтому семейство, имена которого уже заняты, пропускается: семейства обрабатываются в порядке имени, типа
+// и имени метрики в хранилище. Также пропускаются histogram с меткой le, совпадающей с меткой корзины.
+func writeExposition(w *bufio.Writer, metricses []domain.Metrics, openMetrics bool, logger domain.Logger) {
+	action := domain.GetAction(1)
+
+	families := make(map[string]*metricFamily)
+	for _, m := range metricses {
+		if _, ok := m.Labels["le"]; ok && m.MType == domain.HistogramType {
+			logger.Infow(action, "status", "skipped", "metric", m.ID, "type", m.MType, "msg", "histogram has le label")
+			continue
+		}
+
+		key := string(m.MType) + ":" + m.ID
+		family, ok := families[key]
+		if !ok {
+			family = newMetricFamily(&m, openMetrics)
+			families[key] = family
+		}
+		family.metrics = append(family.metrics, m)
+	}
+
+	sorted := make([]*metricFamily, 0, len(families))
+	for _, family := range families {
+		sort.Slice(family.metrics, func(i, j int) bool {
+			return family.metrics[i].Labels.String() < family.metrics[j].Labels.String()
+		})
+		sorted = append(sorted, family)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].name != sorted[j].name {
+			return sorted[i].name < sorted[j].name
+		}
+		if sorted[i].mType != sorted[j].mType {
+			return sorted[i].mType < sorted[j].mType
+		}
+		return sorted[i].id < sorted[j].id
+	})
+
+	owners := make(map[string]*metricFamily) // имя в ответе - семейство, которому оно принадлежит
+	for _, family := range sorted {
+		names := family.names(openMetrics)
+		if owner := nameOwner(owners, names); owner != nil {
+			logger.Infow(action, "status", "skipped", "metric", family.id, "type", family.mType,
+				"msg", "name conflicts with "+string(owner.mType)+" "+owner.id)
+			continue
+		}
+		for _, name := range names {
+			owners[name] = family
+		}
+		writeFamily(w, family, openMetrics)
+	}
+
+	if openMetrics {
+		w.WriteString("# EOF\n")
+	}
+}
+
+func nameOwner(owners map[string]*metricFamily, names []string) *metricFamily {
+	for _, name := range names {
+		if owner, ok := owners[name]; ok {
+			return owner
+		}
+	}
+	return nil
+}
+
+func writeFamily(w *bufio.Writer, family *metricFamily, openMetrics bool) {
+	name := family.name
+
+	switch family.mType {
+	case domain.CounterType:
+		sampleName := name
+		if openMetrics {
+			sampleName = name + "_total"
+		}
+		writeTypeLine(w, name, "counter")
+		for _, m := range family.metrics {
+			writeSample(w, sampleName, m.Labels, "", "", strconv.FormatInt(*m.Delta, 10))
+		}
+	case domain.GaugeType:
+		writeTypeLine(w, name, "gauge")
+		for _, m := range family.metrics {
+			writeSample(w, name, m.Labels, "", "", formatFloat(*m.Value))
+		}
+	case domain.HistogramType:
+		writeTypeLine(w, name, "histogram")
+		for _, m := range family.metrics {
+			h := m.Histogram
+			var cumulative int64
+			for i, bound := range h.Bounds {
+				cumulative += h.Counts[i]
+				writeSample(w, name+"_bucket", m.Labels, "le", formatFloat(bound), strconv.FormatInt(cumulative, 10))
+			}
+			writeSample(w, name+"_bucket", m.Labels, "le", "+Inf", strconv.FormatInt(h.Count, 10))
+			writeSample(w, name+"_sum", m.Labels, "", "", formatFloat(h.Sum))
+			writeSample(w, name+"_count", m.Labels, "", "", strconv.FormatInt(h.Count, 10))
+		}
+	}
+}
+
+func writeTypeLine(w *bufio.Writer, name string, mType string) {
+	w.WriteString("# TYPE ")
+	w.WriteString(name)
+	w.WriteByte(' ')
+	w.WriteString(mType)
+	w.WriteByte('\n')
+}
+
+// writeSample выводит строку значения; extraName/extraValue - дополнительная метка (le для histogram).
+func writeSample(w *bufio.Writer, name string, labels domain.Labels, extraName, extraValue, value string) {
+	w.WriteString(name)
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if len(keys) > 0 || extraName != "" {
+		w.WriteByte('{')
+		first := true
+		writeLabel := func(k, v string) {
+			if !first {
+				w.WriteByte(',')
+			}
+			first = false
+			w.WriteString(k)
+			w.WriteString(`="`)
+			w.WriteString(escapeLabelValue(v))
+			w.WriteByte('"')
+		}
+		for _, k := range keys {
+			writeLabel(k, labels[k])
+		}
+		if extraName != "" {
+			writeLabel(extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteByte(' ')
+	w.WriteString(value)
+	w.WriteByte('\n')
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,202 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/handler"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+var expositionMetrics = []domain.Metrics{
+	{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(5)},
+	{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(1.5), Labels: domain.Labels{"host": "h2"}},
+	{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(2), Labels: domain.Labels{"host": "h1", "path": `c:\"x"`}},
+	{ID: "requests_total", MType: domain.CounterType, Delta: domain.DeltaPtr(7)},
+	{ID: "GCPause", MType: domain.HistogramType, Histogram: &domain.Histogram{
+		Bounds: []float64{0.001, 0.01},
+		Counts: []int64{3, 1, 2},
+		Sum:    0.5,
+		Count:  6,
+	}},
+}
+
+const expectedPrometheusText = `# TYPE Alloc gauge
+Alloc{host="h1",path="c:\\\"x\""} 2
+Alloc{host="h2"} 1.5
+# TYPE GCPause histogram
+GCPause_bucket{le="0.001"} 3
+GCPause_bucket{le="0.01"} 4
+GCPause_bucket{le="+Inf"} 6
+GCPause_sum 0.5
+GCPause_count 6
+# TYPE PollCount counter
+PollCount 5
+# TYPE requests_total counter
+requests_total 7
+`
+
+const expectedOpenMetrics = `# TYPE Alloc gauge
+Alloc{host="h1",path="c:\\\"x\""} 2
+Alloc{host="h2"} 1.5
+# TYPE GCPause histogram
+GCPause_bucket{le="0.001"} 3
+GCPause_bucket{le="0.01"} 4
+GCPause_bucket{le="+Inf"} 6
+GCPause_sum 0.5
+GCPause_count 6
+# TYPE PollCount counter
+PollCount_total 5
+# TYPE requests counter
+requests_total 7
+# EOF
+`
+
+func TestMetricOperation_Exposition(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+	m.EXPECT().GetAllMetrics(gomock.Any()).Return(expositionMetrics, nil).AnyTimes()
+
+	r := chi.NewRouter()
+	handler.AddMetricOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	testCases := []struct {
+		name        string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"no accept", "", handler.PrometheusText, expectedPrometheusText},
+		{"text", "text/plain;version=0.0.4", handler.PrometheusText, expectedPrometheusText},
+		{"openmetrics", "application/openmetrics-text;version=1.0.0", handler.OpenMetrics, expectedOpenMetrics},
+		{
+			"prometheus scraper",
+			"application/openmetrics-text;version=1.0.0,application/openmetrics-text;version=0.0.1;q=0.75,text/plain;version=0.0.4;q=0.5,*/*;q=0.1",
+			handler.OpenMetrics,
+			expectedOpenMetrics,
+		},
+		{"text preferred", "application/openmetrics-text;q=0.5,text/plain", handler.PrometheusText, expectedPrometheusText},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			req := resty.New().R()
+			req.Method = http.MethodGet
+			req.URL = srv.URL + "/metrics"
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+			resp, err := req.Send()
+			require.Nil(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode())
+			require.Equal(t, test.contentType, resp.Header().Get("Content-Type"))
+			require.Equal(t, test.body, string(resp.Body()))
+		})
+	}
+}
+
+func TestMetricOperation_ExpositionConflicts(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	histogram := &domain.Histogram{Bounds: []float64{1}, Counts: []int64{1, 0}, Sum: 0.5, Count: 1}
+	metrics := []domain.Metrics{
+		{ID: "jobs", MType: domain.GaugeType, Value: domain.ValuePtr(1)},
+		{ID: "jobs", MType: domain.CounterType, Delta: domain.DeltaPtr(2)},
+		{ID: "jobs_total", MType: domain.CounterType, Delta: domain.DeltaPtr(3)},
+		{ID: "lat", MType: domain.HistogramType, Histogram: histogram},
+		{ID: "lat_sum", MType: domain.GaugeType, Value: domain.ValuePtr(4)},
+		{ID: "size", MType: domain.HistogramType, Histogram: histogram, Labels: domain.Labels{"le": "1"}},
+	}
+
+	m := NewMockMetricApp(ctrl)
+	m.EXPECT().GetAllMetrics(gomock.Any()).Return(metrics, nil).AnyTimes()
+
+	r := chi.NewRouter()
+	handler.AddMetricOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	// из семейств с общими именами выводится первое по имени, типу и имени метрики
+	testCases := []struct {
+		name   string
+		accept string
+		body   string
+	}{
+		{"text", "", `# TYPE jobs counter
+jobs 2
+# TYPE jobs_total counter
+jobs_total 3
+# TYPE lat histogram
+lat_bucket{le="1"} 1
+lat_bucket{le="+Inf"} 1
+lat_sum 0.5
+lat_count 1
+`},
+		{"openmetrics", "application/openmetrics-text;version=1.0.0", `# TYPE jobs counter
+jobs_total 2
+# TYPE lat histogram
+lat_bucket{le="1"} 1
+lat_bucket{le="+Inf"} 1
+lat_sum 0.5
+lat_count 1
+# EOF
+`},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			req := resty.New().R()
+			req.Method = http.MethodGet
+			req.URL = srv.URL + "/metrics"
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+			resp, err := req.Send()
+			require.Nil(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode())
+			require.Equal(t, test.body, string(resp.Body()))
+		})
+	}
+}
+
+func TestMetricOperation_ExpositionError(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+	m.EXPECT().GetAllMetrics(gomock.Any()).Return(nil, domain.ErrDBConnection).Times(1)
+
+	r := chi.NewRouter()
+	handler.AddMetricOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	req := resty.New().R()
+	req.Method = http.MethodGet
+	req.URL = srv.URL + "/metrics"
+	resp, err := req.Send()
+	require.Nil(t, err)
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode())
+}
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/handler (interfaces: AdminApp,MetricApp)
+// Source: github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/handler (interfaces: AdminApp,MetricApp,AlertApp)
 
 // Package handler_test is a generated GoMock package.
 package handler_test
@@ -73,18 +73,18 @@ func (m *MockMetricApp) EXPECT() *MockMetricAppMockRecorder {
 }
 
 // Get mocks base method.
-func (m *MockMetricApp) Get(arg0 context.Context, arg1 domain.MetricType, arg2 string) (*domain.Metrics, error) {
+func (m *MockMetricApp) Get(arg0 context.Context, arg1 domain.MetricType, arg2 string, arg3 domain.Labels) (*domain.Metrics, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "Get", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(*domain.Metrics)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockMetricAppMockRecorder) Get(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockMetricAppMockRecorder) Get(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockMetricApp)(nil).Get), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockMetricApp)(nil).Get), arg0, arg1, arg2, arg3)
 }
 
 // GetAllMetrics mocks base method.
@@ -102,6 +102,21 @@ func (mr *MockMetricAppMockRecorder) GetAllMetrics(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllMetrics", reflect.TypeOf((*MockMetricApp)(nil).GetAllMetrics), arg0)
 }
 
+// GetHistory mocks base method.
+func (m *MockMetricApp) GetHistory(arg0 context.Context, arg1 *domain.HistoryQuery) (*domain.MetricHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistory", arg0, arg1)
+	ret0, _ := ret[0].(*domain.MetricHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistory indicates an expected call of GetHistory.
+func (mr *MockMetricAppMockRecorder) GetHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistory", reflect.TypeOf((*MockMetricApp)(nil).GetHistory), arg0, arg1)
+}
+
 // Update mocks base method.
 func (m *MockMetricApp) Update(arg0 context.Context, arg1 *domain.Metrics) (*domain.Metrics, error) {
 	m.ctrl.T.Helper()
@@ -130,3 +145,41 @@ func (mr *MockMetricAppMockRecorder) UpdateAll(arg0, arg1 interface{}) *gomock.C
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAll", reflect.TypeOf((*MockMetricApp)(nil).UpdateAll), arg0, arg1)
 }
+
+// MockAlertApp is a mock of AlertApp interface.
+type MockAlertApp struct {
+	ctrl     *gomock.Controller
+	recorder *MockAlertAppMockRecorder
+}
+
+// MockAlertAppMockRecorder is the mock recorder for MockAlertApp.
+type MockAlertAppMockRecorder struct {
+	mock *MockAlertApp
+}
+
+// NewMockAlertApp creates a new mock instance.
+func NewMockAlertApp(ctrl *gomock.Controller) *MockAlertApp {
+	mock := &MockAlertApp{ctrl: ctrl}
+	mock.recorder = &MockAlertAppMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAlertApp) EXPECT() *MockAlertAppMockRecorder {
+	return m.recorder
+}
+
+// GetAlerts mocks base method.
+func (m *MockAlertApp) GetAlerts(arg0 context.Context) ([]domain.Alert, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlerts", arg0)
+	ret0, _ := ret[0].([]domain.Alert)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlerts indicates an expected call of GetAlerts.
+func (mr *MockAlertAppMockRecorder) GetAlerts(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlerts", reflect.TypeOf((*MockAlertApp)(nil).GetAlerts), arg0)
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+func AddInfluxOperations(r *chi.Mux, metricApp MetricApp) {
+	adapter := &influxAdapter{
+		metricApp: metricApp,
+	}
+
+	r.Post("/write", adapter.Write)
+}
+
+type influxAdapter struct {
+	metricApp MetricApp
+}
+
+// Write принимает данные в формате InfluxDB line protocol.
+//
+// POST /write
+//
+// В запросе - строки вида measurement[,tag=value...] field=value[,field=value...] [timestamp].
+// Каждое поле сохраняется как метрика с именем measurement_field и метками из тегов:
+// целые значения (1i, 1u) - как counter, дробные - как gauge; строковые и логические поля пропускаются.
+// Временная метка проверяется, но не используется - сохраняется значение на момент приема.
+// Данные проходят ту же проверку и сохранение, что и в POST /updates/.
+// Тело запроса больше maxRequestBodySize отклоняется с кодом 413.
+func (h *influxAdapter) Write(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	var metrics []domain.Metrics
+
+	scanner := bufio.NewScanner(http.MaxBytesReader(w, req.Body, maxRequestBodySize))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lineMetrics, err := parseInfluxLine(line)
+		if err != nil {
+			fullErr := fmt.Errorf("%w: line %d: %v", domain.ErrDataFormat, lineNum, err.Error())
+			handleAppError(req.Context(), w, fullErr)
+			return
+		}
+		metrics = append(metrics, lineMetrics...)
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if err := scanner.Err(); errors.As(err, &maxBytesErr) {
+		fullErr := fmt.Errorf("%w: request body is larger than %d bytes", domain.ErrEntityTooLarge, maxBytesErr.Limit)
+		handleAppError(req.Context(), w, fullErr)
+		return
+	} else if err != nil {
+		fullErr := fmt.Errorf("%w: read error - %v", domain.ErrDataFormat, err.Error())
+		handleAppError(req.Context(), w, fullErr)
+		return
+	}
+
+	if len(metrics) > 0 {
+		if err := h.metricApp.UpdateAll(req.Context(), metrics); err != nil {
+			handleAppError(req.Context(), w, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseInfluxLine разбирает одну строку line protocol.
+func parseInfluxLine(line string) ([]domain.Metrics, error) {
+	parts := splitUnescaped(line, ' ', true)
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, fmt.Errorf("wrong line format")
+	}
+
+	if len(parts) == 3 {
+		if _, err := strconv.ParseInt(parts[2], 10, 64); err != nil {
+			return nil, fmt.Errorf("wrong timestamp %v", parts[2])
+		}
+	}
+
+	key := splitUnescaped(parts[0], ',', false)
+	measurement := influxUnescape(key[0])
+	if measurement == "" {
+		return nil, fmt.Errorf("measurement is empty")
+	}
+
+	var labels domain.Labels
+	for _, tag := range key[1:] {
+		kv := splitUnescaped(tag, '=', false)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("wrong tag %v", tag)
+		}
+		if labels == nil {
+			labels = make(domain.Labels)
+		}
+		labels[domain.SanitizeName(influxUnescape(kv[0]))] = influxUnescape(kv[1])
+	}
+
+	var metrics []domain.Metrics
+	for _, field := range splitUnescaped(parts[1], ',', true) {
+		kv := splitUnescaped(field, '=', true)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("wrong field %v", field)
+		}
+
+		m, ok, err := influxFieldMetric(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %v", kv[0], err.Error())
+		}
+		if !ok {
+			continue
+		}
+
+		m.ID = domain.SanitizeName(measurement + "_" + influxUnescape(kv[0]))
+		m.Labels = labels
+		metrics = append(metrics, m)
+	}
+
+	return metrics, nil
+}
+
+// influxFieldMetric определяет тип поля по значению; ok == false для строковых и логических полей.
+func influxFieldMetric(value string) (domain.Metrics, bool, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		return domain.Metrics{}, false, nil
+	case strings.HasSuffix(value, "i"):
+		delta, err := strconv.ParseInt(strings.TrimSuffix(value, "i"), 10, 64)
+		if err != nil {
+			return domain.Metrics{}, false, fmt.Errorf("wrong integer value %v", value)
+		}
+		return domain.Metrics{MType: domain.CounterType, Delta: domain.DeltaPtr(delta)}, true, nil
+	case strings.HasSuffix(value, "u"):
+		delta, err := strconv.ParseUint(strings.TrimSuffix(value, "u"), 10, 64)
+		if err != nil || delta > math.MaxInt64 {
+			return domain.Metrics{}, false, fmt.Errorf("wrong unsigned value %v", value)
+		}
+		return domain.Metrics{MType: domain.CounterType, Delta: domain.DeltaPtr(int64(delta))}, true, nil
+	}
+
+	switch value {
+	case "t", "T", "true", "True", "TRUE", "f", "F", "false", "False", "FALSE":
+		return domain.Metrics{}, false, nil
+	}
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return domain.Metrics{}, false, fmt.Errorf("wrong float value %v", value)
+	}
+	return domain.Metrics{MType: domain.GaugeType, Value: domain.ValuePtr(v)}, true, nil
+}
+
+// splitUnescaped делит строку по разделителю sep, не экранированному '\';
+// при quoted разделитель внутри строковых значений в кавычках также пропускается.
+func splitUnescaped(s string, sep byte, quoted bool) []string {
+	var res []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case quoted && s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			res = append(res, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(res, s[start:])
+}
+
+var influxUnescaper = strings.NewReplacer(`\,`, ",", `\ `, " ", `\=`, "=", `\"`, `"`, `\\`, `\`)
+
+func influxUnescape(s string) string {
+	return influxUnescaper.Replace(s)
+}
@@ -0,0 +1,160 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/handler"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfluxWrite(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ms []domain.Metrics) error {
+			require.Equal(t, []domain.Metrics{
+				{
+					ID:     "cpu_usage_idle",
+					MType:  domain.GaugeType,
+					Value:  domain.ValuePtr(92.5),
+					Labels: domain.Labels{"host": "h1", "cpu_total": "cpu all"},
+				},
+				{
+					ID:     "cpu_ticks",
+					MType:  domain.CounterType,
+					Delta:  domain.DeltaPtr(10),
+					Labels: domain.Labels{"host": "h1", "cpu_total": "cpu all"},
+				},
+				{
+					ID:    "disk_io_reads",
+					MType: domain.CounterType,
+					Delta: domain.DeltaPtr(3),
+				},
+				{
+					ID:    "disk_io_ratio",
+					MType: domain.GaugeType,
+					Value: domain.ValuePtr(1),
+				},
+			}, ms)
+			return nil
+		}).Times(1)
+
+	r := chi.NewRouter()
+	handler.AddInfluxOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	body := "# comment\n" +
+		`cpu,host=h1,cpu-total=cpu\ all usage_idle=92.5,ticks=10i,state="idle, ok",up=true 1700000000000000000` + "\n" +
+		"\n" +
+		"disk.io reads=3u,ratio=1\n"
+
+	resp, err := resty.New().R().
+		SetHeader("Content-Type", "text/plain; charset=utf-8").
+		SetBody(body).
+		Post(srv.URL + "/write")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode())
+}
+
+func TestInfluxWrite_WrongData(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).Return(domain.ErrDataFormat).Times(1)
+
+	r := chi.NewRouter()
+	handler.AddInfluxOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	testCases := []struct {
+		name string
+		body string
+	}{
+		{"no_fields", "cpu"},
+		{"empty_measurement", ",host=h1 value=1"},
+		{"wrong_tag", "cpu,host value=1"},
+		{"wrong_field", "cpu value"},
+		{"wrong_integer", "cpu value=1.5i"},
+		{"wrong_unsigned", "cpu value=18446744073709551615u"},
+		{"wrong_float", "cpu value=abc"},
+		{"wrong_timestamp", "cpu value=1 abc"},
+		{"extra_part", "cpu value=1 1 2"},
+		{"app_error", "1cpu value=1"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := resty.New().R().
+				SetBody(test.body).
+				Post(srv.URL + "/write")
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+		})
+	}
+}
+
+func TestInfluxWrite_OnlySkippedFields(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	r := chi.NewRouter()
+	handler.AddInfluxOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := resty.New().R().
+		SetBody(`events message="started",ok=t`).
+		Post(srv.URL + "/write")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode())
+}
+
+func TestInfluxWrite_TooLarge(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	r := chi.NewRouter()
+	handler.AddInfluxOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := resty.New().R().
+		SetBody("cpu value=1\n" + strings.Repeat("# comment\n", 4<<20)).
+		Post(srv.URL + "/write")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode())
+}
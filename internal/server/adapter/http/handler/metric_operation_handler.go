@@ -23,6 +23,7 @@ func AddMetricOperations(r *chi.Mux, metricApp MetricApp, changeDataMw ...middle
 	}
 
 	r.Get("/", adapter.AllMetrics)
+	r.Get("/metrics", adapter.Exposition)
 
 	r.Route("/updates", func(r chi.Router) {
 		//r.Use(changeDataMw...)
@@ -42,6 +43,11 @@ func AddMetricOperations(r *chi.Mux, metricApp MetricApp, changeDataMw ...middle
 		r.Post("/", adapter.ValueMetric)
 		r.Get("/gauge/{name}", adapter.GetGauge)
 		r.Get("/counter/{name}", adapter.GetCounter)
+		r.Get("/histogram/{name}", adapter.GetHistogram)
+	})
+
+	r.Route("/history", func(r chi.Router) {
+		r.Post("/", adapter.History)
 	})
 
 }
@@ -61,6 +67,9 @@ type metricOperationAdapter struct {
 // Content-Type: application/json.
 //
 // В запросе - массив структур [domain.Metrics].
+//
+// Необязательный заголовок X-Idempotency-Key - ключ пакета; пакет с уже примененным ключом
+// не накапливает значения повторно.
 func (h *metricOperationAdapter) PostMetrics(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 
@@ -76,8 +85,13 @@ func (h *metricOperationAdapter) PostMetrics(w http.ResponseWriter, req *http.Re
 		return
 	}
 
-	if err := h.metricApp.UpdateAll(req.Context(), metrics); err != nil {
-		handleAppError(req.Context(), w, err)
+	ctx := req.Context()
+	if key := req.Header.Get(IdempotencyKeyHeader); key != "" {
+		ctx = domain.EnrichWithIdempotencyKey(ctx, key)
+	}
+
+	if err := h.metricApp.UpdateAll(ctx, metrics); err != nil {
+		handleAppError(ctx, w, err)
 		return
 	}
 
@@ -126,7 +140,7 @@ func (h *metricOperationAdapter) PostMetric(w http.ResponseWriter, req *http.Req
 //
 // Content-Type: application/json.
 //
-// В запросе: структура [domain.Metrics] с заполненными полями [Metrics.MType] и [Metrics.ID].
+// В запросе: структура [domain.Metrics] с заполненными полями [Metrics.MType], [Metrics.ID] и, при необходимости, [Metrics.Labels].
 // В ответе:
 //
 //	http.StatusOK и заполненныя структура [domain.Metrics] - если данные найден
@@ -146,7 +160,7 @@ func (h *metricOperationAdapter) ValueMetric(w http.ResponseWriter, req *http.Re
 		return
 	}
 
-	value, err := h.metricApp.Get(req.Context(), metrics.MType, metrics.ID)
+	value, err := h.metricApp.Get(req.Context(), metrics.MType, metrics.ID, metrics.Labels)
 	if err != nil {
 		handleAppError(req.Context(), w, err)
 		return
@@ -164,6 +178,42 @@ func (h *metricOperationAdapter) ValueMetric(w http.ResponseWriter, req *http.Re
 	}
 }
 
+// History используется для получения истории значений метрики.
+//
+// POST /history/
+//
+// Content-Type: application/json.
+//
+// В запросе: структура [domain.HistoryQuery].
+// В ответе: структура [domain.MetricHistory]; значения упорядочены по времени.
+func (h *metricOperationAdapter) History(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	if err := h.checkContentType(ApplicationJSON, req); err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	var query *domain.HistoryQuery
+	if err := json.NewDecoder(req.Body).Decode(&query); err != nil {
+		fullErr := fmt.Errorf("%w: json decode error - %v", domain.ErrDataFormat, err.Error())
+		handleAppError(req.Context(), w, fullErr)
+		return
+	}
+
+	history, err := h.metricApp.GetHistory(req.Context(), query)
+	if err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ApplicationJSON)
+	if err := json.NewEncoder(w).Encode(history); err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+}
+
 // PostGauge используется для добавления метрики типа Gauge
 // POST /gauge/{name}/{value}
 // ContentType: "text/plain"
@@ -248,7 +298,7 @@ func (h *metricOperationAdapter) PostCounter(w http.ResponseWriter, req *http.Re
 }
 
 // GetCounter используется получения данных о метрике типа Counter
-// GET /value/counter/{name}/{value}
+// GET /value/counter/{name}?label=value
 // ContentType: "text/plain"
 func (h *metricOperationAdapter) GetCounter(w http.ResponseWriter, req *http.Request) {
 	if _, err := io.ReadAll(req.Body); err != nil {
@@ -267,7 +317,7 @@ func (h *metricOperationAdapter) GetCounter(w http.ResponseWriter, req *http.Req
 		return
 	}
 
-	value, err := h.metricApp.Get(req.Context(), domain.CounterType, name)
+	value, err := h.metricApp.Get(req.Context(), domain.CounterType, name, h.extractLabels(req))
 	if err != nil {
 		handleAppError(req.Context(), w, err)
 		return
@@ -286,7 +336,7 @@ func (h *metricOperationAdapter) GetCounter(w http.ResponseWriter, req *http.Req
 }
 
 // GetGauge используется получения данных о метрике типа Gauge
-// GET /value/gauge/{name}/{value}
+// GET /value/gauge/{name}?label=value
 // ContentType: "text/plain"
 func (h *metricOperationAdapter) GetGauge(w http.ResponseWriter, req *http.Request) {
 	if _, err := io.ReadAll(req.Body); err != nil {
@@ -305,7 +355,7 @@ func (h *metricOperationAdapter) GetGauge(w http.ResponseWriter, req *http.Reque
 		return
 	}
 
-	value, err := h.metricApp.Get(req.Context(), domain.GaugeType, name)
+	value, err := h.metricApp.Get(req.Context(), domain.GaugeType, name, h.extractLabels(req))
 	if err != nil {
 		handleAppError(req.Context(), w, err)
 		return
@@ -323,6 +373,43 @@ func (h *metricOperationAdapter) GetGauge(w http.ResponseWriter, req *http.Reque
 	}
 }
 
+// GetHistogram используется получения данных о метрике типа Histogram
+// GET /value/histogram/{name}?label=value
+// ContentType: "application/json"; в ответе - структура [domain.Histogram]
+func (h *metricOperationAdapter) GetHistogram(w http.ResponseWriter, req *http.Request) {
+	if _, err := io.ReadAll(req.Body); err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+	defer req.Body.Close()
+
+	var name string
+	var err error
+
+	if name, err = h.extractName(req); err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	value, err := h.metricApp.Get(req.Context(), domain.HistogramType, name, h.extractLabels(req))
+	if err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	if value == nil {
+		err := fmt.Errorf("%w: unknown metric '%v'", domain.ErrNotFound, name)
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", ApplicationJSON)
+	if err := json.NewEncoder(w).Encode(value.Histogram); err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+}
+
 // Используется для получения всех значений метрики
 // GET /
 func (h *metricOperationAdapter) AllMetrics(w http.ResponseWriter, req *http.Request) {
@@ -354,12 +441,14 @@ var allMetricsViewTmplate, _ = template.New("allMetrics").Parse(`<!DOCTYPE html>
     <tr>
         <th>Type</th>
         <th>Name</th>
+        <th>Labels</th>
         <th>Value</th>
     </tr>
     {{ range .}}<tr>
         <td>{{ .MType }}</td>
         <td>{{ .ID }}</td>
-        {{if .Delta}}<td>{{ .Delta }}</td>{{else}}<td>{{ .Value }}</td>{{end}}
+        <td>{{ .Labels }}</td>
+        {{if .Delta}}<td>{{ .Delta }}</td>{{else if .Histogram}}<td>count={{ .Histogram.Count }} sum={{ .Histogram.Sum }}</td>{{else}}<td>{{ .Value }}</td>{{end}}
     </tr>{{ end}}
 </table>
 </body>
@@ -393,6 +482,19 @@ func (h *metricOperationAdapter) extractName(req *http.Request) (string, error)
 	return name, nil
 }
 
+// extractLabels возвращает метки метрики, переданные в параметрах запроса
+func (h *metricOperationAdapter) extractLabels(req *http.Request) domain.Labels {
+	query := req.URL.Query()
+	if len(query) == 0 {
+		return nil
+	}
+	labels := make(domain.Labels, len(query))
+	for k := range query {
+		labels[k] = query.Get(k)
+	}
+	return labels
+}
+
 func (h *metricOperationAdapter) extractFloat64(req *http.Request) (float64, error) {
 
 	valueStr := chi.URLParam(req, "value")
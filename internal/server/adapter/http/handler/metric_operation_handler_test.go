@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/handler"
 	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
@@ -33,7 +34,7 @@ func TestMetricOperation_Counter(t *testing.T) {
 
 	counterName := "TestCounter"
 
-	m.EXPECT().Get(gomock.Any(), gomock.Eq(domain.CounterType), counterName).Return(&domain.Metrics{
+	m.EXPECT().Get(gomock.Any(), gomock.Eq(domain.CounterType), counterName, gomock.Any()).Return(&domain.Metrics{
 		ID:    counterName,
 		MType: domain.CounterType,
 		Delta: domain.DeltaPtr(testValue),
@@ -83,7 +84,7 @@ func TestMetricOperation_Gague(t *testing.T) {
 
 	gaugeName := "TestGauge"
 
-	m.EXPECT().Get(gomock.Any(), gomock.Eq(domain.GaugeType), gaugeName).Return(&domain.Metrics{
+	m.EXPECT().Get(gomock.Any(), gomock.Eq(domain.GaugeType), gaugeName, gomock.Any()).Return(&domain.Metrics{
 		ID:    gaugeName,
 		MType: domain.GaugeType,
 		Value: domain.ValuePtr(testValue),
@@ -179,8 +180,8 @@ func TestMetricOperation_Counter_Update(t *testing.T) {
 			return m, nil
 		}).Times(1)
 
-	m.EXPECT().Get(gomock.Any(), gomock.Eq(domain.CounterType), gomock.Eq(counterName)).DoAndReturn(
-		func(ctx context.Context, metricType domain.MetricType, name string) (*domain.Metrics, error) {
+	m.EXPECT().Get(gomock.Any(), gomock.Eq(domain.CounterType), gomock.Eq(counterName), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, metricType domain.MetricType, name string, labels domain.Labels) (*domain.Metrics, error) {
 			return &domain.Metrics{
 				ID:    counterName,
 				MType: domain.CounterType,
@@ -254,8 +255,8 @@ func TestMetricOperation_Gague_Update(t *testing.T) {
 			return m, nil
 		}).Times(1)
 
-	m.EXPECT().Get(gomock.Any(), gomock.Eq(domain.GaugeType), gomock.Eq(gaugeName)).DoAndReturn(
-		func(ctx context.Context, metricType domain.MetricType, name string) (*domain.Metrics, error) {
+	m.EXPECT().Get(gomock.Any(), gomock.Eq(domain.GaugeType), gomock.Eq(gaugeName), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, metricType domain.MetricType, name string, labels domain.Labels) (*domain.Metrics, error) {
 			require.Equal(t, gaugeName, name)
 			return &domain.Metrics{
 				ID:    gaugeName,
@@ -356,6 +357,202 @@ func TestMetricOperation_PostMetrics(t *testing.T) {
 	require.Nil(t, err)
 }
 
+func TestMetricOperation_PostMetricsIdempotencyKey(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	var keys []string
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ms []domain.Metrics) error {
+			keys = append(keys, domain.GetIdempotencyKey(ctx))
+			return nil
+		}).Times(2)
+
+	r := chi.NewRouter()
+
+	handler.AddMetricOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	metricsReq := []domain.Metrics{
+		{
+			ID:    "PollCount",
+			MType: domain.CounterType,
+			Delta: domain.DeltaPtr(1),
+		},
+	}
+
+	resp, err := resty.New().R().
+		SetHeader("Content-Type", handler.ApplicationJSON).
+		SetHeader(handler.IdempotencyKeyHeader, "batch-1").
+		SetBody(metricsReq).
+		Post(srv.URL + "/updates/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	resp, err = resty.New().R().
+		SetHeader("Content-Type", handler.ApplicationJSON).
+		SetBody(metricsReq).
+		Post(srv.URL + "/updates/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	require.Equal(t, []string{"batch-1", ""}, keys)
+}
+
+func TestMetricOperation_Histogram(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	histogramName := "GCPause"
+	histogram := &domain.Histogram{
+		Bounds: []float64{0.001, 0.01},
+		Counts: []int64{3, 1, 0},
+		Sum:    0.012,
+		Count:  4,
+	}
+
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ms []domain.Metrics) error {
+			require.Equal(t, 1, len(ms))
+			require.Equal(t, domain.HistogramType, ms[0].MType)
+			require.Equal(t, histogram, ms[0].Histogram)
+			return nil
+		}).Times(1)
+
+	m.EXPECT().Get(gomock.Any(), gomock.Eq(domain.HistogramType), gomock.Eq(histogramName), gomock.Any()).Return(
+		&domain.Metrics{
+			ID:        histogramName,
+			MType:     domain.HistogramType,
+			Histogram: histogram,
+		}, nil).Times(2)
+
+	r := chi.NewRouter()
+
+	handler.AddMetricOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	req := resty.New().R()
+	req.Method = http.MethodPost
+	req.URL = srv.URL + "/updates/"
+	req.Header.Add("Content-Type", handler.ApplicationJSON)
+	req.SetBody([]domain.Metrics{
+		{
+			ID:        histogramName,
+			MType:     domain.HistogramType,
+			Histogram: histogram,
+		},
+	})
+	resp, err := req.Send()
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	req = resty.New().R()
+	req.Method = http.MethodPost
+	req.URL = srv.URL + "/value/"
+	req.Header.Add("Content-Type", handler.ApplicationJSON)
+	req.SetBody(domain.Metrics{
+		ID:    histogramName,
+		MType: domain.HistogramType,
+	})
+	resp, err = req.Send()
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	var respMetrics domain.Metrics
+	err = json.Unmarshal(resp.Body(), &respMetrics)
+	require.Nil(t, err)
+	require.Equal(t, histogram, respMetrics.Histogram)
+
+	req = resty.New().R()
+	req.Method = http.MethodGet
+	req.URL = srv.URL + "/value/histogram/" + histogramName
+	resp, err = req.Send()
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	var respHistogram domain.Histogram
+	err = json.Unmarshal(resp.Body(), &respHistogram)
+	require.Nil(t, err)
+	require.Equal(t, *histogram, respHistogram)
+}
+
+func TestMetricOperation_Labels(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	gaugeName := "Alloc"
+	labels := domain.Labels{"host": "h1", "zone": "eu"}
+	testValue := float64(10)
+
+	m.EXPECT().Get(gomock.Any(), gomock.Eq(domain.GaugeType), gomock.Eq(gaugeName), gomock.Eq(labels)).Return(&domain.Metrics{
+		ID:     gaugeName,
+		MType:  domain.GaugeType,
+		Value:  domain.ValuePtr(testValue),
+		Labels: labels,
+	}, nil).Times(2)
+
+	m.EXPECT().Get(gomock.Any(), gomock.Eq(domain.GaugeType), gomock.Eq(gaugeName), gomock.Nil()).Return(nil, nil).Times(1)
+
+	r := chi.NewRouter()
+
+	handler.AddMetricOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	req := resty.New().R()
+	req.Method = http.MethodGet
+	req.URL = srv.URL + "/value/gauge/Alloc?host=h1&zone=eu"
+	resp, err := req.Send()
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Equal(t, "10", string(resp.Body()))
+
+	req = resty.New().R()
+	req.Method = http.MethodGet
+	req.URL = srv.URL + "/value/gauge/Alloc"
+	resp, err = req.Send()
+	require.Nil(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode())
+
+	req = resty.New().R()
+	req.Method = http.MethodPost
+	req.URL = srv.URL + "/value/"
+	req.Header.Add("Content-Type", handler.ApplicationJSON)
+	req.SetBody(domain.Metrics{
+		ID:     gaugeName,
+		MType:  domain.GaugeType,
+		Labels: labels,
+	})
+	resp, err = req.Send()
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	var respMetrics domain.Metrics
+	err = json.Unmarshal(resp.Body(), &respMetrics)
+	require.Nil(t, err)
+	require.Equal(t, labels, respMetrics.Labels)
+}
+
 func logger() *zap.SugaredLogger {
 	logger, err := zap.NewDevelopment()
 	if err != nil {
@@ -367,3 +564,86 @@ func logger() *zap.SugaredLogger {
 	log := logger.Sugar()
 	return log
 }
+
+func TestMetricOperation_History(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	to := time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC)
+	from := to.Add(-time.Minute)
+
+	history := &domain.MetricHistory{
+		ID:     "Alloc",
+		MType:  domain.GaugeType,
+		Labels: domain.Labels{"host": "h1"},
+		Samples: []domain.Sample{
+			{Timestamp: from, Value: 1},
+			{Timestamp: to, Value: 2},
+		},
+	}
+
+	m.EXPECT().GetHistory(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, q *domain.HistoryQuery) (*domain.MetricHistory, error) {
+			require.Equal(t, "Alloc", q.ID)
+			require.Equal(t, domain.GaugeType, q.MType)
+			require.Equal(t, domain.Labels{"host": "h1"}, q.Labels)
+			require.True(t, from.Equal(q.From))
+			require.True(t, to.Equal(q.To))
+			return history, nil
+		}).Times(1)
+
+	m.EXPECT().GetHistory(gomock.Any(), gomock.Any()).Return(nil, domain.ErrNotFound).Times(1)
+
+	r := chi.NewRouter()
+
+	handler.AddMetricOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	req := resty.New().R()
+	req.Method = http.MethodPost
+	req.URL = srv.URL + "/history/"
+	req.Header.Add("Content-Type", handler.ApplicationJSON)
+	req.SetBody(domain.HistoryQuery{
+		ID:     "Alloc",
+		MType:  domain.GaugeType,
+		Labels: domain.Labels{"host": "h1"},
+		From:   from,
+		To:     to,
+	})
+	resp, err := req.Send()
+	require.Nil(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	var respHistory domain.MetricHistory
+	err = json.Unmarshal(resp.Body(), &respHistory)
+	require.Nil(t, err)
+	require.Equal(t, *history, respHistory)
+
+	req = resty.New().R()
+	req.Method = http.MethodPost
+	req.URL = srv.URL + "/history/"
+	req.Header.Add("Content-Type", handler.ApplicationJSON)
+	req.SetBody(domain.HistoryQuery{
+		ID:    "Alloc",
+		MType: domain.GaugeType,
+	})
+	resp, err = req.Send()
+	require.Nil(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode())
+
+	req = resty.New().R()
+	req.Method = http.MethodPost
+	req.URL = srv.URL + "/history/"
+	req.Header.Add("Content-Type", handler.ApplicationJSON)
+	req.SetBody("{")
+	resp, err = req.Send()
+	require.Nil(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+}
@@ -0,0 +1,421 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/go-chi/chi/v5"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	otlpStateTTL           = time.Hour       // состояние ряда без новых значений удаляется
+	otlpStateEvictInterval = 5 * time.Minute // период проверки состояний рядов
+)
+
+func AddOTLPOperations(r *chi.Mux, metricApp MetricApp) {
+	now := time.Now()
+	adapter := &otlpAdapter{
+		metricApp: metricApp,
+		states:    make(map[string]*cumulativeState),
+		since:     uint64(now.UnixNano()),
+		evictedAt: now,
+	}
+
+	r.Post("/v1/metrics", adapter.Metrics)
+}
+
+// cumulativeState часть накопленного значения ряда, уже сохраненная как приращение.
+type cumulativeState struct {
+	start     uint64            // время начала накопления; при смене - ряд начинается заново
+	value     float64           // для sum
+	histogram *domain.Histogram // для histogram
+	updated   time.Time         // время получения значения
+}
+
+// otlpAdapter принимает метрики OpenTelemetry.
+//
+// counter и histogram этого сервера накапливают приращения, поэтому накопленные (cumulative) значения
+// OTLP преобразуются в приращения относительно последнего сохраненного значения ряда.
+// Первое значение ряда сохраняется как приращение от нуля, только если ряд начался (StartTimeUnixNano)
+// после запуска сервера; иначе значение запоминается как начальное и не сохраняется - накопленное
+// до перезапуска сервера уже учтено. Состояние ряда без новых значений дольше otlpStateTTL удаляется;
+// после этого ряды, начавшиеся до удаления, тоже начинаются с начального значения.
+type otlpAdapter struct {
+	metricApp MetricApp
+	mu        sync.Mutex
+	states    map[string]*cumulativeState // ключ - тип и ключ метрики
+	since     uint64                      // ряды, начавшиеся не раньше, накоплены полностью на этом сервере
+	evictedAt time.Time                   // время последней проверки состояний
+}
+
+// Metrics принимает данные по протоколу OTLP/HTTP.
+//
+// POST /v1/metrics
+//
+// Content-Type: application/x-protobuf или application/json.
+//
+// В запросе - ExportMetricsServiceRequest. Метки метрики - атрибуты ресурса и точки данных;
+// символы, недопустимые в имени метрики или метки (например, '.'), заменяются на '_'.
+// Монотонная сумма сохраняется как counter, немонотонная и gauge - как gauge, histogram - как histogram.
+// Exponential histogram и summary пропускаются.
+func (h *otlpAdapter) Metrics(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil || (mediaType != ApplicationProtobuf && mediaType != ApplicationJSON) {
+		err := fmt.Errorf("%w: only '%v' and '%v' supported", domain.ErrMediaType, ApplicationProtobuf, ApplicationJSON)
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	body, err := readBody(w, req, maxRequestBodySize)
+	if err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	// ExportMetricsServiceRequest и MetricsData совпадают по формату: repeated ResourceMetrics resource_metrics = 1
+	var data metricspb.MetricsData
+	if mediaType == ApplicationJSON {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, &data)
+	} else {
+		err = proto.Unmarshal(body, &data)
+	}
+	if err != nil {
+		fullErr := fmt.Errorf("%w: otlp decode error - %v", domain.ErrDataFormat, err.Error())
+		handleAppError(req.Context(), w, fullErr)
+		return
+	}
+
+	// приращения вычисляются под блокировкой, сохранение в хранилище - без нее
+	metrics, changes, err := h.toDeltas(&data)
+	if err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	if len(metrics) > 0 {
+		if err := h.metricApp.UpdateAll(req.Context(), metrics); err != nil {
+			// состояние рядов возвращается - повтор запроса клиентом не теряет приращения
+			h.rollback(changes)
+			handleAppError(req.Context(), w, err)
+			return
+		}
+	}
+
+	// ответ - пустой ExportMetricsServiceResponse
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(http.StatusOK)
+	if mediaType == ApplicationJSON {
+		w.Write([]byte("{}"))
+	}
+}
+
+// stateChange изменение состояния ряда запросом
+type stateChange struct {
+	prev *cumulativeState // nil для нового ряда
+	cur  *cumulativeState
+}
+
+// toDeltas преобразует данные в метрики и обновляет состояние рядов; возвращает изменения
+// состояния для отката при ошибке сохранения.
+func (h *otlpAdapter) toDeltas(data *metricspb.MetricsData) ([]domain.Metrics, map[string]stateChange, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	h.evict(now)
+
+	pending := make(map[string]*cumulativeState)
+	metrics, err := h.fromMetricsData(data, now, pending)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes := make(map[string]stateChange, len(pending))
+	for key, state := range pending {
+		changes[key] = stateChange{prev: h.states[key], cur: state}
+		h.states[key] = state
+	}
+	return metrics, changes, nil
+}
+
+// rollback возвращает предыдущее состояние рядов, если их не изменил другой запрос.
+func (h *otlpAdapter) rollback(changes map[string]stateChange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key, change := range changes {
+		if h.states[key] != change.cur {
+			continue
+		}
+		if change.prev == nil {
+			delete(h.states, key)
+		} else {
+			h.states[key] = change.prev
+		}
+	}
+}
+
+// evict удаляет состояние рядов, не получавших значений дольше otlpStateTTL.
+func (h *otlpAdapter) evict(now time.Time) {
+	if now.Sub(h.evictedAt) < otlpStateEvictInterval {
+		return
+	}
+	h.evictedAt = now
+
+	evicted := false
+	for key, state := range h.states {
+		if now.Sub(state.updated) > otlpStateTTL {
+			delete(h.states, key)
+			evicted = true
+		}
+	}
+	if evicted {
+		h.since = uint64(now.UnixNano())
+	}
+}
+
+// isNew проверяет, что ряд начался после запуска сервера и удаления состояний.
+func (h *otlpAdapter) isNew(start uint64) bool {
+	return start != 0 && start >= h.since
+}
+
+func (h *otlpAdapter) fromMetricsData(data *metricspb.MetricsData, now time.Time, pending map[string]*cumulativeState) ([]domain.Metrics, error) {
+	var metrics []domain.Metrics
+
+	for _, rm := range data.GetResourceMetrics() {
+		resourceLabels := attributesToLabels(nil, rm.GetResource().GetAttributes())
+
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, metric := range sm.GetMetrics() {
+				name := domain.SanitizeName(metric.GetName())
+
+				switch {
+				case metric.GetGauge() != nil:
+					for _, dp := range metric.GetGauge().GetDataPoints() {
+						if noRecordedValue(dp.GetFlags()) {
+							continue
+						}
+						value, err := numberValue(dp)
+						if err != nil {
+							return nil, fmt.Errorf("%w: metric %v: %v", domain.ErrDataFormat, metric.GetName(), err.Error())
+						}
+						metrics = append(metrics, domain.Metrics{
+							ID:     name,
+							MType:  domain.GaugeType,
+							Value:  domain.ValuePtr(value),
+							Labels: attributesToLabels(resourceLabels, dp.GetAttributes()),
+						})
+					}
+				case metric.GetSum() != nil:
+					sum := metric.GetSum()
+					for _, dp := range sum.GetDataPoints() {
+						if noRecordedValue(dp.GetFlags()) {
+							continue
+						}
+						value, err := numberValue(dp)
+						if err != nil {
+							return nil, fmt.Errorf("%w: metric %v: %v", domain.ErrDataFormat, metric.GetName(), err.Error())
+						}
+						m := domain.Metrics{
+							ID:     name,
+							Labels: attributesToLabels(resourceLabels, dp.GetAttributes()),
+						}
+						if !sum.GetIsMonotonic() {
+							m.MType = domain.GaugeType
+							m.Value = domain.ValuePtr(value)
+						} else {
+							m.MType = domain.CounterType
+							delta := math.Round(value)
+							if sum.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+								var ok bool
+								if delta, ok = h.sumDelta(m.Key(), dp.GetStartTimeUnixNano(), value, now, pending); !ok {
+									continue
+								}
+							}
+							m.Delta = domain.DeltaPtr(int64(delta))
+						}
+						metrics = append(metrics, m)
+					}
+				case metric.GetHistogram() != nil:
+					hist := metric.GetHistogram()
+					for _, dp := range hist.GetDataPoints() {
+						if noRecordedValue(dp.GetFlags()) {
+							continue
+						}
+						histogram, err := toHistogram(dp)
+						if err != nil {
+							return nil, fmt.Errorf("%w: metric %v: %v", domain.ErrDataFormat, metric.GetName(), err.Error())
+						}
+						m := domain.Metrics{
+							ID:     name,
+							MType:  domain.HistogramType,
+							Labels: attributesToLabels(resourceLabels, dp.GetAttributes()),
+						}
+						if hist.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+							if histogram = h.histogramDelta(m.Key(), dp.GetStartTimeUnixNano(), histogram, now, pending); histogram == nil {
+								continue
+							}
+						}
+						m.Histogram = histogram
+						metrics = append(metrics, m)
+					}
+				}
+			}
+		}
+	}
+
+	return metrics, nil
+}
+
+func (h *otlpAdapter) state(key string, pending map[string]*cumulativeState) *cumulativeState {
+	if state, ok := pending[key]; ok {
+		return state
+	}
+	return h.states[key]
+}
+
+// sumDelta возвращает целое приращение накопленной суммы; дробный остаток учитывается в следующих значениях.
+// Для начального значения ряда возвращается false.
+func (h *otlpAdapter) sumDelta(metricKey string, start uint64, value float64, now time.Time, pending map[string]*cumulativeState) (float64, bool) {
+	key := string(domain.CounterType) + ":" + metricKey
+
+	prev := h.state(key, pending)
+	state := &cumulativeState{start: start, updated: now}
+	pending[key] = state
+
+	if prev == nil && !h.isNew(start) {
+		state.value = value
+		return 0, false
+	}
+	// при перезапуске источника сумма начинается заново
+	if prev != nil && prev.start == start && value >= prev.value {
+		state.value = prev.value
+	}
+
+	delta := math.Round(value - state.value)
+	state.value += delta
+	return delta, true
+}
+
+// histogramDelta возвращает приращение накопленной гистограммы; для начального значения ряда возвращается nil.
+func (h *otlpAdapter) histogramDelta(metricKey string, start uint64, cur *domain.Histogram, now time.Time, pending map[string]*cumulativeState) *domain.Histogram {
+	key := string(domain.HistogramType) + ":" + metricKey
+
+	prev := h.state(key, pending)
+	pending[key] = &cumulativeState{start: start, histogram: cur, updated: now}
+
+	if prev == nil && !h.isNew(start) {
+		return nil
+	}
+	if prev == nil || prev.start != start || !prev.histogram.SameBounds(cur) || cur.Count < prev.histogram.Count {
+		return cur
+	}
+
+	delta := cur.Copy()
+	delta.Count -= prev.histogram.Count
+	delta.Sum -= prev.histogram.Sum
+	for i := range delta.Counts {
+		delta.Counts[i] -= prev.histogram.Counts[i]
+		if delta.Counts[i] < 0 {
+			// значения корзин уменьшились - ряд начался заново
+			return cur
+		}
+	}
+	return delta
+}
+
+// toHistogram преобразует точку гистограммы; Check отклоняет, в том числе, бесконечные границы и сумму.
+func toHistogram(dp *metricspb.HistogramDataPoint) (*domain.Histogram, error) {
+	histogram := domain.NewHistogram(dp.GetExplicitBounds())
+	histogram.Sum = dp.GetSum()
+
+	if dp.GetCount() > math.MaxInt64 {
+		return nil, fmt.Errorf("histogram count overflow")
+	}
+	histogram.Count = int64(dp.GetCount())
+
+	switch len(dp.GetBucketCounts()) {
+	case 0:
+		// корзины не переданы - все наблюдения в последней корзине
+		histogram.Counts[len(histogram.Counts)-1] = histogram.Count
+	case len(histogram.Counts):
+		for i, c := range dp.GetBucketCounts() {
+			if c > math.MaxInt64 {
+				return nil, fmt.Errorf("histogram bucket count overflow")
+			}
+			histogram.Counts[i] = int64(c)
+		}
+	default:
+		return nil, fmt.Errorf("histogram has %d bounds and %d bucket counts", len(dp.GetExplicitBounds()), len(dp.GetBucketCounts()))
+	}
+
+	if err := histogram.Check(); err != nil {
+		return nil, err
+	}
+	return histogram, nil
+}
+
+func noRecordedValue(flags uint32) bool {
+	return flags&uint32(metricspb.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK) != 0
+}
+
+// numberValue возвращает значение точки; NaN и ±Inf не допускаются.
+func numberValue(dp *metricspb.NumberDataPoint) (float64, error) {
+	if v, ok := dp.GetValue().(*metricspb.NumberDataPoint_AsInt); ok {
+		return float64(v.AsInt), nil
+	}
+	v := dp.GetAsDouble()
+	if !domain.IsFinite(v) {
+		return 0, fmt.Errorf("value %v is not finite", v)
+	}
+	return v, nil
+}
+
+// attributesToLabels добавляет атрибуты к копии base; атрибуты-массивы и вложенные структуры пропускаются.
+func attributesToLabels(base domain.Labels, attrs []*commonpb.KeyValue) domain.Labels {
+	if len(base) == 0 && len(attrs) == 0 {
+		return nil
+	}
+
+	labels := base.Copy()
+	if labels == nil {
+		labels = make(domain.Labels)
+	}
+
+	for _, kv := range attrs {
+		var value string
+		switch v := kv.GetValue().GetValue().(type) {
+		case *commonpb.AnyValue_StringValue:
+			value = v.StringValue
+		case *commonpb.AnyValue_BoolValue:
+			value = strconv.FormatBool(v.BoolValue)
+		case *commonpb.AnyValue_IntValue:
+			value = strconv.FormatInt(v.IntValue, 10)
+		case *commonpb.AnyValue_DoubleValue:
+			value = strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+		default:
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		labels[domain.SanitizeName(kv.GetKey())] = value
+	}
+
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
@@ -0,0 +1,307 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/handler"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func strAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
+
+func postOTLP(t *testing.T, url string, contentType string, body []byte) *resty.Response {
+	t.Helper()
+	resp, err := resty.New().R().
+		SetHeader("Content-Type", contentType).
+		SetBody(body).
+		Post(url + "/v1/metrics")
+	require.NoError(t, err)
+	return resp
+}
+
+func TestOTLPMetrics_Protobuf(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ms []domain.Metrics) error {
+			require.Equal(t, []domain.Metrics{
+				{
+					ID:     "process_memory_usage",
+					MType:  domain.GaugeType,
+					Value:  domain.ValuePtr(1024),
+					Labels: domain.Labels{"service_name": "api", "pool": "heap"},
+				},
+				{
+					ID:     "http_server_requests",
+					MType:  domain.CounterType,
+					Delta:  domain.DeltaPtr(7),
+					Labels: domain.Labels{"service_name": "api", "code": "200", "ok": "true"},
+				},
+				{
+					ID:     "queue_size",
+					MType:  domain.GaugeType,
+					Value:  domain.ValuePtr(-2.5),
+					Labels: domain.Labels{"service_name": "api"},
+				},
+				{
+					ID:    "http_server_duration",
+					MType: domain.HistogramType,
+					Histogram: &domain.Histogram{
+						Bounds: []float64{0.1, 1},
+						Counts: []int64{2, 1, 0},
+						Sum:    0.9,
+						Count:  3,
+					},
+					Labels: domain.Labels{"service_name": "api"},
+				},
+			}, ms)
+			return nil
+		}).Times(1)
+
+	r := chi.NewRouter()
+	handler.AddOTLPOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	sum := 0.9
+	data := &metricspb.MetricsData{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			{
+				Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{strAttr("service.name", "api")}},
+				ScopeMetrics: []*metricspb.ScopeMetrics{
+					{
+						Metrics: []*metricspb.Metric{
+							{
+								Name: "process.memory.usage",
+								Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+									DataPoints: []*metricspb.NumberDataPoint{
+										{
+											Attributes: []*commonpb.KeyValue{strAttr("pool", "heap")},
+											Value:      &metricspb.NumberDataPoint_AsInt{AsInt: 1024},
+										},
+										{
+											Flags: uint32(metricspb.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK),
+										},
+									},
+								}},
+							},
+							{
+								Name: "http.server.requests",
+								Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+									AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+									IsMonotonic:            true,
+									DataPoints: []*metricspb.NumberDataPoint{
+										{
+											Attributes: []*commonpb.KeyValue{
+												{Key: "code", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 200}}},
+												{Key: "ok", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}},
+												{Key: "list", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{}}},
+											},
+											Value: &metricspb.NumberDataPoint_AsInt{AsInt: 7},
+										},
+									},
+								}},
+							},
+							{
+								Name: "queue.size",
+								Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+									AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+									DataPoints: []*metricspb.NumberDataPoint{
+										{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: -2.5}},
+									},
+								}},
+							},
+							{
+								Name: "http.server.duration",
+								Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+									AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+									DataPoints: []*metricspb.HistogramDataPoint{
+										{
+											Count:          3,
+											Sum:            &sum,
+											BucketCounts:   []uint64{2, 1, 0},
+											ExplicitBounds: []float64{0.1, 1},
+										},
+									},
+								}},
+							},
+							{
+								Name: "ignored.summary",
+								Data: &metricspb.Metric_Summary{Summary: &metricspb.Summary{}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	body, err := proto.Marshal(data)
+	require.NoError(t, err)
+
+	resp := postOTLP(t, srv.URL, handler.ApplicationProtobuf, body)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.Empty(t, resp.Body())
+}
+
+func TestOTLPMetrics_JSONCumulative(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	expectDelta := func(delta int64, histogram *domain.Histogram) *gomock.Call {
+		return m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, ms []domain.Metrics) error {
+				require.Equal(t, []domain.Metrics{
+					{ID: "jobs_done", MType: domain.CounterType, Delta: domain.DeltaPtr(delta)},
+					{ID: "job_duration", MType: domain.HistogramType, Histogram: histogram},
+				}, ms)
+				return nil
+			})
+	}
+
+	gomock.InOrder(
+		// ошибка сохранения не меняет состояние ряда
+		m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).Return(domain.ErrDBConnection),
+		expectDelta(5, &domain.Histogram{Bounds: []float64{1}, Counts: []int64{1, 0}, Sum: 0.5, Count: 1}),
+		// источник перезапущен - новое время начала
+		expectDelta(3, &domain.Histogram{Bounds: []float64{1}, Counts: []int64{0, 1}, Sum: 2, Count: 1}),
+	)
+
+	r := chi.NewRouter()
+	handler.AddOTLPOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	body := func(start string, value int, count int, sum float64, buckets string) []byte {
+		return []byte(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[
+			{"name":"jobs.done","sum":{"aggregationTemporality":2,"isMonotonic":true,
+				"dataPoints":[{"startTimeUnixNano":"` + start + `","asInt":"` + strconv.Itoa(value) + `"}]}},
+			{"name":"job.duration","histogram":{"aggregationTemporality":2,
+				"dataPoints":[{"startTimeUnixNano":"` + start + `","count":"` + strconv.Itoa(count) + `","sum":` + strconv.FormatFloat(sum, 'g', -1, 64) +
+			`,"bucketCounts":` + buckets + `,"explicitBounds":[1]}]}}
+		]}]}]}`)
+	}
+
+	// ряд начался до запуска сервера - первое значение запоминается как начальное и не сохраняется
+	resp := postOTLP(t, srv.URL, handler.ApplicationJSON, body("100", 10, 2, 3, `["1","1"]`))
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	require.JSONEq(t, "{}", string(resp.Body()))
+
+	resp = postOTLP(t, srv.URL, handler.ApplicationJSON, body("100", 15, 3, 3.5, `["2","1"]`))
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode())
+
+	resp = postOTLP(t, srv.URL, handler.ApplicationJSON, body("100", 15, 3, 3.5, `["2","1"]`))
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	resp = postOTLP(t, srv.URL, handler.ApplicationJSON, body("200", 3, 1, 2, `["0","1"]`))
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+}
+
+func TestOTLPMetrics_WrongData(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	r := chi.NewRouter()
+	handler.AddOTLPOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp := postOTLP(t, srv.URL, handler.TextPlain, []byte("{}"))
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode())
+
+	resp = postOTLP(t, srv.URL, handler.ApplicationJSON, []byte("{"))
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	resp = postOTLP(t, srv.URL, handler.ApplicationProtobuf, []byte{0xff, 0xff})
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	// размер тела запроса ограничен
+	resp = postOTLP(t, srv.URL, handler.ApplicationJSON, []byte(`{"resourceMetrics":[]`+strings.Repeat(" ", 40<<20)+`}`))
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	resp = postOTLP(t, srv.URL, handler.ApplicationJSON, []byte(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[
+		{"name":"h","histogram":{"aggregationTemporality":1,
+			"dataPoints":[{"count":"1","bucketCounts":["1"],"explicitBounds":[1]}]}}]}]}]}`))
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	// NaN и ±Inf не допускаются
+	for _, metric := range []string{
+		`{"name":"g","gauge":{"dataPoints":[{"asDouble":"NaN"}]}}`,
+		`{"name":"s","sum":{"aggregationTemporality":1,"isMonotonic":true,"dataPoints":[{"asDouble":"Infinity"}]}}`,
+		`{"name":"s","sum":{"aggregationTemporality":2,"dataPoints":[{"asDouble":"-Infinity"}]}}`,
+		`{"name":"h","histogram":{"aggregationTemporality":1,
+			"dataPoints":[{"count":"1","sum":"NaN","bucketCounts":["1","0"],"explicitBounds":[1]}]}}`,
+		`{"name":"h","histogram":{"aggregationTemporality":1,
+			"dataPoints":[{"count":"1","sum":1,"bucketCounts":["1","0","0"],"explicitBounds":[1,"Infinity"]}]}}`,
+	} {
+		resp = postOTLP(t, srv.URL, handler.ApplicationJSON, []byte(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[`+metric+`]}]}]}`))
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode(), metric)
+	}
+}
+
+func TestOTLPMetrics_NewSeries(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	// ряд начался после запуска сервера - первое значение сохраняется как приращение от нуля
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ms []domain.Metrics) error {
+			require.Equal(t, []domain.Metrics{
+				{ID: "jobs_done", MType: domain.CounterType, Delta: domain.DeltaPtr(10)},
+			}, ms)
+			return nil
+		}).Times(1)
+
+	r := chi.NewRouter()
+	handler.AddOTLPOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	start := strconv.FormatInt(time.Now().UnixNano(), 10)
+	resp := postOTLP(t, srv.URL, handler.ApplicationJSON, []byte(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[
+		{"name":"jobs.done","sum":{"aggregationTemporality":2,"isMonotonic":true,
+			"dataPoints":[{"startTimeUnixNano":"`+start+`","asInt":"10"}]}}
+	]}]}]}`))
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+}
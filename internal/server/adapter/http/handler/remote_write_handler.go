@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/proto/prompb"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	ApplicationProtobuf = "application/x-protobuf"
+
+	metricNameLabel = "__name__"
+
+	// remoteWriteMaxDecodedSize максимальный размер запроса после распаковки snappy
+	remoteWriteMaxDecodedSize = 64 << 20
+)
+
+func AddRemoteWriteOperations(r *chi.Mux, metricApp MetricApp) {
+	adapter := &remoteWriteAdapter{
+		metricApp: metricApp,
+	}
+
+	r.Post("/api/v1/write", adapter.Write)
+}
+
+type remoteWriteAdapter struct {
+	metricApp MetricApp
+}
+
+// Write принимает данные по протоколу Prometheus remote-write.
+//
+// POST /api/v1/write
+//
+// Content-Type: application/x-protobuf, Content-Encoding: snappy.
+//
+// В запросе - сжатый snappy prompb.WriteRequest. Для каждого временного ряда сохраняется последнее значение
+// как gauge: remote-write передает абсолютные значения, а counter этого сервера накапливает приращения.
+// Имя метрики берется из метки __name__, остальные метки сохраняются как метки метрики.
+// Данные проходят ту же проверку и сохранение, что и в POST /updates/.
+func (h *remoteWriteAdapter) Write(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	if contentType := req.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, ApplicationProtobuf) {
+		err := fmt.Errorf("%w: only '%v' supported", domain.ErrMediaType, ApplicationProtobuf)
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	if encoding := req.Header.Get("Content-Encoding"); encoding != "" && encoding != "snappy" {
+		err := fmt.Errorf("%w: only 'snappy' encoding supported", domain.ErrMediaType)
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	compressed, err := readBody(w, req, maxRequestBodySize)
+	if err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	// размер распакованных данных записан в заголовке snappy; память под них выделяется до распаковки
+	decodedLen, err := snappy.DecodedLen(compressed)
+	if err == nil && decodedLen > remoteWriteMaxDecodedSize {
+		err = fmt.Errorf("decoded size %d is larger than %d bytes", decodedLen, remoteWriteMaxDecodedSize)
+	}
+	if err != nil {
+		fullErr := fmt.Errorf("%w: snappy decode error - %v", domain.ErrDataFormat, err.Error())
+		handleAppError(req.Context(), w, fullErr)
+		return
+	}
+
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		fullErr := fmt.Errorf("%w: snappy decode error - %v", domain.ErrDataFormat, err.Error())
+		handleAppError(req.Context(), w, fullErr)
+		return
+	}
+
+	var writeReq prompb.WriteRequest
+	if err := proto.Unmarshal(data, &writeReq); err != nil {
+		fullErr := fmt.Errorf("%w: protobuf decode error - %v", domain.ErrDataFormat, err.Error())
+		handleAppError(req.Context(), w, fullErr)
+		return
+	}
+
+	metrics, err := fromWriteRequest(&writeReq)
+	if err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	if err := h.metricApp.UpdateAll(req.Context(), metrics); err != nil {
+		handleAppError(req.Context(), w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func fromWriteRequest(writeReq *prompb.WriteRequest) ([]domain.Metrics, error) {
+	metrics := make([]domain.Metrics, 0, len(writeReq.GetTimeseries()))
+
+	for _, ts := range writeReq.GetTimeseries() {
+		var name string
+		var labels domain.Labels
+		for _, l := range ts.GetLabels() {
+			if l.GetName() == metricNameLabel {
+				name = l.GetValue()
+				continue
+			}
+			// в Prometheus метка с пустым значением эквивалентна ее отсутствию
+			if l.GetValue() == "" {
+				continue
+			}
+			if labels == nil {
+				labels = make(domain.Labels)
+			}
+			labels[l.GetName()] = l.GetValue()
+		}
+
+		if name == "" {
+			return nil, fmt.Errorf("%w: time series without %v label", domain.ErrDataFormat, metricNameLabel)
+		}
+
+		sample, ok := latestSample(ts.GetSamples())
+		if !ok {
+			continue
+		}
+
+		metrics = append(metrics, domain.Metrics{
+			// имена правил записи Prometheus содержат ':', недопустимый в имени метрики
+			ID:     strings.ReplaceAll(name, ":", "_"),
+			MType:  domain.GaugeType,
+			Value:  domain.ValuePtr(sample.GetValue()),
+			Labels: labels,
+		})
+	}
+
+	return metrics, nil
+}
+
+// latestSample возвращает последнее по времени значение; NaN (в том числе метки устаревания Prometheus) и ±Inf пропускаются.
+func latestSample(samples []*prompb.Sample) (*prompb.Sample, bool) {
+	var latest *prompb.Sample
+	for _, s := range samples {
+		if math.IsNaN(s.GetValue()) || math.IsInf(s.GetValue(), 0) {
+			continue
+		}
+		if latest == nil || s.GetTimestamp() >= latest.GetTimestamp() {
+			latest = s
+		}
+	}
+	return latest, latest != nil
+}
@@ -0,0 +1,173 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/proto/prompb"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/handler"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/klauspost/compress/snappy"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func remoteWriteBody(t *testing.T, writeReq *prompb.WriteRequest) []byte {
+	t.Helper()
+	data, err := proto.Marshal(writeReq)
+	require.NoError(t, err)
+	return snappy.Encode(nil, data)
+}
+
+func sendRemoteWrite(t *testing.T, url string, body []byte, encoding string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url+"/api/v1/write", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", handler.ApplicationProtobuf)
+	req.Header.Set("Content-Encoding", encoding)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestRemoteWrite(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ms []domain.Metrics) error {
+			require.Equal(t, []domain.Metrics{
+				{
+					ID:     "node_load1",
+					MType:  domain.GaugeType,
+					Value:  domain.ValuePtr(0.75),
+					Labels: domain.Labels{"instance": "h1", "job": "node"},
+				},
+				{
+					ID:    "job_http_requests_rate5m",
+					MType: domain.GaugeType,
+					Value: domain.ValuePtr(12),
+				},
+			}, ms)
+			return nil
+		}).Times(1)
+
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).Return(domain.ErrDataFormat).Times(1)
+
+	r := chi.NewRouter()
+	handler.AddRemoteWriteOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	body := remoteWriteBody(t, &prompb.WriteRequest{
+		Timeseries: []*prompb.TimeSeries{
+			{
+				Labels: []*prompb.Label{
+					{Name: "__name__", Value: "node_load1"},
+					{Name: "instance", Value: "h1"},
+					{Name: "job", Value: "node"},
+					{Name: "empty", Value: ""},
+				},
+				Samples: []*prompb.Sample{
+					{Value: 0.5, Timestamp: 1000},
+					{Value: 0.75, Timestamp: 2000},
+					{Value: 0.1, Timestamp: 1500},
+				},
+			},
+			{
+				Labels: []*prompb.Label{
+					{Name: "__name__", Value: "job:http_requests:rate5m"},
+				},
+				Samples: []*prompb.Sample{
+					{Value: 12, Timestamp: 1000},
+					{Value: math.NaN(), Timestamp: 2000},
+					{Value: math.Inf(1), Timestamp: 3000},
+					{Value: math.Inf(-1), Timestamp: 4000},
+				},
+			},
+			{
+				// ряд только с меткой устаревания пропускается
+				Labels: []*prompb.Label{
+					{Name: "__name__", Value: "stale"},
+				},
+				Samples: []*prompb.Sample{
+					{Value: math.NaN(), Timestamp: 2000},
+				},
+			},
+			{
+				// ряд только с бесконечными значениями пропускается
+				Labels: []*prompb.Label{
+					{Name: "__name__", Value: "inf"},
+				},
+				Samples: []*prompb.Sample{
+					{Value: math.Inf(1), Timestamp: 1000},
+					{Value: math.Inf(-1), Timestamp: 2000},
+				},
+			},
+		},
+	})
+
+	require.Equal(t, http.StatusNoContent, sendRemoteWrite(t, srv.URL, body, "snappy"))
+
+	// ошибка проверки данных в UpdateAll
+	require.Equal(t, http.StatusBadRequest, sendRemoteWrite(t, srv.URL, body, "snappy"))
+}
+
+func TestRemoteWrite_BadRequest(t *testing.T) {
+	log := logger()
+	domain.SetMainLogger(log)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockMetricApp(ctrl)
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).Times(0)
+
+	r := chi.NewRouter()
+	handler.AddRemoteWriteOperations(r, m)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	// не snappy
+	require.Equal(t, http.StatusBadRequest, sendRemoteWrite(t, srv.URL, []byte("not snappy"), "snappy"))
+
+	// неподдерживаемое сжатие
+	require.Equal(t, http.StatusUnsupportedMediaType, sendRemoteWrite(t, srv.URL, []byte{}, "zstd"))
+
+	// не protobuf
+	require.Equal(t, http.StatusBadRequest, sendRemoteWrite(t, srv.URL, snappy.Encode(nil, []byte{0xff, 0xff}), "snappy"))
+
+	// заголовок snappy заявляет 4 ГиБ распакованных данных
+	require.Equal(t, http.StatusBadRequest, sendRemoteWrite(t, srv.URL, binary.AppendUvarint(nil, 1<<32-1), "snappy"))
+
+	// сжатый запрос больше допустимого
+	require.Equal(t, http.StatusBadRequest, sendRemoteWrite(t, srv.URL, make([]byte, 40<<20), "snappy"))
+
+	// нет имени метрики
+	body := remoteWriteBody(t, &prompb.WriteRequest{
+		Timeseries: []*prompb.TimeSeries{
+			{
+				Labels:  []*prompb.Label{{Name: "job", Value: "node"}},
+				Samples: []*prompb.Sample{{Value: 1, Timestamp: 1}},
+			},
+		},
+	})
+	require.Equal(t, http.StatusBadRequest, sendRemoteWrite(t, srv.URL, body, "snappy"))
+}
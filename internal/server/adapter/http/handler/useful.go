@@ -2,7 +2,9 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
@@ -14,6 +16,12 @@ const (
 	TextHTML        = "text/html"
 )
 
+// maxRequestBodySize максимальный размер тела запроса протоколов приема метрик
+const maxRequestBodySize = 32 << 20
+
+// IdempotencyKeyHeader заголовок с ключом пакета метрик
+const IdempotencyKeyHeader = "X-Idempotency-Key"
+
 func BadRequestHandler(w http.ResponseWriter, req *http.Request) {
 	http.Error(w, "BadRequest", http.StatusBadRequest)
 }
@@ -50,3 +58,13 @@ func handleAppError(ctx context.Context, w http.ResponseWriter, err error) {
 	clientErr := domain.MapDomainErrorToHTTPStatusErr(err)
 	w.WriteHeader(clientErr)
 }
+
+// readBody читает тело запроса размером не больше limit.
+func readBody(w http.ResponseWriter, req *http.Request, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, limit))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, fmt.Errorf("%w: request body is larger than %d bytes", domain.ErrDataFormat, limit)
+	}
+	return body, err
+}
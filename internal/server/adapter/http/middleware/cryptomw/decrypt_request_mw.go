@@ -11,7 +11,7 @@ import (
 	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
 )
 
-// NewDecrytpMw дешифрует входящие данные
+// NewDecrytpMw дешифрует входящие данные; принимается формат конверта keygen.EncryptEnvelope и старый формат агентов (RSA-OAEP)
 func NewDecrytpMw(privKey *rsa.PrivateKey) middleware.Middleware {
 	return func(next http.Handler) http.Handler {
 		lrw := func(w http.ResponseWriter, req *http.Request) {
@@ -25,7 +25,7 @@ func NewDecrytpMw(privKey *rsa.PrivateKey) middleware.Middleware {
 				return
 			}
 
-			decryptedBytes, err := keygen.DecryptWithPrivateKey(encryptedData, privKey)
+			decryptedBytes, err := keygen.DecryptEnvelope(encryptedData, privKey)
 
 			if err != nil {
 				log.Infow(action, "error", err.Error())
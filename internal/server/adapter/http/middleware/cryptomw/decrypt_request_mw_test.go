@@ -1,6 +1,7 @@
 package cryptomw_test
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha512"
@@ -11,11 +12,14 @@ import (
 
 	"testing"
 
+	"github.com/StasMerzlyakov/go-metrics/internal/keygen"
 	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/middleware"
 	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/http/middleware/cryptomw"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
 	"github.com/go-resty/resty/v2"
 	gomock "github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	"gotest.tools/v3/assert"
 )
 
@@ -57,3 +61,56 @@ func TestDecryptMW(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode())
 }
+
+func TestDecryptMW_Envelope(t *testing.T) {
+
+	logger, err := zap.NewDevelopment()
+	require.NoError(t, err)
+	defer logger.Sync()
+
+	suga := logger.Sugar()
+	domain.SetMainLogger(suga)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockHandler := NewMockHandler(ctrl)
+
+	// данные больше, чем RSA-OAEP может зашифровать напрямую
+	testBytes := bytes.Repeat([]byte("test string to check encription/decryption"), 1000)
+
+	mockHandler.EXPECT().ServeHTTP(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(w http.ResponseWriter, req *http.Request) {
+			data, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			defer req.Body.Close()
+			require.Equal(t, testBytes, data)
+		}).Times(1)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 4096)
+	require.NoError(t, err)
+
+	encrypted, err := keygen.EncryptEnvelope(testBytes, &privateKey.PublicKey)
+	require.NoError(t, err)
+
+	decryptedMW := cryptomw.NewDecrytpMw(privateKey)
+
+	srv := httptest.NewServer(middleware.Conveyor(mockHandler, decryptedMW))
+	defer srv.Close()
+
+	resp, err := resty.New().R().
+		SetHeader("Content-Type", "text/plain; charset=UTF-8").
+		SetBody(encrypted).
+		Post(srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	// поврежденные данные
+	encrypted[len(encrypted)-1] ^= 0xff
+	resp, err = resty.New().R().
+		SetHeader("Content-Type", "text/plain; charset=UTF-8").
+		SetBody(encrypted).
+		Post(srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+}
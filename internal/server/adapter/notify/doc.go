@@ -0,0 +1,2 @@
+// Package notify contains alert notification channels
+package notify
@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+func NewLog() *logSink {
+	return &logSink{}
+}
+
+// logSink выводит оповещения в лог сервера; каждое оповещение группы - отдельной записью.
+type logSink struct{}
+
+func (ls *logSink) Name() string {
+	return "log"
+}
+
+func (ls *logSink) Send(ctx context.Context, notification *domain.Notification) error {
+	logger := domain.GetCtxLogger(ctx)
+	for _, alert := range notification.Alerts {
+		logger.Infow("alert",
+			"group", notification.Group,
+			"rule", alert.Rule,
+			"metric", alert.Metric,
+			"type", alert.MType,
+			"labels", alert.Labels.String(),
+			"state", alert.State,
+			"value", alert.Value,
+			"threshold", alert.Threshold,
+			"activeAt", alert.ActiveAt,
+		)
+	}
+	return nil
+}
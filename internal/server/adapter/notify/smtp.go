@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+// smtpTimeout ограничение времени отправки письма, если контекст не задает его сам
+const smtpTimeout = 30 * time.Second
+
+type SMTPConf struct {
+	Addr     string   // адрес SMTP-сервера host:port
+	From     string   // адрес отправителя
+	To       []string // адреса получателей
+	Username string   // имя пользователя; если не задано - без аутентификации
+	Password string
+}
+
+func NewSMTP(conf *SMTPConf) *smtpSender {
+	return &smtpSender{
+		conf: *conf,
+	}
+}
+
+// smtpSender отправляет оповещения группы одним письмом.
+type smtpSender struct {
+	conf SMTPConf
+}
+
+func (ss *smtpSender) Name() string {
+	return "smtp"
+}
+
+func (ss *smtpSender) Send(ctx context.Context, notification *domain.Notification) error {
+	host, _, err := net.SplitHostPort(ss.conf.Addr)
+	if err != nil {
+		return err
+	}
+
+	err = ss.sendMail(ctx, host, ss.message(notification))
+	if err == nil {
+		return nil
+	}
+
+	// постоянные ошибки сервера (5xx) не повторяем
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) && tpErr.Code >= 500 {
+		return fmt.Errorf("smtp error - %w", err)
+	}
+	return fmt.Errorf("%w: smtp error - %v", domain.ErrNotification, err.Error())
+}
+
+// sendMail повторяет smtp.SendMail, но соединение открывается с учетом ctx
+// и обмен с сервером прерывается по истечении срока ctx или smtpTimeout.
+func (ss *smtpSender) sendMail(ctx context.Context, host string, msg []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", ss.conf.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	// отмена ctx прерывает ожидание ответа сервера
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+
+	if ss.conf.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(smtp.PlainAuth("", ss.conf.Username, ss.conf.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(ss.conf.From); err != nil {
+		return err
+	}
+	for _, addr := range ss.conf.To {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (ss *smtpSender) message(notification *domain.Notification) []byte {
+	var firing int
+	for _, alert := range notification.Alerts {
+		if alert.State == domain.AlertFiring {
+			firing++
+		}
+	}
+
+	var subject string
+	if firing > 0 {
+		subject = fmt.Sprintf("[FIRING:%d] %s", firing, notification.Group)
+	} else {
+		subject = fmt.Sprintf("[RESOLVED] %s", notification.Group)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", ss.conf.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(ss.conf.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", subject)
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+
+	for _, alert := range notification.Alerts {
+		fmt.Fprintf(&buf, "%s %s %s%s value=%v threshold=%v since=%s\r\n",
+			alert.State, alert.Rule, alert.Metric, alert.Labels.String(), alert.Value, alert.Threshold, alert.ActiveAt.Format(time.RFC3339))
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,158 @@
+package notify_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/notify"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpStub минимальный SMTP-сервер; сохраняет полученное письмо.
+// rcptCode - код ответа на RCPT TO.
+type smtpStub struct {
+	listener net.Listener
+	rcptCode string
+	messages chan string
+}
+
+func newSMTPStub(t *testing.T, rcptCode string) *smtpStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	stub := &smtpStub{
+		listener: listener,
+		rcptCode: rcptCode,
+		messages: make(chan string, 1),
+	}
+	go stub.serve()
+	t.Cleanup(func() { listener.Close() })
+	return stub
+}
+
+func (s *smtpStub) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStub) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	w("220 localhost ESMTP stub")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			w("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			w("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			w(s.rcptCode + " rcpt")
+		case cmd == "DATA":
+			w("354 go ahead")
+			var sb strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				sb.WriteString(dataLine)
+			}
+			s.messages <- sb.String()
+			w("250 OK")
+		case cmd == "QUIT":
+			w("221 bye")
+			return
+		default:
+			w("250 OK")
+		}
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	stub := newSMTPStub(t, "250")
+
+	sender := notify.NewSMTP(&notify.SMTPConf{
+		Addr: stub.listener.Addr().String(),
+		From: "metrics@localhost",
+		To:   []string{"ops@localhost"},
+	})
+
+	err := sender.Send(context.Background(), testNotification)
+	require.NoError(t, err)
+
+	msg := <-stub.messages
+	require.Contains(t, msg, "Subject: [FIRING:1] HighAlloc")
+	require.Contains(t, msg, "To: ops@localhost")
+	require.Contains(t, msg, `firing HighAlloc Alloc{host="h1"} value=200 threshold=100`)
+}
+
+func TestSMTP_Errors(t *testing.T) {
+	// временная ошибка - отправка повторяется
+	stub := newSMTPStub(t, "451")
+	err := notify.NewSMTP(&notify.SMTPConf{
+		Addr: stub.listener.Addr().String(),
+		From: "metrics@localhost",
+		To:   []string{"ops@localhost"},
+	}).Send(context.Background(), testNotification)
+	require.True(t, errors.Is(err, domain.ErrNotification))
+
+	// постоянная ошибка
+	stub = newSMTPStub(t, "550")
+	err = notify.NewSMTP(&notify.SMTPConf{
+		Addr: stub.listener.Addr().String(),
+		From: "metrics@localhost",
+		To:   []string{"ops@localhost"},
+	}).Send(context.Background(), testNotification)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, domain.ErrNotification))
+}
+
+func TestSMTP_Timeout(t *testing.T) {
+	// сервер принимает соединение, но не отвечает
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		<-done
+		conn.Close()
+	}()
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelFn()
+
+	start := time.Now()
+	err = notify.NewSMTP(&notify.SMTPConf{
+		Addr: listener.Addr().String(),
+		From: "metrics@localhost",
+		To:   []string{"ops@localhost"},
+	}).Send(ctx, testNotification)
+	require.True(t, errors.Is(err, domain.ErrNotification))
+	require.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+func NewWebhook(url string, client *http.Client) *webhook {
+	return &webhook{
+		url:    url,
+		client: client,
+	}
+}
+
+// webhook отправляет оповещения POST-запросом с телом [domain.Notification] в формате JSON.
+type webhook struct {
+	url    string
+	client *http.Client
+}
+
+func (wh *webhook) Name() string {
+	return "webhook"
+}
+
+func (wh *webhook) Send(ctx context.Context, notification *domain.Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: webhook request error - %v", domain.ErrNotification, err.Error())
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: webhook status %v", domain.ErrNotification, resp.StatusCode)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return fmt.Errorf("webhook status %v", resp.StatusCode)
+	default:
+		return nil
+	}
+}
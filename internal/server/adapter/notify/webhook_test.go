@@ -0,0 +1,69 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/notify"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/stretchr/testify/require"
+)
+
+var testNotification = &domain.Notification{
+	Group: "HighAlloc",
+	Alerts: []domain.Alert{
+		{
+			Rule:      "HighAlloc",
+			Metric:    "Alloc",
+			MType:     domain.GaugeType,
+			Labels:    domain.Labels{"host": "h1"},
+			State:     domain.AlertFiring,
+			Value:     200,
+			Threshold: 100,
+			ActiveAt:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	},
+}
+
+func TestWebhook(t *testing.T) {
+	status := http.StatusOK
+	var received domain.Notification
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	wh := notify.NewWebhook(srv.URL, srv.Client())
+	ctx := context.Background()
+
+	err := wh.Send(ctx, testNotification)
+	require.NoError(t, err)
+	require.Equal(t, *testNotification, received)
+
+	status = http.StatusServiceUnavailable
+	err = wh.Send(ctx, testNotification)
+	require.True(t, errors.Is(err, domain.ErrNotification))
+
+	status = http.StatusBadRequest
+	err = wh.Send(ctx, testNotification)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, domain.ErrNotification))
+}
+
+func TestWebhook_Unavailable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	err := notify.NewWebhook(url, http.DefaultClient).Send(context.Background(), testNotification)
+	require.True(t, errors.Is(err, domain.ErrNotification))
+}
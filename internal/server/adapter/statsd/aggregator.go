@@ -0,0 +1,181 @@
+package statsd
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+// gaugeTTL время хранения значения gauge без обновлений
+const gaugeTTL = time.Hour
+
+// DefaultTimerBuckets границы корзин гистограмм для таймеров StatsD, в миллисекундах.
+var DefaultTimerBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+type counterAgg struct {
+	name   string
+	labels domain.Labels
+	value  float64
+}
+
+type gaugeAgg struct {
+	name      string
+	labels    domain.Labels
+	value     float64
+	updated   bool
+	updatedAt time.Time
+}
+
+type timerAgg struct {
+	name      string
+	labels    domain.Labels
+	histogram *domain.Histogram
+}
+
+type setAgg struct {
+	name   string
+	labels domain.Labels
+	values map[string]struct{}
+}
+
+func newAggregator(buckets []float64) *aggregator {
+	return &aggregator{
+		buckets:  buckets,
+		counters: make(map[string]*counterAgg),
+		gauges:   make(map[string]*gaugeAgg),
+		timers:   make(map[string]*timerAgg),
+		sets:     make(map[string]*setAgg),
+	}
+}
+
+// aggregator накапливает значения StatsD в течение интервала сброса.
+//
+// counter - сумма приращений с учетом частоты выборки; дробный остаток переносится на следующий интервал.
+// gauge - последнее значение; значение со знаком изменяет текущее, поэтому значения gauge хранятся между интервалами,
+// но не дольше gaugeTTL без обновлений.
+// timer (ms, h) - histogram с границами buckets. set - gauge с количеством уникальных значений за интервал.
+type aggregator struct {
+	buckets  []float64
+	mu       sync.Mutex
+	counters map[string]*counterAgg
+	gauges   map[string]*gaugeAgg
+	timers   map[string]*timerAgg
+	sets     map[string]*setAgg
+}
+
+func (ag *aggregator) add(s *sample, now time.Time) {
+	key := domain.MetricKey(s.name, s.labels)
+
+	ag.mu.Lock()
+	defer ag.mu.Unlock()
+
+	switch s.kind {
+	case kindCounter:
+		c, ok := ag.counters[key]
+		if !ok {
+			c = &counterAgg{name: s.name, labels: s.labels}
+			ag.counters[key] = c
+		}
+		c.value += s.value / s.rate
+	case kindGauge:
+		g, ok := ag.gauges[key]
+		if !ok {
+			g = &gaugeAgg{name: s.name, labels: s.labels}
+			ag.gauges[key] = g
+		}
+		if s.relative {
+			g.value += s.value
+		} else {
+			g.value = s.value
+		}
+		g.updated = true
+		g.updatedAt = now
+	case kindTimer, kindHistogram:
+		t, ok := ag.timers[key]
+		if !ok {
+			t = &timerAgg{name: s.name, labels: s.labels, histogram: domain.NewHistogram(ag.buckets)}
+			ag.timers[key] = t
+		}
+		// при частоте выборки rate одно значение соответствует 1/rate наблюдениям
+		t.histogram.ObserveN(s.value, int64(math.Round(1/s.rate)))
+	case kindSet:
+		st, ok := ag.sets[key]
+		if !ok {
+			st = &setAgg{name: s.name, labels: s.labels, values: make(map[string]struct{})}
+			ag.sets[key] = st
+		}
+		st.values[s.rawValue] = struct{}{}
+	}
+}
+
+// flush возвращает накопленные за интервал метрики и начинает новый интервал.
+func (ag *aggregator) flush(now time.Time) []domain.Metrics {
+	ag.mu.Lock()
+	defer ag.mu.Unlock()
+
+	var res []domain.Metrics
+
+	for key, c := range ag.counters {
+		delta := math.Round(c.value)
+		c.value -= delta
+		if c.value == 0 {
+			delete(ag.counters, key)
+		}
+		if delta == 0 {
+			continue
+		}
+		res = append(res, domain.Metrics{
+			ID:     c.name,
+			MType:  domain.CounterType,
+			Delta:  domain.DeltaPtr(int64(delta)),
+			Labels: c.labels,
+		})
+	}
+
+	for key, g := range ag.gauges {
+		if !g.updated {
+			if now.Sub(g.updatedAt) > gaugeTTL {
+				delete(ag.gauges, key)
+			}
+			continue
+		}
+		g.updated = false
+		res = append(res, domain.Metrics{
+			ID:     g.name,
+			MType:  domain.GaugeType,
+			Value:  domain.ValuePtr(g.value),
+			Labels: g.labels,
+		})
+	}
+
+	for _, t := range ag.timers {
+		res = append(res, domain.Metrics{
+			ID:        t.name,
+			MType:     domain.HistogramType,
+			Histogram: t.histogram,
+			Labels:    t.labels,
+		})
+	}
+	ag.timers = make(map[string]*timerAgg)
+
+	for _, st := range ag.sets {
+		res = append(res, domain.Metrics{
+			ID:     st.name,
+			MType:  domain.GaugeType,
+			Value:  domain.ValuePtr(float64(len(st.values))),
+			Labels: st.labels,
+		})
+	}
+	ag.sets = make(map[string]*setAgg)
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].MType != res[j].MType {
+			return res[i].MType < res[j].MType
+		}
+		return res[i].Key() < res[j].Key()
+	})
+	return res
+}
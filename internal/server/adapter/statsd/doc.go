@@ -0,0 +1,2 @@
+// Package statsd содержит UDP-приемник метрик в формате StatsD
+package statsd
@@ -0,0 +1,126 @@
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+// maxPacketSize максимальный размер UDP-пакета.
+const maxPacketSize = 65535
+
+func NewListener(address string, flushInterval time.Duration, metricApp MetricApp) *listener {
+	return &listener{
+		address:       address,
+		flushInterval: flushInterval,
+		metricApp:     metricApp,
+		aggregator:    newAggregator(DefaultTimerBuckets),
+	}
+}
+
+// listener принимает строки StatsD по UDP и раз в flushInterval сохраняет агрегированные значения через UpdateAll.
+type listener struct {
+	address       string
+	flushInterval time.Duration
+	metricApp     MetricApp
+	aggregator    *aggregator
+	conn          net.PacketConn
+}
+
+// Listen открывает UDP-порт.
+func (l *listener) Listen() error {
+	conn, err := net.ListenPacket("udp", l.address)
+	if err != nil {
+		return err
+	}
+	l.conn = conn
+	return nil
+}
+
+// Addr возвращает адрес открытого порта.
+func (l *listener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// Serve принимает пакеты до отмены контекста; накопленные значения сохраняются перед завершением.
+func (l *listener) Serve(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		l.read(ctx)
+	}()
+
+	go func() {
+		<-ctx.Done()
+		l.conn.Close()
+	}()
+
+	ticker := time.NewTicker(l.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			// контекст сервера отменен - последние значения сохраняем с новым контекстом
+			flushCtx, cancelFn := context.WithTimeout(context.Background(), l.flushInterval)
+			l.flush(flushCtx)
+			cancelFn()
+			return
+		case <-ticker.C:
+			l.flush(ctx)
+		}
+	}
+}
+
+func (l *listener) read(ctx context.Context) {
+	logger := domain.GetCtxLogger(ctx)
+	action := domain.GetAction(1)
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, _, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			// временная ошибка сокета не останавливает прием
+			logger.Errorw(action, "status", "error", "msg", err.Error())
+			continue
+		}
+
+		for _, line := range bytes.Split(buf[:n], []byte("\n")) {
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			s, err := parseLine(string(line))
+			if err != nil {
+				// StatsD не предполагает ответа клиенту; некорректные строки пропускаются
+				logger.Infow(action, "status", "error", "msg", err.Error())
+				continue
+			}
+			l.aggregator.add(s, time.Now())
+		}
+	}
+}
+
+func (l *listener) flush(ctx context.Context) {
+	metrics := l.aggregator.flush(time.Now())
+	if len(metrics) == 0 {
+		return
+	}
+
+	if err := l.metricApp.UpdateAll(ctx, metrics); err != nil {
+		logger := domain.GetCtxLogger(ctx)
+		action := domain.GetAction(1)
+		logger.Errorw(action, "status", "error", "msg", err.Error())
+	}
+}
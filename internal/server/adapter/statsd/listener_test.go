@@ -0,0 +1,234 @@
+package statsd_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/statsd"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// received накапливает результаты всех сбросов: counter суммируются, для gauge берется последнее значение.
+type received struct {
+	mu         sync.Mutex
+	counters   map[string]int64
+	gauges     map[string]float64
+	histograms map[string]*domain.Histogram
+}
+
+func newReceived() *received {
+	return &received{
+		counters:   make(map[string]int64),
+		gauges:     make(map[string]float64),
+		histograms: make(map[string]*domain.Histogram),
+	}
+}
+
+func (r *received) UpdateAll(ctx context.Context, mtr []domain.Metrics) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range mtr {
+		switch m.MType {
+		case domain.CounterType:
+			r.counters[m.Key()] += *m.Delta
+		case domain.GaugeType:
+			r.gauges[m.Key()] = *m.Value
+		case domain.HistogramType:
+			r.histograms[m.Key()] = domain.MergeHistogram(r.histograms[m.Key()], m.Histogram)
+		}
+	}
+	return nil
+}
+
+func (r *received) counter(key string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counters[key]
+}
+
+func (r *received) gauge(key string) (float64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.gauges[key]
+	return v, ok
+}
+
+func (r *received) histogram(key string) *domain.Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.histograms[key].Copy()
+}
+
+func startListener(t *testing.T, metricApp statsd.MetricApp) (net.Conn, func()) {
+	t.Helper()
+
+	logger, err := zap.NewDevelopment()
+	require.NoError(t, err)
+	domain.SetMainLogger(logger.Sugar())
+
+	l := statsd.NewListener("127.0.0.1:0", 20*time.Millisecond, metricApp)
+	require.NoError(t, l.Listen())
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Serve(ctx)
+	}()
+
+	conn, err := net.Dial("udp", l.Addr().String())
+	require.NoError(t, err)
+
+	return conn, func() {
+		conn.Close()
+		cancelFn()
+		<-done
+	}
+}
+
+func TestListener(t *testing.T) {
+	res := newReceived()
+	conn, stop := startListener(t, res)
+	defer stop()
+
+	_, err := conn.Write([]byte("api.requests:1|c\napi.requests:2|c|@0.5\n" +
+		"temp:20|g\ntemp:+5|g\ntemp:-3|g\n" +
+		"db.query:7|ms\ndb.query:300|ms|@0.5\n" +
+		"users:alice|s\nusers:bob|s\nusers:alice|s\n" +
+		"hits:1|c|#host:h1,region:eu\n"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		v, _ := res.gauge("temp")
+		h := res.histogram("db_query")
+		users, _ := res.gauge("users")
+		return res.counter("api_requests") == 5 && v == 22 &&
+			h != nil && h.Count == 3 && users == 2 &&
+			res.counter(domain.MetricKey("hits", domain.Labels{"host": "h1", "region": "eu"})) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	h := res.histogram("db_query")
+	require.Equal(t, float64(607), h.Sum)
+	require.Equal(t, statsd.DefaultTimerBuckets, h.Bounds)
+
+	// относительное значение gauge применяется к значению из предыдущего интервала
+	_, err = conn.Write([]byte("temp:+1|g"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		v, _ := res.gauge("temp")
+		return v == 23
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestListener_LowSampleRate(t *testing.T) {
+	res := newReceived()
+	conn, stop := startListener(t, res)
+	defer stop()
+
+	// одно значение таймера соответствует миллиону наблюдений
+	_, err := conn.Write([]byte("db.query:2|ms|@0.000001\n"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		h := res.histogram("db_query")
+		return h != nil && h.Count == 1_000_000
+	}, time.Second, 10*time.Millisecond)
+
+	h := res.histogram("db_query")
+	require.Equal(t, float64(2_000_000), h.Sum)
+	require.Equal(t, int64(1_000_000), h.Counts[1])
+	require.NoError(t, h.Check())
+}
+
+func TestListener_WrongLines(t *testing.T) {
+	res := newReceived()
+	conn, stop := startListener(t, res)
+	defer stop()
+
+	_, err := conn.Write([]byte("no_type:1\n:1|c\n1abc:1|c\nx:abc|c\nx:1|zz\nx:1|c|@2\nx:1|ms|@0.000000001\n" +
+		"valid:4|c\n"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return res.counter("valid") == 4
+	}, time.Second, 10*time.Millisecond)
+
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	require.Len(t, res.counters, 1)
+	require.Empty(t, res.gauges)
+	require.Empty(t, res.histograms)
+}
+
+func TestListener_NotFiniteValues(t *testing.T) {
+	res := newReceived()
+	conn, stop := startListener(t, res)
+	defer stop()
+
+	_, err := conn.Write([]byte("x:NaN|g\nx:+Inf|g\nx:-Inf|c\ny:Inf|ms\ny:NaN|h\n" +
+		"valid:4|c\n"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return res.counter("valid") == 4
+	}, time.Second, 10*time.Millisecond)
+
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	require.Len(t, res.counters, 1)
+	require.Empty(t, res.gauges)
+	require.Empty(t, res.histograms)
+}
+
+func TestListener_FlushOnShutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger, err := zap.NewDevelopment()
+	require.NoError(t, err)
+	domain.SetMainLogger(logger.Sugar())
+
+	var mu sync.Mutex
+	var flushed []domain.Metrics
+	m := NewMockMetricApp(ctrl)
+	m.EXPECT().UpdateAll(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, mtr []domain.Metrics) error {
+		mu.Lock()
+		defer mu.Unlock()
+		flushed = append(flushed, mtr...)
+		return nil
+	}).MinTimes(1)
+
+	// интервал сброса больше времени теста - значения сохраняются только при остановке
+	l := statsd.NewListener("127.0.0.1:0", time.Hour, m)
+	require.NoError(t, l.Listen())
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Serve(ctx)
+	}()
+
+	conn, err := net.Dial("udp", l.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("requests:3|c"))
+	require.NoError(t, err)
+
+	// даем приемнику прочитать пакет
+	time.Sleep(100 * time.Millisecond)
+	cancelFn()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []domain.Metrics{{ID: "requests", MType: domain.CounterType, Delta: domain.DeltaPtr(3)}}, flushed)
+}
@@ -0,0 +1,112 @@
+package statsd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+// Типы метрик StatsD
+const (
+	kindCounter   = "c"
+	kindGauge     = "g"
+	kindTimer     = "ms"
+	kindHistogram = "h"
+	kindSet       = "s"
+)
+
+// minSampleRate минимальная частота выборки; одно значение соответствует не более чем 1/minSampleRate наблюдениям
+const minSampleRate = 1e-6
+
+// sample одно значение из строки StatsD.
+type sample struct {
+	name     string
+	kind     string
+	value    float64
+	rawValue string  // исходное значение; используется для set
+	relative bool    // для gauge: значение со знаком изменяет текущее
+	rate     float64 // частота выборки [minSampleRate, 1]
+	labels   domain.Labels
+}
+
+// parseLine разбирает строку вида name:value|type[|@rate][|#tag:value,...].
+func parseLine(line string) (*sample, error) {
+	nameEnd := strings.IndexByte(line, ':')
+	if nameEnd <= 0 {
+		return nil, fmt.Errorf("%w: statsd line '%v' has no name", domain.ErrDataFormat, line)
+	}
+
+	name, err := sanitizeName(line[:nameEnd])
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(line[nameEnd+1:], "|")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("%w: statsd line '%v' has no type", domain.ErrDataFormat, line)
+	}
+
+	s := &sample{
+		name:     name,
+		kind:     parts[1],
+		rawValue: parts[0],
+		rate:     1,
+	}
+
+	switch s.kind {
+	case kindCounter, kindGauge, kindTimer, kindHistogram:
+		if s.value, err = strconv.ParseFloat(parts[0], 64); err != nil || !domain.IsFinite(s.value) {
+			return nil, fmt.Errorf("%w: statsd line '%v' wrong value", domain.ErrDataFormat, line)
+		}
+		s.relative = s.kind == kindGauge && (parts[0][0] == '+' || parts[0][0] == '-')
+	case kindSet:
+	default:
+		return nil, fmt.Errorf("%w: statsd line '%v' unknown type %v", domain.ErrDataFormat, line, s.kind)
+	}
+
+	for _, part := range parts[2:] {
+		switch {
+		case strings.HasPrefix(part, "@"):
+			if s.rate, err = strconv.ParseFloat(part[1:], 64); err != nil || s.rate < minSampleRate || s.rate > 1 {
+				return nil, fmt.Errorf("%w: statsd line '%v' wrong sample rate", domain.ErrDataFormat, line)
+			}
+		case strings.HasPrefix(part, "#"):
+			if s.labels, err = parseTags(part[1:]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// parseTags разбирает теги DogStatsD вида key:value,key2:value2; тег без значения пропускается.
+func parseTags(tags string) (domain.Labels, error) {
+	var labels domain.Labels
+	for _, tag := range strings.Split(tags, ",") {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok || key == "" || value == "" {
+			continue
+		}
+		name, err := sanitizeName(key)
+		if err != nil {
+			return nil, err
+		}
+		if labels == nil {
+			labels = make(domain.Labels)
+		}
+		labels[name] = value
+	}
+	return labels, nil
+}
+
+// sanitizeName преобразует имя StatsD в имя метрики; имя должно начинаться с буквы.
+func sanitizeName(name string) (string, error) {
+	res := domain.SanitizeName(name)
+	if res == "" || res[0] == '_' || res[0] >= '0' && res[0] <= '9' {
+		return "", fmt.Errorf("%w: wrong statsd name '%v'", domain.ErrDataFormat, name)
+	}
+	return res, nil
+}
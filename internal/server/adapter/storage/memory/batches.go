@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+// DefaultBatchKeysCapacity количество хранимых ключей идемпотентности.
+const DefaultBatchKeysCapacity = 100000
+
+type batchKey struct {
+	key       string
+	appliedAt time.Time
+}
+
+// batchKeys хранит ключи примененных пакетов в порядке применения; при переполнении удаляются самые старые.
+type batchKeys struct {
+	mu      sync.Mutex
+	keys    []batchKey // кольцевой буфер
+	start   int        // индекс самого старого ключа
+	size    int
+	applied map[string]struct{}
+}
+
+func newBatchKeys(capacity int) *batchKeys {
+	return &batchKeys{
+		keys:    make([]batchKey, capacity),
+		applied: make(map[string]struct{}),
+	}
+}
+
+func (b *batchKeys) push(key string, ts time.Time) {
+	capacity := len(b.keys)
+	if b.size == capacity {
+		delete(b.applied, b.keys[b.start].key)
+		b.start = (b.start + 1) % capacity
+		b.size--
+	}
+	b.keys[(b.start+b.size)%capacity] = batchKey{key: key, appliedAt: ts}
+	b.size++
+	b.applied[key] = struct{}{}
+}
+
+func (st *storage) AddMetricsOnce(ctx context.Context, key string, metric []domain.Metrics) (bool, error) {
+	st.batches.mu.Lock()
+	defer st.batches.mu.Unlock()
+
+	if _, ok := st.batches.applied[key]; ok {
+		return false, nil
+	}
+
+	if err := st.AddMetrics(ctx, metric); err != nil {
+		return false, err
+	}
+
+	st.batches.push(key, time.Now())
+	return true, nil
+}
+
+func (st *storage) DeleteBatchKeysBefore(ctx context.Context, ts time.Time) error {
+	st.batches.mu.Lock()
+	defer st.batches.mu.Unlock()
+
+	b := st.batches
+	for b.size > 0 && b.keys[b.start].appliedAt.Before(ts) {
+		delete(b.applied, b.keys[b.start].key)
+		b.keys[b.start] = batchKey{}
+		b.start = (b.start + 1) % len(b.keys)
+		b.size--
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/storage/memory"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStorageAddMetricsOnce(t *testing.T) {
+	storage := memory.NewStorage()
+	ctx := context.Background()
+
+	batch := []domain.Metrics{
+		{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(2)},
+	}
+
+	applied, err := storage.AddMetricsOnce(ctx, "batch-1", batch)
+	require.NoError(t, err)
+	require.True(t, applied)
+
+	// повтор пакета не применяется
+	applied, err = storage.AddMetricsOnce(ctx, "batch-1", batch)
+	require.NoError(t, err)
+	require.False(t, applied)
+
+	applied, err = storage.AddMetricsOnce(ctx, "batch-2", batch)
+	require.NoError(t, err)
+	require.True(t, applied)
+
+	m, err := storage.Get(ctx, "PollCount", nil, domain.CounterType)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), *m.Delta)
+
+	// ключи, примененные раньше ts, удаляются
+	require.NoError(t, storage.DeleteBatchKeysBefore(ctx, time.Now().Add(time.Second)))
+
+	applied, err = storage.AddMetricsOnce(ctx, "batch-1", batch)
+	require.NoError(t, err)
+	require.True(t, applied)
+}
+
+func TestMemoryStorageBatchKeysCapacity(t *testing.T) {
+	storage := memory.NewStorage()
+	ctx := context.Background()
+
+	for i := 0; i <= memory.DefaultBatchKeysCapacity; i++ {
+		applied, err := storage.AddMetricsOnce(ctx, fmt.Sprintf("batch-%d", i), nil)
+		require.NoError(t, err)
+		require.True(t, applied)
+	}
+
+	// самый старый ключ вытеснен, последний - хранится
+	applied, err := storage.AddMetricsOnce(ctx, "batch-0", nil)
+	require.NoError(t, err)
+	require.True(t, applied)
+
+	applied, err = storage.AddMetricsOnce(ctx, fmt.Sprintf("batch-%d", memory.DefaultBatchKeysCapacity), nil)
+	require.NoError(t, err)
+	require.False(t, applied)
+}
@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+// DefaultHistoryCapacity количество значений, хранимых для одной метрики.
+const DefaultHistoryCapacity = 1024
+
+// history хранит историю значений метрик; для каждой метрики - кольцевой буфер фиксированного размера.
+type history struct {
+	mu       sync.Mutex
+	capacity int
+	rings    map[string]*ring
+}
+
+func newHistory(capacity int) *history {
+	return &history{
+		capacity: capacity,
+		rings:    make(map[string]*ring),
+	}
+}
+
+// ring кольцевой буфер; при переполнении перезаписываются самые старые значения.
+type ring struct {
+	samples []domain.Sample
+	start   int // индекс самого старого значения
+	size    int
+}
+
+func newRing(capacity int) *ring {
+	return &ring{
+		samples: make([]domain.Sample, capacity),
+	}
+}
+
+func (r *ring) push(s domain.Sample) {
+	capacity := len(r.samples)
+	if r.size < capacity {
+		r.samples[(r.start+r.size)%capacity] = s
+		r.size++
+		return
+	}
+	r.samples[r.start] = s
+	r.start = (r.start + 1) % capacity
+}
+
+// at возвращает i-е значение, начиная с самого старого.
+func (r *ring) at(i int) domain.Sample {
+	return r.samples[(r.start+i)%len(r.samples)]
+}
+
+// dropBefore удаляет значения старше ts.
+func (r *ring) dropBefore(ts time.Time) {
+	for r.size > 0 && r.at(0).Timestamp.Before(ts) {
+		r.start = (r.start + 1) % len(r.samples)
+		r.size--
+	}
+}
+
+func historyKey(id string, labels domain.Labels, mType domain.MetricType) string {
+	return string(mType) + ":" + domain.MetricKey(id, labels)
+}
+
+func (st *storage) AppendSamples(ctx context.Context, ts time.Time, metrics []domain.Metrics) error {
+	st.history.mu.Lock()
+	defer st.history.mu.Unlock()
+
+	for _, m := range metrics {
+		key := historyKey(m.ID, m.Labels, m.MType)
+		r, ok := st.history.rings[key]
+		if !ok {
+			r = newRing(st.history.capacity)
+			st.history.rings[key] = r
+		}
+		r.push(domain.ToSample(&m, ts))
+	}
+	return nil
+}
+
+func (st *storage) GetSamples(ctx context.Context, id string, labels domain.Labels, mType domain.MetricType, from, to time.Time) ([]domain.Sample, error) {
+	st.history.mu.Lock()
+	defer st.history.mu.Unlock()
+
+	r, ok := st.history.rings[historyKey(id, labels, mType)]
+	if !ok {
+		return nil, nil
+	}
+
+	// значения добавляются в порядке возрастания времени
+	first := sort.Search(r.size, func(i int) bool {
+		return !r.at(i).Timestamp.Before(from)
+	})
+
+	var out []domain.Sample
+	for i := first; i < r.size; i++ {
+		s := r.at(i)
+		if s.Timestamp.After(to) {
+			break
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func (st *storage) DeleteSamplesBefore(ctx context.Context, ts time.Time) error {
+	st.history.mu.Lock()
+	defer st.history.mu.Unlock()
+
+	for key, r := range st.history.rings {
+		r.dropBefore(ts)
+		if r.size == 0 {
+			delete(st.history.rings, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/storage/memory"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStorageHistory(t *testing.T) {
+	storage := memory.NewStorage()
+	ctx := context.Background()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	labels := domain.Labels{"host": "h1"}
+
+	for i := 0; i < 10; i++ {
+		err := storage.AppendSamples(ctx, start.Add(time.Duration(i)*time.Second), []domain.Metrics{
+			{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(float64(i))},
+			{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(float64(100 + i)), Labels: labels},
+			{ID: "Alloc", MType: domain.CounterType, Delta: domain.DeltaPtr(int64(i))},
+		})
+		require.NoError(t, err)
+	}
+
+	samples, err := storage.GetSamples(ctx, "Alloc", nil, domain.GaugeType, start.Add(2*time.Second), start.Add(4*time.Second))
+	require.NoError(t, err)
+	require.Equal(t, []domain.Sample{
+		{Timestamp: start.Add(2 * time.Second), Value: 2},
+		{Timestamp: start.Add(3 * time.Second), Value: 3},
+		{Timestamp: start.Add(4 * time.Second), Value: 4},
+	}, samples)
+
+	samples, err = storage.GetSamples(ctx, "Alloc", labels, domain.GaugeType, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 10, len(samples))
+	require.Equal(t, float64(100), samples[0].Value)
+
+	samples, err = storage.GetSamples(ctx, "Alloc", nil, domain.CounterType, start.Add(9*time.Second), start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(samples))
+	require.Equal(t, float64(9), samples[0].Value)
+
+	samples, err = storage.GetSamples(ctx, "Unknown", nil, domain.GaugeType, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Nil(t, samples)
+
+	err = storage.DeleteSamplesBefore(ctx, start.Add(8*time.Second))
+	require.NoError(t, err)
+
+	samples, err = storage.GetSamples(ctx, "Alloc", nil, domain.GaugeType, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(samples))
+	require.Equal(t, float64(8), samples[0].Value)
+}
+
+func TestMemoryStorageHistoryOverflow(t *testing.T) {
+	storage := memory.NewStorage()
+	ctx := context.Background()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	total := memory.DefaultHistoryCapacity + 10
+
+	for i := 0; i < total; i++ {
+		err := storage.AppendSamples(ctx, start.Add(time.Duration(i)*time.Second), []domain.Metrics{
+			{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(float64(i))},
+		})
+		require.NoError(t, err)
+	}
+
+	samples, err := storage.GetSamples(ctx, "Alloc", nil, domain.GaugeType, start, start.Add(time.Duration(total)*time.Second))
+	require.NoError(t, err)
+	require.Equal(t, memory.DefaultHistoryCapacity, len(samples))
+	require.Equal(t, float64(10), samples[0].Value)
+	require.Equal(t, float64(total-1), samples[len(samples)-1].Value)
+}
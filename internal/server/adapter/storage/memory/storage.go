@@ -10,35 +10,54 @@ import (
 
 func NewStorage() *storage {
 	return &storage{
-		counterStorage: make(map[string]int64),
-		gaugeStorage:   make(map[string]float64),
+		counterStorage:   make(map[string]int64),
+		gaugeStorage:     make(map[string]float64),
+		histogramStorage: make(map[string]*domain.Histogram),
+		labelsStorage:    make(map[string]domain.Labels),
+		history:          newHistory(DefaultHistoryCapacity),
+		batches:          newBatchKeys(DefaultBatchKeysCapacity),
 	}
 }
 
+// storage хранит значения по ключу domain.MetricKey; метки ключа хранятся отдельно в labelsStorage
 type storage struct {
-	counterStorage map[string]int64
-	gaugeStorage   map[string]float64
+	counterStorage   map[string]int64
+	gaugeStorage     map[string]float64
+	histogramStorage map[string]*domain.Histogram
+	labelsStorage    map[string]domain.Labels
+	history          *history
+	batches          *batchKeys
 }
 
 func (st *storage) SetAllMetrics(ctx context.Context, in []domain.Metrics) error {
 	newCounterStorage := make(map[string]int64)
 	newGaugeStorage := make(map[string]float64)
+	newHistogramStorage := make(map[string]*domain.Histogram)
+	newLabelsStorage := make(map[string]domain.Labels)
 
 	for _, m := range in {
+		key := m.Key()
 		switch m.MType {
 		case domain.CounterType:
 			delta := *m.Delta
-			newCounterStorage[m.ID] = delta
+			newCounterStorage[key] = delta
 		case domain.GaugeType:
 			value := *m.Value
-			newGaugeStorage[m.ID] = value
+			newGaugeStorage[key] = value
+		case domain.HistogramType:
+			newHistogramStorage[key] = m.Histogram.Copy()
 		default:
 			return fmt.Errorf("unknown MType %v", m.MType)
 		}
+		if len(m.Labels) != 0 {
+			newLabelsStorage[key] = m.Labels.Copy()
+		}
 	}
 
 	st.counterStorage = newCounterStorage
 	st.gaugeStorage = newGaugeStorage
+	st.histogramStorage = newHistogramStorage
+	st.labelsStorage = newLabelsStorage
 	return nil
 }
 
@@ -49,18 +68,29 @@ func (st *storage) GetAllMetrics(ctx context.Context) ([]domain.Metrics, error)
 	for k, v := range st.counterStorage {
 		delta := v
 		out = append(out, domain.Metrics{
-			ID:    k,
-			MType: domain.CounterType,
-			Delta: &delta,
+			ID:     domain.MetricIDFromKey(k),
+			MType:  domain.CounterType,
+			Delta:  &delta,
+			Labels: st.labelsStorage[k].Copy(),
 		})
 	}
 
 	for k, v := range st.gaugeStorage {
 		value := v
 		out = append(out, domain.Metrics{
-			ID:    k,
-			MType: domain.GaugeType,
-			Value: &value,
+			ID:     domain.MetricIDFromKey(k),
+			MType:  domain.GaugeType,
+			Value:  &value,
+			Labels: st.labelsStorage[k].Copy(),
+		})
+	}
+
+	for k, v := range st.histogramStorage {
+		out = append(out, domain.Metrics{
+			ID:        domain.MetricIDFromKey(k),
+			MType:     domain.HistogramType,
+			Histogram: v.Copy(),
+			Labels:    st.labelsStorage[k].Copy(),
 		})
 	}
 
@@ -68,71 +98,97 @@ func (st *storage) GetAllMetrics(ctx context.Context) ([]domain.Metrics, error)
 }
 
 func (st *storage) Set(ctx context.Context, m *domain.Metrics) error {
+	key := m.Key()
 	switch m.MType {
 	case domain.CounterType:
 		delta := *m.Delta
-		st.counterStorage[m.ID] = delta
+		st.counterStorage[key] = delta
 	case domain.GaugeType:
 		value := *m.Value
-		st.gaugeStorage[m.ID] = value
+		st.gaugeStorage[key] = value
+	case domain.HistogramType:
+		st.histogramStorage[key] = m.Histogram.Copy()
 	default:
 		return fmt.Errorf("unknown MType %v", m.MType)
 	}
+	st.storeLabels(key, m.Labels)
 	return nil
 }
 
 func (st *storage) Add(ctx context.Context, m *domain.Metrics) error {
+	key := m.Key()
 	switch m.MType {
 	case domain.CounterType:
 		delta := *m.Delta
-		curValue, ok := st.counterStorage[m.ID]
+		curValue, ok := st.counterStorage[key]
 		if ok {
 			delta += curValue
-			st.counterStorage[m.ID] = delta
+			st.counterStorage[key] = delta
 			// обновляем значение для входной переменной
 			m.Delta = &delta
 		} else {
-			st.counterStorage[m.ID] = delta
+			st.counterStorage[key] = delta
 		}
 	case domain.GaugeType:
 		value := *m.Value
-		st.gaugeStorage[m.ID] = value
-		curValue, ok := st.gaugeStorage[m.ID]
+		st.gaugeStorage[key] = value
+		curValue, ok := st.gaugeStorage[key]
 		if ok {
 			curValue += value
-			st.gaugeStorage[m.ID] = curValue
+			st.gaugeStorage[key] = curValue
 			// обновляем значение для входной переменной
 			m.Value = &curValue
 		} else {
-			st.gaugeStorage[m.ID] = value
+			st.gaugeStorage[key] = value
 		}
+	case domain.HistogramType:
+		merged := domain.MergeHistogram(st.histogramStorage[key], m.Histogram)
+		st.histogramStorage[key] = merged
+		// обновляем значение для входной переменной
+		m.Histogram = merged.Copy()
 	default:
 		return fmt.Errorf("unknown MType %v", m.MType)
 	}
+	st.storeLabels(key, m.Labels)
 	return nil
 }
-func (st *storage) Get(ctx context.Context, id string, mType domain.MetricType) (*domain.Metrics, error) {
+func (st *storage) Get(ctx context.Context, id string, labels domain.Labels, mType domain.MetricType) (*domain.Metrics, error) {
+	key := domain.MetricKey(id, labels)
 	switch mType {
 	case domain.CounterType:
-		curValue, ok := st.counterStorage[id]
+		curValue, ok := st.counterStorage[key]
 		delta := curValue
 		if ok {
 			return &domain.Metrics{
-				ID:    id,
-				MType: mType,
-				Delta: &delta,
+				ID:     id,
+				MType:  mType,
+				Delta:  &delta,
+				Labels: labels.Copy(),
 			}, nil
 		} else {
 			return nil, nil
 		}
 	case domain.GaugeType:
-		curValue, ok := st.gaugeStorage[id]
+		curValue, ok := st.gaugeStorage[key]
 		value := curValue
 		if ok {
 			return &domain.Metrics{
-				ID:    id,
-				MType: mType,
-				Value: &value,
+				ID:     id,
+				MType:  mType,
+				Value:  &value,
+				Labels: labels.Copy(),
+			}, nil
+		} else {
+			return nil, nil
+		}
+	case domain.HistogramType:
+		curValue, ok := st.histogramStorage[key]
+		if ok {
+			return &domain.Metrics{
+				ID:        id,
+				MType:     mType,
+				Histogram: curValue.Copy(),
+				Labels:    labels.Copy(),
 			}, nil
 		} else {
 			return nil, nil
@@ -152,39 +208,48 @@ func (st *storage) Ping(ctx context.Context) error {
 
 func (st *storage) SetMetrics(ctx context.Context, metric []domain.Metrics) error {
 	for _, m := range metric {
+		key := m.Key()
 		switch m.MType {
 		case domain.GaugeType:
-			st.gaugeStorage[m.ID] = *m.Value
+			st.gaugeStorage[key] = *m.Value
 
 		case domain.CounterType:
-			st.counterStorage[m.ID] = *m.Delta
+			st.counterStorage[key] = *m.Delta
+
+		case domain.HistogramType:
+			st.histogramStorage[key] = m.Histogram.Copy()
 		}
+		st.storeLabels(key, m.Labels)
 	}
 	return nil
 }
 
 func (st *storage) AddMetrics(ctx context.Context, metric []domain.Metrics) error {
 	for _, m := range metric {
+		key := m.Key()
 		switch m.MType {
 		case domain.GaugeType:
 			value := *m.Value
-			curValue, ok := st.gaugeStorage[m.ID]
+			curValue, ok := st.gaugeStorage[key]
 			if ok {
 				curValue += value
-				st.gaugeStorage[m.ID] = curValue
+				st.gaugeStorage[key] = curValue
 			} else {
-				st.gaugeStorage[m.ID] = value
+				st.gaugeStorage[key] = value
 			}
 		case domain.CounterType:
 			delta := *m.Delta
-			curValue, ok := st.counterStorage[m.ID]
+			curValue, ok := st.counterStorage[key]
 			if ok {
 				delta += curValue
-				st.counterStorage[m.ID] = delta
+				st.counterStorage[key] = delta
 			} else {
-				st.counterStorage[m.ID] = delta
+				st.counterStorage[key] = delta
 			}
+		case domain.HistogramType:
+			st.histogramStorage[key] = domain.MergeHistogram(st.histogramStorage[key], m.Histogram)
 		}
+		st.storeLabels(key, m.Labels)
 	}
 	return nil
 }
@@ -192,3 +257,12 @@ func (st *storage) AddMetrics(ctx context.Context, metric []domain.Metrics) erro
 func (st *storage) Close(ctx context.Context) error {
 	return nil
 }
+
+func (st *storage) storeLabels(key string, labels domain.Labels) {
+	if len(labels) == 0 {
+		return
+	}
+	if _, ok := st.labelsStorage[key]; !ok {
+		st.labelsStorage[key] = labels.Copy()
+	}
+}
@@ -72,18 +72,18 @@ func TestMemoryStorageGaugeOperations(t *testing.T) {
 
 	ctx := context.Background()
 
-	ms, err := storage.Get(ctx, GagueID, domain.CounterType)
+	ms, err := storage.Get(ctx, GagueID, nil, domain.CounterType)
 	require.NoError(t, err)
 	require.Nil(t, ms)
 
-	ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+	ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 	require.NoError(t, err)
 	require.Nil(t, ms)
 
 	err = storage.Set(ctx, mConst)
 	require.NoError(t, err)
 
-	ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+	ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 	require.NoError(t, err)
 	require.NotNil(t, ms)
 	require.Equal(t, ms.ID, GagueID)
@@ -92,13 +92,13 @@ func TestMemoryStorageGaugeOperations(t *testing.T) {
 	require.Equal(t, float64(2), *ms.Value)
 	require.Nil(t, ms.Delta)
 
-	ms, err = storage.Get(ctx, GagueID, domain.CounterType)
+	ms, err = storage.Get(ctx, GagueID, nil, domain.CounterType)
 	require.NoError(t, err)
 	require.Nil(t, ms)
 
 	err = storage.Set(ctx, mConst)
 	require.NoError(t, err)
-	ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+	ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 	require.NoError(t, err)
 	require.NotNil(t, ms)
 	require.Equal(t, ms.ID, GagueID)
@@ -109,7 +109,7 @@ func TestMemoryStorageGaugeOperations(t *testing.T) {
 
 	err = storage.Add(ctx, mConst)
 	require.NoError(t, err)
-	ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+	ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 	require.NoError(t, err)
 	require.NotNil(t, ms)
 	require.Equal(t, ms.ID, GagueID)
@@ -133,18 +133,18 @@ func TestMemoryStorageCounterOperations(t *testing.T) {
 
 	ctx := context.Background()
 
-	ms, err := storage.Get(ctx, CounterID, domain.CounterType)
+	ms, err := storage.Get(ctx, CounterID, nil, domain.CounterType)
 	require.NoError(t, err)
 	require.Nil(t, ms)
 
-	ms, err = storage.Get(ctx, CounterID, domain.GaugeType)
+	ms, err = storage.Get(ctx, CounterID, nil, domain.GaugeType)
 	require.NoError(t, err)
 	require.Nil(t, ms)
 
 	err = storage.Set(ctx, mConst)
 	require.NoError(t, err)
 
-	ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+	ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 	require.NoError(t, err)
 	require.NotNil(t, ms)
 	require.Equal(t, ms.ID, CounterID)
@@ -153,13 +153,13 @@ func TestMemoryStorageCounterOperations(t *testing.T) {
 	require.Equal(t, int64(2), *ms.Delta)
 	require.Nil(t, ms.Value)
 
-	ms, err = storage.Get(ctx, CounterID, domain.GaugeType)
+	ms, err = storage.Get(ctx, CounterID, nil, domain.GaugeType)
 	require.NoError(t, err)
 	require.Nil(t, ms)
 
 	err = storage.Set(ctx, mConst)
 	require.NoError(t, err)
-	ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+	ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 	require.NoError(t, err)
 	require.NotNil(t, ms)
 	require.Equal(t, ms.ID, CounterID)
@@ -170,7 +170,7 @@ func TestMemoryStorageCounterOperations(t *testing.T) {
 
 	err = storage.Add(ctx, mConst)
 	require.NoError(t, err)
-	ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+	ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 	require.NoError(t, err)
 	require.NotNil(t, ms)
 	require.Equal(t, ms.ID, CounterID)
@@ -195,21 +195,21 @@ func TestAddMetrics(t *testing.T) {
 
 		ctx := context.Background()
 
-		ms, err := storage.Get(ctx, GagueID, domain.GaugeType)
+		ms, err := storage.Get(ctx, GagueID, nil, domain.GaugeType)
 		require.NoError(t, err)
 		require.Nil(t, ms)
 
 		err = storage.AddMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+		ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 		require.NoError(t, err)
 		require.Equal(t, *mConst.Value, *ms.Value)
 
 		err = storage.AddMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+		ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 		require.NoError(t, err)
 		require.Equal(t, 2**mConst.Value, *ms.Value)
 	})
@@ -227,21 +227,21 @@ func TestAddMetrics(t *testing.T) {
 
 		ctx := context.Background()
 
-		ms, err := storage.Get(ctx, CounterID, domain.CounterType)
+		ms, err := storage.Get(ctx, CounterID, nil, domain.CounterType)
 		require.NoError(t, err)
 		require.Nil(t, ms)
 
 		err = storage.AddMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+		ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 		require.NoError(t, err)
 		require.Equal(t, *mConst.Delta, *ms.Delta)
 
 		err = storage.AddMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+		ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 		require.NoError(t, err)
 		require.Equal(t, 2**mConst.Delta, *ms.Delta)
 	})
@@ -262,21 +262,21 @@ func TestSetMetrics(t *testing.T) {
 
 		ctx := context.Background()
 
-		ms, err := storage.Get(ctx, GagueID, domain.GaugeType)
+		ms, err := storage.Get(ctx, GagueID, nil, domain.GaugeType)
 		require.NoError(t, err)
 		require.Nil(t, ms)
 
 		err = storage.SetMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+		ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 		require.NoError(t, err)
 		require.Equal(t, *mConst.Value, *ms.Value)
 
 		err = storage.SetMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+		ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 		require.NoError(t, err)
 		require.Equal(t, *mConst.Value, *ms.Value)
 	})
@@ -294,22 +294,149 @@ func TestSetMetrics(t *testing.T) {
 
 		ctx := context.Background()
 
-		ms, err := storage.Get(ctx, CounterID, domain.CounterType)
+		ms, err := storage.Get(ctx, CounterID, nil, domain.CounterType)
 		require.NoError(t, err)
 		require.Nil(t, ms)
 
 		err = storage.SetMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+		ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 		require.NoError(t, err)
 		require.Equal(t, *mConst.Delta, *ms.Delta)
 
 		err = storage.SetMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+		ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 		require.NoError(t, err)
 		require.Equal(t, *mConst.Delta, *ms.Delta)
 	})
 }
+
+func TestMemoryStorageHistogramOperations(t *testing.T) {
+
+	storage := memory.NewStorage()
+
+	HistogramID := "GCPause"
+
+	mConst := &domain.Metrics{
+		ID:    HistogramID,
+		MType: domain.HistogramType,
+		Histogram: &domain.Histogram{
+			Bounds: []float64{1, 2},
+			Counts: []int64{1, 0, 1},
+			Sum:    3.5,
+			Count:  2,
+		},
+	}
+
+	ctx := context.Background()
+
+	ms, err := storage.Get(ctx, HistogramID, nil, domain.HistogramType)
+	require.NoError(t, err)
+	require.Nil(t, ms)
+
+	err = storage.Set(ctx, mConst)
+	require.NoError(t, err)
+
+	ms, err = storage.Get(ctx, HistogramID, nil, domain.HistogramType)
+	require.NoError(t, err)
+	require.NotNil(t, ms)
+	require.Equal(t, mConst.Histogram, ms.Histogram)
+	require.Nil(t, ms.Value)
+	require.Nil(t, ms.Delta)
+
+	err = storage.AddMetrics(ctx, []domain.Metrics{*mConst})
+	require.NoError(t, err)
+
+	ms, err = storage.Get(ctx, HistogramID, nil, domain.HistogramType)
+	require.NoError(t, err)
+	require.Equal(t, []int64{2, 0, 2}, ms.Histogram.Counts)
+	require.Equal(t, int64(4), ms.Histogram.Count)
+	require.Equal(t, float64(7), ms.Histogram.Sum)
+	require.Equal(t, []int64{1, 0, 1}, mConst.Histogram.Counts, "input must not be changed")
+
+	// изменение границ корзин сбрасывает накопленные данные
+	changed := &domain.Metrics{
+		ID:    HistogramID,
+		MType: domain.HistogramType,
+		Histogram: &domain.Histogram{
+			Bounds: []float64{5},
+			Counts: []int64{1, 0},
+			Sum:    4,
+			Count:  1,
+		},
+	}
+	err = storage.Add(ctx, changed)
+	require.NoError(t, err)
+
+	ms, err = storage.Get(ctx, HistogramID, nil, domain.HistogramType)
+	require.NoError(t, err)
+	require.Equal(t, changed.Histogram, ms.Histogram)
+
+	all, err := storage.GetAllMetrics(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(all))
+	require.Equal(t, domain.HistogramType, all[0].MType)
+}
+
+func TestMemoryStorageLabels(t *testing.T) {
+
+	storage := memory.NewStorage()
+
+	ctx := context.Background()
+
+	h1 := domain.Labels{"host": "h1"}
+	h2 := domain.Labels{"host": "h2"}
+
+	err := storage.SetMetrics(ctx, []domain.Metrics{
+		{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(1), Labels: h1},
+		{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(2), Labels: h2},
+		{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(3)},
+	})
+	require.NoError(t, err)
+
+	err = storage.AddMetrics(ctx, []domain.Metrics{
+		{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(1), Labels: h1},
+		{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(5), Labels: h2},
+		{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(1), Labels: domain.Labels{"host": "h1"}},
+	})
+	require.NoError(t, err)
+
+	ms, err := storage.Get(ctx, "Alloc", h1, domain.GaugeType)
+	require.NoError(t, err)
+	require.Equal(t, float64(1), *ms.Value)
+	require.Equal(t, h1, ms.Labels)
+
+	ms, err = storage.Get(ctx, "Alloc", h2, domain.GaugeType)
+	require.NoError(t, err)
+	require.Equal(t, float64(2), *ms.Value)
+
+	ms, err = storage.Get(ctx, "Alloc", nil, domain.GaugeType)
+	require.NoError(t, err)
+	require.Equal(t, float64(3), *ms.Value)
+	require.Nil(t, ms.Labels)
+
+	ms, err = storage.Get(ctx, "PollCount", h1, domain.CounterType)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), *ms.Delta)
+
+	ms, err = storage.Get(ctx, "PollCount", nil, domain.CounterType)
+	require.NoError(t, err)
+	require.Nil(t, ms)
+
+	all, err := storage.GetAllMetrics(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(all))
+
+	// восстановление из бэкапа сохраняет метки
+	restored := memory.NewStorage()
+	err = restored.SetAllMetrics(ctx, all)
+	require.NoError(t, err)
+
+	ms, err = restored.Get(ctx, "PollCount", h2, domain.CounterType)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), *ms.Delta)
+	require.Equal(t, h2, ms.Labels)
+}
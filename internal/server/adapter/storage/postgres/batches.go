@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+// AddMetricsOnce накапливает значения и сохраняет ключ пакета в одной транзакции.
+// Параллельная вставка того же ключа ожидает завершения первой транзакции и не применяет значения повторно.
+func (st *storage) AddMetricsOnce(ctx context.Context, key string, metric []domain.Metrics) (bool, error) {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO batches(key, applied_at) VALUES ($1, $2) ON CONFLICT(key) DO NOTHING", key, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if inserted == 0 {
+		return false, nil
+	}
+
+	if err := st.addMetricsTx(ctx, tx, metric); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (st *storage) DeleteBatchKeysBefore(ctx context.Context, ts time.Time) error {
+	_, err := st.db.ExecContext(ctx, "DELETE FROM batches WHERE applied_at < $1", ts)
+	return err
+}
@@ -0,0 +1,53 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/storage/postgres"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorageAddMetricsOnce(t *testing.T) {
+
+	ctx, cancelFN := context.WithCancel(context.Background())
+	defer cancelFN()
+	connString, err := postgresContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	storage := postgres.NewStorage(connString)
+	err = storage.Bootstrap(ctx)
+	require.NoError(t, err)
+
+	err = clear(ctx)
+	require.NoError(t, err)
+
+	batch := []domain.Metrics{
+		{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(5)},
+	}
+
+	applied, err := storage.AddMetricsOnce(ctx, "key1", batch)
+	require.NoError(t, err)
+	require.True(t, applied)
+
+	applied, err = storage.AddMetricsOnce(ctx, "key1", batch)
+	require.NoError(t, err)
+	require.False(t, applied)
+
+	m, err := storage.Get(ctx, "PollCount", nil, domain.CounterType)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), *m.Delta)
+
+	err = storage.DeleteBatchKeysBefore(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	applied, err = storage.AddMetricsOnce(ctx, "key1", batch)
+	require.NoError(t, err)
+	require.True(t, applied)
+
+	m, err = storage.Get(ctx, "PollCount", nil, domain.CounterType)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), *m.Delta)
+}
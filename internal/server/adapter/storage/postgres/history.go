@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+func (st *storage) AppendSamples(ctx context.Context, ts time.Time, metrics []domain.Metrics) error {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO samples(name, labels, type, ts, value) VALUES ($1, $2, $3, $4, $5)")
+	if err != nil {
+		return err
+	}
+
+	for _, m := range metrics {
+		sample := domain.ToSample(&m, ts)
+		if _, err := stmt.ExecContext(ctx, m.ID, labelsJSON(m.Labels), string(m.MType), sample.Timestamp, sample.Value); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (st *storage) GetSamples(ctx context.Context, id string, labels domain.Labels, mType domain.MetricType, from, to time.Time) ([]domain.Sample, error) {
+	logger := domain.GetCtxLogger(ctx)
+	action := domain.GetAction(1)
+
+	rows, err := st.db.QueryContext(ctx, `
+	SELECT ts, value FROM samples
+		WHERE name = $1 AND type = $2 AND labels = $3 AND ts >= $4 AND ts <= $5
+		ORDER BY ts`, id, string(mType), labelsJSON(labels), from, to)
+	if err != nil {
+		logger.Errorw(action, "status", "error", "msg", err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []domain.Sample
+	for rows.Next() {
+		var s domain.Sample
+		if err := rows.Scan(&s.Timestamp, &s.Value); err != nil {
+			logger.Errorw(action, "status", "error", "msg", err.Error())
+			return nil, err
+		}
+		samples = append(samples, s)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return samples, nil
+}
+
+func (st *storage) DeleteSamplesBefore(ctx context.Context, ts time.Time) error {
+	_, err := st.db.ExecContext(ctx, "DELETE FROM samples WHERE ts < $1", ts)
+	return err
+}
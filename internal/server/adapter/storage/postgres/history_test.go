@@ -0,0 +1,63 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/storage/postgres"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorageHistory(t *testing.T) {
+
+	ctx, cancelFN := context.WithCancel(context.Background())
+	defer cancelFN()
+	connString, err := postgresContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	storage := postgres.NewStorage(connString)
+	err = storage.Bootstrap(ctx)
+	require.NoError(t, err)
+
+	err = clear(ctx)
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	labels := domain.Labels{"host": "h1"}
+
+	for i := 0; i < 10; i++ {
+		err := storage.AppendSamples(ctx, start.Add(time.Duration(i)*time.Second), []domain.Metrics{
+			{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(float64(i))},
+			{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(float64(100 + i)), Labels: labels},
+			{ID: "Alloc", MType: domain.CounterType, Delta: domain.DeltaPtr(int64(i))},
+		})
+		require.NoError(t, err)
+	}
+
+	samples, err := storage.GetSamples(ctx, "Alloc", nil, domain.GaugeType, start.Add(2*time.Second), start.Add(4*time.Second))
+	require.NoError(t, err)
+	require.Equal(t, 3, len(samples))
+	require.True(t, start.Add(2*time.Second).Equal(samples[0].Timestamp))
+	require.Equal(t, float64(2), samples[0].Value)
+	require.Equal(t, float64(4), samples[2].Value)
+
+	samples, err = storage.GetSamples(ctx, "Alloc", labels, domain.GaugeType, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 10, len(samples))
+	require.Equal(t, float64(100), samples[0].Value)
+
+	samples, err = storage.GetSamples(ctx, "Alloc", nil, domain.CounterType, start.Add(9*time.Second), start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(samples))
+	require.Equal(t, float64(9), samples[0].Value)
+
+	err = storage.DeleteSamplesBefore(ctx, start.Add(8*time.Second))
+	require.NoError(t, err)
+
+	samples, err = storage.GetSamples(ctx, "Alloc", nil, domain.GaugeType, start, start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, len(samples))
+	require.Equal(t, float64(8), samples[0].Value)
+}
@@ -79,5 +79,8 @@ func clear(ctx context.Context) error {
 
 	tx.Exec(ctx, `DELETE FROM gauge`)
 	tx.Exec(ctx, `DELETE FROM counter`)
+	tx.Exec(ctx, `DELETE FROM histogram`)
+	tx.Exec(ctx, `DELETE FROM samples`)
+	tx.Exec(ctx, `DELETE FROM batches`)
 	return tx.Commit(ctx)
 }
@@ -4,6 +4,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -23,7 +24,7 @@ type storage struct {
 }
 
 func (st *storage) SetAllMetrics(ctx context.Context, in []domain.Metrics) error {
-	_, err := st.db.ExecContext(ctx, "TRUNCATE counter,gauge")
+	_, err := st.db.ExecContext(ctx, "TRUNCATE counter,gauge,histogram")
 
 	if err != nil {
 		return err
@@ -31,21 +32,31 @@ func (st *storage) SetAllMetrics(ctx context.Context, in []domain.Metrics) error
 
 	var gaugeList []gauge
 	var counterList []counter
+	var histogramList []histogram
 
 	for _, metrics := range in {
 		switch metrics.MType {
 		case domain.CounterType:
 			counter := counter{
-				name:  metrics.ID,
-				value: *metrics.Delta,
+				name:   metrics.ID,
+				labels: metrics.Labels,
+				value:  *metrics.Delta,
 			}
 			counterList = append(counterList, counter)
 		case domain.GaugeType:
 			gauge := gauge{
-				name:  metrics.ID,
-				value: *metrics.Value,
+				name:   metrics.ID,
+				labels: metrics.Labels,
+				value:  *metrics.Value,
 			}
 			gaugeList = append(gaugeList, gauge)
+		case domain.HistogramType:
+			histogram := histogram{
+				name:   metrics.ID,
+				labels: metrics.Labels,
+				value:  metrics.Histogram,
+			}
+			histogramList = append(histogramList, histogram)
 		default:
 			return fmt.Errorf("unknown MType %v", metrics.MType)
 		}
@@ -59,6 +70,10 @@ func (st *storage) SetAllMetrics(ctx context.Context, in []domain.Metrics) error
 		return err
 	}
 
+	if err := st.insertHistogramList(ctx, histogramList); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -72,9 +87,10 @@ func (st *storage) GetAllMetrics(ctx context.Context) ([]domain.Metrics, error)
 	for _, gauge := range gaugeList {
 		value := gauge.value
 		metricsList = append(metricsList, domain.Metrics{
-			ID:    gauge.name,
-			MType: domain.GaugeType,
-			Value: &value,
+			ID:     gauge.name,
+			MType:  domain.GaugeType,
+			Value:  &value,
+			Labels: gauge.labels,
 		})
 	}
 
@@ -86,9 +102,24 @@ func (st *storage) GetAllMetrics(ctx context.Context) ([]domain.Metrics, error)
 	for _, counter := range counterList {
 		delta := counter.value
 		metricsList = append(metricsList, domain.Metrics{
-			ID:    counter.name,
-			MType: domain.CounterType,
-			Delta: &delta,
+			ID:     counter.name,
+			MType:  domain.CounterType,
+			Delta:  &delta,
+			Labels: counter.labels,
+		})
+	}
+
+	histogramList, err := st.getAllHistogram(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, histogram := range histogramList {
+		metricsList = append(metricsList, domain.Metrics{
+			ID:        histogram.name,
+			MType:     domain.HistogramType,
+			Histogram: histogram.value,
+			Labels:    histogram.labels,
 		})
 	}
 
@@ -99,11 +130,18 @@ func (st *storage) Set(ctx context.Context, m *domain.Metrics) error {
 	switch m.MType {
 	case domain.CounterType:
 		delta := *m.Delta
-		_, err := st.db.ExecContext(ctx, "INSERT INTO counter(name, value) VALUES ($1, $2) ON CONFLICT(name) DO UPDATE SET value = EXCLUDED.value", m.ID, delta)
+		_, err := st.db.ExecContext(ctx, "INSERT INTO counter(name, labels, value) VALUES ($1, $2, $3) ON CONFLICT(name, labels) DO UPDATE SET value = EXCLUDED.value", m.ID, labelsJSON(m.Labels), delta)
 		return err
 	case domain.GaugeType:
 		value := *m.Value
-		_, err := st.db.ExecContext(ctx, "INSERT INTO gauge(name, value) VALUES ($1, $2) ON CONFLICT(name) DO UPDATE SET value = EXCLUDED.value", m.ID, value)
+		_, err := st.db.ExecContext(ctx, "INSERT INTO gauge(name, labels, value) VALUES ($1, $2, $3) ON CONFLICT(name, labels) DO UPDATE SET value = EXCLUDED.value", m.ID, labelsJSON(m.Labels), value)
+		return err
+	case domain.HistogramType:
+		data, err := json.Marshal(m.Histogram)
+		if err != nil {
+			return err
+		}
+		_, err = st.db.ExecContext(ctx, "INSERT INTO histogram(name, labels, value) VALUES ($1, $2, $3) ON CONFLICT(name, labels) DO UPDATE SET value = EXCLUDED.value", m.ID, labelsJSON(m.Labels), data)
 		return err
 	default:
 		return fmt.Errorf("unknown MType %v", m.MType)
@@ -114,24 +152,36 @@ func (st *storage) Add(ctx context.Context, m *domain.Metrics) error {
 	switch m.MType {
 	case domain.CounterType:
 		delta := *m.Delta
-		_, err := st.db.ExecContext(ctx, "INSERT INTO counter(name, value) VALUES ($1, $2) ON CONFLICT(name) DO UPDATE SET value = counter.value + EXCLUDED.value", m.ID, delta)
+		_, err := st.db.ExecContext(ctx, "INSERT INTO counter(name, labels, value) VALUES ($1, $2, $3) ON CONFLICT(name, labels) DO UPDATE SET value = counter.value + EXCLUDED.value", m.ID, labelsJSON(m.Labels), delta)
 		return err
 	case domain.GaugeType:
 		value := *m.Value
-		_, err := st.db.ExecContext(ctx, "INSERT INTO gauge(name, value) VALUES ($1, $2) ON CONFLICT(name) DO UPDATE SET value = gauge.value + EXCLUDED.value", m.ID, value)
+		_, err := st.db.ExecContext(ctx, "INSERT INTO gauge(name, labels, value) VALUES ($1, $2, $3) ON CONFLICT(name, labels) DO UPDATE SET value = gauge.value + EXCLUDED.value", m.ID, labelsJSON(m.Labels), value)
 		return err
+	case domain.HistogramType:
+		tx, err := st.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
 
+		if err := st.mergeHistogram(ctx, tx, m); err != nil {
+			return err
+		}
+		return tx.Commit()
 	default:
 		return fmt.Errorf("unknown MType %v", m.MType)
 	}
 }
 
-func (st *storage) Get(ctx context.Context, id string, mType domain.MetricType) (*domain.Metrics, error) {
+func (st *storage) Get(ctx context.Context, id string, labels domain.Labels, mType domain.MetricType) (*domain.Metrics, error) {
 	switch mType {
 	case domain.CounterType:
-		return st.getCounter(ctx, id)
+		return st.getCounter(ctx, id, labels)
 	case domain.GaugeType:
-		return st.getGauge(ctx, id)
+		return st.getGauge(ctx, id, labels)
+	case domain.HistogramType:
+		return st.getHistogram(ctx, id, labels)
 	default:
 		return nil, fmt.Errorf("unknown MType %v", mType)
 	}
@@ -153,17 +203,60 @@ func (st *storage) Bootstrap(ctx context.Context) error {
 		tx.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS counter(
 			name text not null,
+			labels jsonb not null default '{}',
 			value bigint,
-			PRIMARY KEY(name)
+			PRIMARY KEY(name, labels)
 		);`)
 
 		tx.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS gauge(
 			name text not null,
+			labels jsonb not null default '{}',
 			value double precision,
-			PRIMARY KEY(name)
+			PRIMARY KEY(name, labels)
+		);`)
+
+		tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS histogram(
+			name text not null,
+			labels jsonb not null default '{}',
+			value jsonb,
+			PRIMARY KEY(name, labels)
 		);`)
 
+		tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS samples(
+			name text not null,
+			labels jsonb not null default '{}',
+			type text not null,
+			ts timestamptz not null,
+			value double precision
+		);`)
+
+		tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS samples_name_type_ts_idx ON samples(name, type, ts);`)
+		tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS samples_ts_idx ON samples(ts);`)
+
+		tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS batches(
+			key text PRIMARY KEY,
+			applied_at timestamptz not null
+		);`)
+
+		tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS batches_applied_at_idx ON batches(applied_at);`)
+
+		// миграция таблиц, созданных до появления меток
+		for _, table := range []string{"counter", "gauge", "histogram"} {
+			tx.ExecContext(ctx, fmt.Sprintf(`
+			DO $$
+			BEGIN
+				IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = '%[1]s' AND column_name = 'labels') THEN
+					ALTER TABLE %[1]s ADD COLUMN labels jsonb not null default '{}';
+					ALTER TABLE %[1]s DROP CONSTRAINT %[1]s_pkey;
+					ALTER TABLE %[1]s ADD PRIMARY KEY (name, labels);
+				END IF;
+			END $$;`, table))
+		}
+
 		return tx.Commit()
 	}
 
@@ -195,14 +288,21 @@ func (st *storage) SetMetrics(ctx context.Context, metric []domain.Metrics) erro
 	defer tx.Rollback()
 
 	counterStmt, err := tx.PrepareContext(ctx, `
-	INSERT INTO counter(name, value) VALUES ($1, $2) ON CONFLICT(name) 
+	INSERT INTO counter(name, labels, value) VALUES ($1, $2, $3) ON CONFLICT(name, labels) 
 		DO UPDATE SET value = EXCLUDED.value`)
 	if err != nil {
 		return err
 	}
 
 	gaugeStmt, err := tx.PrepareContext(ctx, `
-	INSERT INTO gauge(name, value) VALUES ($1, $2) ON CONFLICT(name)
+	INSERT INTO gauge(name, labels, value) VALUES ($1, $2, $3) ON CONFLICT(name, labels)
+		DO UPDATE SET value = EXCLUDED.value`)
+	if err != nil {
+		return err
+	}
+
+	histogramStmt, err := tx.PrepareContext(ctx, `
+	INSERT INTO histogram(name, labels, value) VALUES ($1, $2, $3) ON CONFLICT(name, labels)
 		DO UPDATE SET value = EXCLUDED.value`)
 	if err != nil {
 		return err
@@ -211,12 +311,21 @@ func (st *storage) SetMetrics(ctx context.Context, metric []domain.Metrics) erro
 	for _, m := range metric {
 		switch m.MType {
 		case domain.CounterType:
-			_, err := counterStmt.ExecContext(ctx, m.ID, *m.Delta)
+			_, err := counterStmt.ExecContext(ctx, m.ID, labelsJSON(m.Labels), *m.Delta)
 			if err != nil {
 				return err
 			}
 		case domain.GaugeType:
-			_, err := gaugeStmt.ExecContext(ctx, m.ID, *m.Value)
+			_, err := gaugeStmt.ExecContext(ctx, m.ID, labelsJSON(m.Labels), *m.Value)
+			if err != nil {
+				return err
+			}
+		case domain.HistogramType:
+			data, err := json.Marshal(m.Histogram)
+			if err != nil {
+				return err
+			}
+			_, err = histogramStmt.ExecContext(ctx, m.ID, labelsJSON(m.Labels), data)
 			if err != nil {
 				return err
 			}
@@ -233,15 +342,24 @@ func (st *storage) AddMetrics(ctx context.Context, metric []domain.Metrics) erro
 	}
 	defer tx.Rollback()
 
+	if err := st.addMetricsTx(ctx, tx, metric); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// addMetricsTx накапливает значения в рамках транзакции tx
+func (st *storage) addMetricsTx(ctx context.Context, tx *sql.Tx, metric []domain.Metrics) error {
 	counterStmt, err := tx.PrepareContext(ctx, `
-	INSERT INTO counter(name, value) VALUES ($1, $2) ON CONFLICT(name) 
+	INSERT INTO counter(name, labels, value) VALUES ($1, $2, $3) ON CONFLICT(name, labels) 
 		DO UPDATE SET value = counter.value + EXCLUDED.value`)
 	if err != nil {
 		return err
 	}
 
 	gaugeStmt, err := tx.PrepareContext(ctx, `
-	INSERT INTO gauge(name, value) VALUES ($1, $2) ON CONFLICT(name)
+	INSERT INTO gauge(name, labels, value) VALUES ($1, $2, $3) ON CONFLICT(name, labels)
 		DO UPDATE SET value = gauge.value + EXCLUDED.value`)
 	if err != nil {
 		return err
@@ -250,19 +368,23 @@ func (st *storage) AddMetrics(ctx context.Context, metric []domain.Metrics) erro
 	for _, m := range metric {
 		switch m.MType {
 		case domain.CounterType:
-			_, err := counterStmt.ExecContext(ctx, m.ID, *m.Delta)
+			_, err := counterStmt.ExecContext(ctx, m.ID, labelsJSON(m.Labels), *m.Delta)
 			if err != nil {
 				return err
 			}
 		case domain.GaugeType:
-			_, err := gaugeStmt.ExecContext(ctx, m.ID, *m.Value)
+			_, err := gaugeStmt.ExecContext(ctx, m.ID, labelsJSON(m.Labels), *m.Value)
 			if err != nil {
 				return err
 			}
+		case domain.HistogramType:
+			if err := st.mergeHistogram(ctx, tx, &m); err != nil {
+				return err
+			}
 		}
 	}
 
-	return tx.Commit()
+	return nil
 }
 
 func (st *storage) insertCounterList(ctx context.Context, counterList []counter) error {
@@ -274,15 +396,16 @@ func (st *storage) insertCounterList(ctx context.Context, counterList []counter)
 	// Попытка реализовать bulk insert средствами database/sql
 	// https://stackoverflow.com/questions/12486436/how-do-i-batch-sql-statements-with-package-database-sql
 	valueStrings := make([]string, 0, len(counterList))
-	valueArgs := make([]interface{}, 0, len(counterList)*2)
+	valueArgs := make([]interface{}, 0, len(counterList)*3)
 
 	for i, counter := range counterList {
-		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d)", i*2+1, i*2+2))
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d)", i*3+1, i*3+2, i*3+3))
 		valueArgs = append(valueArgs, counter.name)
+		valueArgs = append(valueArgs, labelsJSON(counter.labels))
 		valueArgs = append(valueArgs, counter.value)
 	}
 
-	sqlQuery := fmt.Sprintf("INSERT INTO counter (name, value) VALUES %s", strings.Join(valueStrings, ","))
+	sqlQuery := fmt.Sprintf("INSERT INTO counter (name, labels, value) VALUES %s", strings.Join(valueStrings, ","))
 
 	_, err := st.db.ExecContext(ctx, sqlQuery, valueArgs...)
 	return err
@@ -295,24 +418,92 @@ func (st *storage) insertGaugeList(ctx context.Context, gaugeList []gauge) error
 	}
 
 	valueStrings := make([]string, 0, len(gaugeList))
-	valueArgs := make([]interface{}, 0, len(gaugeList)*2)
+	valueArgs := make([]interface{}, 0, len(gaugeList)*3)
 
 	for i, gauge := range gaugeList {
-		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d)", i*2+1, i*2+2))
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d)", i*3+1, i*3+2, i*3+3))
 		valueArgs = append(valueArgs, gauge.name)
+		valueArgs = append(valueArgs, labelsJSON(gauge.labels))
 		valueArgs = append(valueArgs, gauge.value)
 	}
 
-	sqlQuery := fmt.Sprintf("INSERT INTO gauge (name, value) VALUES %s", strings.Join(valueStrings, ","))
+	sqlQuery := fmt.Sprintf("INSERT INTO gauge (name, labels, value) VALUES %s", strings.Join(valueStrings, ","))
 
 	_, err := st.db.ExecContext(ctx, sqlQuery, valueArgs...)
 	return err
 }
 
+func (st *storage) insertHistogramList(ctx context.Context, histogramList []histogram) error {
+
+	if len(histogramList) == 0 {
+		return nil
+	}
+
+	valueStrings := make([]string, 0, len(histogramList))
+	valueArgs := make([]interface{}, 0, len(histogramList)*3)
+
+	for i, histogram := range histogramList {
+		data, err := json.Marshal(histogram.value)
+		if err != nil {
+			return err
+		}
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d)", i*3+1, i*3+2, i*3+3))
+		valueArgs = append(valueArgs, histogram.name)
+		valueArgs = append(valueArgs, labelsJSON(histogram.labels))
+		valueArgs = append(valueArgs, data)
+	}
+
+	sqlQuery := fmt.Sprintf("INSERT INTO histogram (name, labels, value) VALUES %s", strings.Join(valueStrings, ","))
+
+	_, err := st.db.ExecContext(ctx, sqlQuery, valueArgs...)
+	return err
+}
+
+// mergeHistogram сливает гистограмму с сохраненным значением; строка блокируется до завершения транзакции.
+// Новая строка вставляется через ON CONFLICT DO NOTHING: при одновременной первой записи вторая транзакция
+// дожидается первой и сливает свои данные с уже сохраненными.
+func (st *storage) mergeHistogram(ctx context.Context, tx *sql.Tx, m *domain.Metrics) error {
+	data, err := json.Marshal(m.Histogram)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+	INSERT INTO histogram(name, labels, value) VALUES ($1, $2, $3) ON CONFLICT(name, labels) DO NOTHING`,
+		m.ID, labelsJSON(m.Labels), data)
+	if err != nil {
+		return err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted > 0 {
+		return nil
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT value from histogram WHERE name = $1 AND labels = $2 FOR UPDATE", m.ID, labelsJSON(m.Labels)).Scan(&data)
+	if err != nil {
+		return err
+	}
+	cur := &domain.Histogram{}
+	if err := json.Unmarshal(data, cur); err != nil {
+		return err
+	}
+
+	data, err = json.Marshal(domain.MergeHistogram(cur, m.Histogram))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE histogram SET value = $3 WHERE name = $1 AND labels = $2", m.ID, labelsJSON(m.Labels), data)
+	return err
+}
+
 func (st *storage) getAllCounter(ctx context.Context) ([]counter, error) {
 	var counterList []counter
 
-	rows, err := st.db.QueryContext(ctx, "SELECT name, value from counter")
+	rows, err := st.db.QueryContext(ctx, "SELECT name, labels, value from counter")
 	if err != nil {
 		return nil, err
 	}
@@ -320,10 +511,14 @@ func (st *storage) getAllCounter(ctx context.Context) ([]counter, error) {
 
 	for rows.Next() {
 		var c counter
-		err = rows.Scan(&c.name, &c.value)
+		var labels []byte
+		err = rows.Scan(&c.name, &labels, &c.value)
 		if err != nil {
 			return nil, err
 		}
+		if c.labels, err = parseLabels(labels); err != nil {
+			return nil, err
+		}
 		counterList = append(counterList, c)
 	}
 
@@ -339,7 +534,7 @@ func (st *storage) getAllGauge(ctx context.Context) ([]gauge, error) {
 
 	var gaugeList []gauge
 
-	rows, err := st.db.QueryContext(ctx, "SELECT name, value from gauge")
+	rows, err := st.db.QueryContext(ctx, "SELECT name, labels, value from gauge")
 	if err != nil {
 		return nil, err
 	}
@@ -347,10 +542,14 @@ func (st *storage) getAllGauge(ctx context.Context) ([]gauge, error) {
 
 	for rows.Next() {
 		var c gauge
-		err = rows.Scan(&c.name, &c.value)
+		var labels []byte
+		err = rows.Scan(&c.name, &labels, &c.value)
 		if err != nil {
 			return nil, err
 		}
+		if c.labels, err = parseLabels(labels); err != nil {
+			return nil, err
+		}
 		gaugeList = append(gaugeList, c)
 	}
 
@@ -362,11 +561,50 @@ func (st *storage) getAllGauge(ctx context.Context) ([]gauge, error) {
 	return gaugeList, nil
 }
 
-func (st *storage) getCounter(ctx context.Context, id string) (*domain.Metrics, error) {
+func (st *storage) getAllHistogram(ctx context.Context) ([]histogram, error) {
+
+	var histogramList []histogram
+
+	rows, err := st.db.QueryContext(ctx, "SELECT name, labels, value from histogram")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var labels, data []byte
+		err = rows.Scan(&name, &labels, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		h := histogram{
+			name:  name,
+			value: &domain.Histogram{},
+		}
+		if h.labels, err = parseLabels(labels); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, h.value); err != nil {
+			return nil, err
+		}
+		histogramList = append(histogramList, h)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return histogramList, nil
+}
+
+func (st *storage) getCounter(ctx context.Context, id string, labels domain.Labels) (*domain.Metrics, error) {
 	logger := domain.GetCtxLogger(ctx)
 	action := domain.GetAction(1)
 
-	rows, err := st.db.QueryContext(ctx, "SELECT name, value from counter WHERE name = $1", id)
+	rows, err := st.db.QueryContext(ctx, "SELECT name, value from counter WHERE name = $1 AND labels = $2", id, labelsJSON(labels))
 	if err != nil {
 		logger.Errorw(action, "status", "error", "msg", err.Error())
 		return nil, err
@@ -383,9 +621,10 @@ func (st *storage) getCounter(ctx context.Context, id string) (*domain.Metrics,
 		}
 
 		return &domain.Metrics{
-			ID:    name,
-			MType: domain.CounterType,
-			Delta: &delta,
+			ID:     name,
+			MType:  domain.CounterType,
+			Delta:  &delta,
+			Labels: labels.Copy(),
 		}, nil
 	}
 
@@ -397,11 +636,11 @@ func (st *storage) getCounter(ctx context.Context, id string) (*domain.Metrics,
 	return nil, nil
 }
 
-func (st *storage) getGauge(ctx context.Context, id string) (*domain.Metrics, error) {
+func (st *storage) getGauge(ctx context.Context, id string, labels domain.Labels) (*domain.Metrics, error) {
 	logger := domain.GetCtxLogger(ctx)
 	action := domain.GetAction(1)
 
-	rows, err := st.db.QueryContext(ctx, "SELECT name, value from gauge WHERE name = $1", id)
+	rows, err := st.db.QueryContext(ctx, "SELECT name, value from gauge WHERE name = $1 AND labels = $2", id, labelsJSON(labels))
 	if err != nil {
 		logger.Errorw(action, "status", "error", "msg", err.Error())
 		return nil, err
@@ -418,9 +657,52 @@ func (st *storage) getGauge(ctx context.Context, id string) (*domain.Metrics, er
 		}
 
 		return &domain.Metrics{
-			ID:    name,
-			MType: domain.GaugeType,
-			Value: &value,
+			ID:     name,
+			MType:  domain.GaugeType,
+			Value:  &value,
+			Labels: labels.Copy(),
+		}, nil
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (st *storage) getHistogram(ctx context.Context, id string, labels domain.Labels) (*domain.Metrics, error) {
+	logger := domain.GetCtxLogger(ctx)
+	action := domain.GetAction(1)
+
+	rows, err := st.db.QueryContext(ctx, "SELECT name, value from histogram WHERE name = $1 AND labels = $2", id, labelsJSON(labels))
+	if err != nil {
+		logger.Errorw(action, "status", "error", "msg", err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var name string
+		var data []byte
+		err = rows.Scan(&name, &data)
+		if err != nil {
+			logger.Errorw(action, "status", "error", "msg", err.Error())
+			return nil, err
+		}
+
+		var value domain.Histogram
+		if err := json.Unmarshal(data, &value); err != nil {
+			logger.Errorw(action, "status", "error", "msg", err.Error())
+			return nil, err
+		}
+
+		return &domain.Metrics{
+			ID:        name,
+			MType:     domain.HistogramType,
+			Histogram: &value,
+			Labels:    labels.Copy(),
 		}, nil
 	}
 
@@ -432,12 +714,37 @@ func (st *storage) getGauge(ctx context.Context, id string) (*domain.Metrics, er
 	return nil, nil
 }
 
+// labelsJSON возвращает метки в формате jsonb; пустые метки хранятся как '{}'
+func labelsJSON(labels domain.Labels) []byte {
+	if len(labels) == 0 {
+		return []byte("{}")
+	}
+	data, _ := json.Marshal(labels) // map[string]string всегда сериализуется
+	return data
+}
+
+func parseLabels(data []byte) (domain.Labels, error) {
+	var labels domain.Labels
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, err
+	}
+	return labels.Copy(), nil
+}
+
 type gauge struct {
-	name  string
-	value float64
+	name   string
+	labels domain.Labels
+	value  float64
 }
 
 type counter struct {
-	name  string
-	value int64
+	name   string
+	labels domain.Labels
+	value  int64
+}
+
+type histogram struct {
+	name   string
+	labels domain.Labels
+	value  *domain.Histogram
 }
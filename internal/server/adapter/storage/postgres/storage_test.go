@@ -2,6 +2,7 @@ package postgres_test
 
 import (
 	"context"
+	"sync"
 	"testing"
 
 	"github.com/StasMerzlyakov/go-metrics/internal/server/adapter/storage/postgres"
@@ -91,18 +92,18 @@ func TestPostgresStorageGaugeOperations(t *testing.T) {
 		Value: domain.ValuePtr(2),
 	}
 
-	ms, err := storage.Get(ctx, GagueID, domain.CounterType)
+	ms, err := storage.Get(ctx, GagueID, nil, domain.CounterType)
 	require.NoError(t, err)
 	require.Nil(t, ms)
 
-	ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+	ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 	require.NoError(t, err)
 	require.Nil(t, ms)
 
 	err = storage.Set(ctx, mConst)
 	require.NoError(t, err)
 
-	ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+	ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 	require.NoError(t, err)
 	require.NotNil(t, ms)
 	require.Equal(t, ms.ID, GagueID)
@@ -111,13 +112,13 @@ func TestPostgresStorageGaugeOperations(t *testing.T) {
 	require.Equal(t, float64(2), *ms.Value)
 	require.Nil(t, ms.Delta)
 
-	ms, err = storage.Get(ctx, GagueID, domain.CounterType)
+	ms, err = storage.Get(ctx, GagueID, nil, domain.CounterType)
 	require.NoError(t, err)
 	require.Nil(t, ms)
 
 	err = storage.Set(ctx, mConst)
 	require.NoError(t, err)
-	ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+	ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 	require.NoError(t, err)
 	require.NotNil(t, ms)
 	require.Equal(t, ms.ID, GagueID)
@@ -128,7 +129,7 @@ func TestPostgresStorageGaugeOperations(t *testing.T) {
 
 	err = storage.Add(ctx, mConst)
 	require.NoError(t, err)
-	ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+	ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 	require.NoError(t, err)
 	require.NotNil(t, ms)
 	require.Equal(t, ms.ID, GagueID)
@@ -160,18 +161,18 @@ func TestMemoryStorageCounterOperations(t *testing.T) {
 		Delta: domain.DeltaPtr(2),
 	}
 
-	ms, err := storage.Get(ctx, CounterID, domain.CounterType)
+	ms, err := storage.Get(ctx, CounterID, nil, domain.CounterType)
 	require.NoError(t, err)
 	require.Nil(t, ms)
 
-	ms, err = storage.Get(ctx, CounterID, domain.GaugeType)
+	ms, err = storage.Get(ctx, CounterID, nil, domain.GaugeType)
 	require.NoError(t, err)
 	require.Nil(t, ms)
 
 	err = storage.Set(ctx, mConst)
 	require.NoError(t, err)
 
-	ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+	ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 	require.NoError(t, err)
 	require.NotNil(t, ms)
 	require.Equal(t, ms.ID, CounterID)
@@ -180,13 +181,13 @@ func TestMemoryStorageCounterOperations(t *testing.T) {
 	require.Equal(t, int64(2), *ms.Delta)
 	require.Nil(t, ms.Value)
 
-	ms, err = storage.Get(ctx, CounterID, domain.GaugeType)
+	ms, err = storage.Get(ctx, CounterID, nil, domain.GaugeType)
 	require.NoError(t, err)
 	require.Nil(t, ms)
 
 	err = storage.Set(ctx, mConst)
 	require.NoError(t, err)
-	ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+	ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 	require.NoError(t, err)
 	require.NotNil(t, ms)
 	require.Equal(t, ms.ID, CounterID)
@@ -197,7 +198,7 @@ func TestMemoryStorageCounterOperations(t *testing.T) {
 
 	err = storage.Add(ctx, mConst)
 	require.NoError(t, err)
-	ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+	ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 	require.NoError(t, err)
 	require.NotNil(t, ms)
 	require.Equal(t, ms.ID, CounterID)
@@ -207,6 +208,157 @@ func TestMemoryStorageCounterOperations(t *testing.T) {
 	require.Nil(t, ms.Value)
 }
 
+func TestPostgresStorageHistogramOperations(t *testing.T) {
+
+	ctx, cancelFN := context.WithCancel(context.Background())
+	defer cancelFN()
+	connString, err := postgresContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	storage := postgres.NewStorage(connString)
+	err = storage.Bootstrap(ctx)
+	require.NoError(t, err)
+
+	err = clear(ctx)
+	require.NoError(t, err)
+
+	HistogramID := "GCPause"
+
+	mConst := &domain.Metrics{
+		ID:    HistogramID,
+		MType: domain.HistogramType,
+		Histogram: &domain.Histogram{
+			Bounds: []float64{1, 2},
+			Counts: []int64{1, 0, 1},
+			Sum:    3.5,
+			Count:  2,
+		},
+	}
+
+	ms, err := storage.Get(ctx, HistogramID, nil, domain.HistogramType)
+	require.NoError(t, err)
+	require.Nil(t, ms)
+
+	err = storage.Set(ctx, mConst)
+	require.NoError(t, err)
+
+	ms, err = storage.Get(ctx, HistogramID, nil, domain.HistogramType)
+	require.NoError(t, err)
+	require.NotNil(t, ms)
+	require.Equal(t, mConst.Histogram, ms.Histogram)
+
+	err = storage.Add(ctx, mConst)
+	require.NoError(t, err)
+
+	err = storage.AddMetrics(ctx, []domain.Metrics{*mConst})
+	require.NoError(t, err)
+
+	ms, err = storage.Get(ctx, HistogramID, nil, domain.HistogramType)
+	require.NoError(t, err)
+	require.NotNil(t, ms)
+	require.Equal(t, []int64{3, 0, 3}, ms.Histogram.Counts)
+	require.Equal(t, int64(6), ms.Histogram.Count)
+	require.Equal(t, 10.5, ms.Histogram.Sum)
+	require.Nil(t, ms.Value)
+	require.Nil(t, ms.Delta)
+}
+
+func TestPostgresStorageHistogramConcurrentAdd(t *testing.T) {
+
+	ctx, cancelFN := context.WithCancel(context.Background())
+	defer cancelFN()
+	connString, err := postgresContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	storage := postgres.NewStorage(connString)
+	err = storage.Bootstrap(ctx)
+	require.NoError(t, err)
+
+	err = clear(ctx)
+	require.NoError(t, err)
+
+	m := &domain.Metrics{
+		ID:    "ConcurrentPause",
+		MType: domain.HistogramType,
+		Histogram: &domain.Histogram{
+			Bounds: []float64{1},
+			Counts: []int64{1, 0},
+			Sum:    0.5,
+			Count:  1,
+		},
+	}
+
+	// первые записи одного ряда не должны затирать друг друга
+	const writers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- storage.Add(ctx, m)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	ms, err := storage.Get(ctx, m.ID, nil, domain.HistogramType)
+	require.NoError(t, err)
+	require.NotNil(t, ms)
+	require.Equal(t, int64(writers), ms.Histogram.Count)
+	require.Equal(t, []int64{writers, 0}, ms.Histogram.Counts)
+}
+
+func TestPostgresStorageLabels(t *testing.T) {
+
+	ctx, cancelFN := context.WithCancel(context.Background())
+	defer cancelFN()
+	connString, err := postgresContainer.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	storage := postgres.NewStorage(connString)
+	err = storage.Bootstrap(ctx)
+	require.NoError(t, err)
+
+	err = clear(ctx)
+	require.NoError(t, err)
+
+	h1 := domain.Labels{"host": "h1", "zone": "eu"}
+	h2 := domain.Labels{"host": "h2"}
+
+	err = storage.SetMetrics(ctx, []domain.Metrics{
+		{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(1), Labels: h1},
+		{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(2), Labels: h2},
+		{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(3)},
+	})
+	require.NoError(t, err)
+
+	err = storage.AddMetrics(ctx, []domain.Metrics{
+		{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(1), Labels: h1},
+		{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(1), Labels: domain.Labels{"zone": "eu", "host": "h1"}},
+	})
+	require.NoError(t, err)
+
+	ms, err := storage.Get(ctx, "Alloc", h1, domain.GaugeType)
+	require.NoError(t, err)
+	require.Equal(t, float64(1), *ms.Value)
+
+	ms, err = storage.Get(ctx, "Alloc", nil, domain.GaugeType)
+	require.NoError(t, err)
+	require.Equal(t, float64(3), *ms.Value)
+
+	ms, err = storage.Get(ctx, "PollCount", h1, domain.CounterType)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), *ms.Delta)
+
+	all, err := storage.GetAllMetrics(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 4, len(all))
+}
+
 func TestAddMetrics(t *testing.T) {
 
 	t.Run("gague", func(t *testing.T) {
@@ -230,21 +382,21 @@ func TestAddMetrics(t *testing.T) {
 			Value: domain.ValuePtr(2),
 		}
 
-		ms, err := storage.Get(ctx, GagueID, domain.GaugeType)
+		ms, err := storage.Get(ctx, GagueID, nil, domain.GaugeType)
 		require.NoError(t, err)
 		require.Nil(t, ms)
 
 		err = storage.AddMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+		ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 		require.NoError(t, err)
 		require.Equal(t, *mConst.Value, *ms.Value)
 
 		err = storage.AddMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+		ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 		require.NoError(t, err)
 		require.Equal(t, 2**mConst.Value, *ms.Value)
 	})
@@ -270,21 +422,21 @@ func TestAddMetrics(t *testing.T) {
 			Delta: domain.DeltaPtr(2),
 		}
 
-		ms, err := storage.Get(ctx, CounterID, domain.CounterType)
+		ms, err := storage.Get(ctx, CounterID, nil, domain.CounterType)
 		require.NoError(t, err)
 		require.Nil(t, ms)
 
 		err = storage.AddMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+		ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 		require.NoError(t, err)
 		require.Equal(t, *mConst.Delta, *ms.Delta)
 
 		err = storage.AddMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+		ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 		require.NoError(t, err)
 		require.Equal(t, 2**mConst.Delta, *ms.Delta)
 	})
@@ -313,21 +465,21 @@ func TestSetMetrics(t *testing.T) {
 			Value: domain.ValuePtr(2),
 		}
 
-		ms, err := storage.Get(ctx, GagueID, domain.GaugeType)
+		ms, err := storage.Get(ctx, GagueID, nil, domain.GaugeType)
 		require.NoError(t, err)
 		require.Nil(t, ms)
 
 		err = storage.SetMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+		ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 		require.NoError(t, err)
 		require.Equal(t, *mConst.Value, *ms.Value)
 
 		err = storage.SetMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, GagueID, domain.GaugeType)
+		ms, err = storage.Get(ctx, GagueID, nil, domain.GaugeType)
 		require.NoError(t, err)
 		require.Equal(t, *mConst.Value, *ms.Value)
 	})
@@ -353,21 +505,21 @@ func TestSetMetrics(t *testing.T) {
 			Delta: domain.DeltaPtr(2),
 		}
 
-		ms, err := storage.Get(ctx, CounterID, domain.CounterType)
+		ms, err := storage.Get(ctx, CounterID, nil, domain.CounterType)
 		require.NoError(t, err)
 		require.Nil(t, ms)
 
 		err = storage.SetMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+		ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 		require.NoError(t, err)
 		require.Equal(t, *mConst.Delta, *ms.Delta)
 
 		err = storage.SetMetrics(ctx, []domain.Metrics{*mConst})
 		require.NoError(t, err)
 
-		ms, err = storage.Get(ctx, CounterID, domain.CounterType)
+		ms, err = storage.Get(ctx, CounterID, nil, domain.CounterType)
 		require.NoError(t, err)
 		require.Equal(t, *mConst.Delta, *ms.Delta)
 	})
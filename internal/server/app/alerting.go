@@ -0,0 +1,234 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+func NewAlerting(storage AllMetricsStorage, reader AlertRulesReader) *alertingUseCase {
+	return &alertingUseCase{
+		storage: storage,
+		reader:  reader,
+		alerts:  make(map[string]*domain.Alert),
+		now:     time.Now,
+	}
+}
+
+// alertingUseCase периодически проверяет правила оповещения по текущим значениям метрик.
+//
+// Жизненный цикл оповещения: условие выполнилось - pending; условие выполняется дольше For - firing;
+// условие перестало выполняться - pending удаляется, firing переходит в resolved и удаляется при следующей проверке.
+type alertingUseCase struct {
+	storage   AllMetricsStorage
+	reader    AlertRulesReader
+	mu        sync.Mutex
+	rules     []domain.AlertRule
+	alerts    map[string]*domain.Alert // ключ - имя правила и ключ метрики
+	listeners []domain.AlertListener
+	now       func() time.Time
+}
+
+// AddListener добавляет обработчик изменения состояния оповещений.
+func (al *alertingUseCase) AddListener(listener domain.AlertListener) {
+	al.listeners = append(al.listeners, listener)
+}
+
+// LoadRules читает и проверяет правила; текущие оповещения сбрасываются.
+func (al *alertingUseCase) LoadRules(ctx context.Context) error {
+	rules, err := al.reader.Read(ctx)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]struct{}, len(rules))
+	for i := range rules {
+		if err := al.CheckRule(&rules[i]); err != nil {
+			return err
+		}
+		if _, ok := names[rules[i].Name]; ok {
+			return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("duplicate rule name %v", rules[i].Name))
+		}
+		names[rules[i].Name] = struct{}{}
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.rules = rules
+	al.alerts = make(map[string]*domain.Alert)
+	return nil
+}
+
+func (al *alertingUseCase) CheckRule(rule *domain.AlertRule) error {
+	if rule.Name == "" {
+		return errors.Wrap(domain.ErrDataFormat, "rule name is empty")
+	}
+
+	if !nameRegexp.MatchString(rule.Metric) {
+		return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("rule %v: wrong metric ID %v", rule.Name, rule.Metric))
+	}
+
+	if rule.MType != domain.GaugeType && rule.MType != domain.CounterType {
+		return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("rule %v: unsupported metric type %v", rule.Name, rule.MType))
+	}
+
+	for k := range rule.Labels {
+		if !labelNameRegexp.MatchString(k) {
+			return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("rule %v: wrong label name %v", rule.Name, k))
+		}
+	}
+
+	if _, err := rule.Op.Compare(0, 0); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("rule %v", rule.Name))
+	}
+
+	if (rule.Threshold == nil) == (rule.CompareTo == "") {
+		return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("rule %v: exactly one of threshold or compare_to must be set", rule.Name))
+	}
+
+	if rule.CompareTo != "" && !nameRegexp.MatchString(rule.CompareTo) {
+		return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("rule %v: wrong metric ID %v", rule.Name, rule.CompareTo))
+	}
+
+	if rule.For < 0 {
+		return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("rule %v: negative for duration", rule.Name))
+	}
+
+	return nil
+}
+
+// Evaluate проверяет все правила и обновляет состояние оповещений.
+func (al *alertingUseCase) Evaluate(ctx context.Context) error {
+	metrics, err := al.storage.GetAllMetrics(ctx)
+	if err != nil {
+		return err
+	}
+
+	index := make(map[string]*domain.Metrics, len(metrics))
+	for i := range metrics {
+		index[alertMetricKey(metrics[i].ID, metrics[i].Labels, metrics[i].MType)] = &metrics[i]
+	}
+
+	al.mu.Lock()
+
+	now := al.now()
+	active := make(map[string]struct{})
+	var changed []domain.Alert
+
+	for _, rule := range al.rules {
+		for i := range metrics {
+			m := &metrics[i]
+			if m.ID != rule.Metric || m.MType != rule.MType || !m.Labels.Match(rule.Labels) {
+				continue
+			}
+
+			value := alertMetricValue(m)
+
+			var threshold float64
+			if rule.Threshold != nil {
+				threshold = *rule.Threshold
+			} else {
+				other, ok := index[alertMetricKey(rule.CompareTo, m.Labels, m.MType)]
+				if !ok {
+					continue
+				}
+				threshold = alertMetricValue(other)
+			}
+
+			if ok, _ := rule.Op.Compare(value, threshold); !ok {
+				continue
+			}
+
+			key := rule.Name + ":" + m.Key()
+			active[key] = struct{}{}
+
+			alert, ok := al.alerts[key]
+			if !ok || alert.State == domain.AlertResolved {
+				alert = &domain.Alert{
+					Rule:     rule.Name,
+					Metric:   m.ID,
+					MType:    m.MType,
+					Labels:   m.Labels.Copy(),
+					State:    domain.AlertPending,
+					ActiveAt: now,
+				}
+				al.alerts[key] = alert
+				changed = append(changed, *alert)
+			}
+
+			alert.Value = value
+			alert.Threshold = threshold
+
+			if alert.State == domain.AlertPending && now.Sub(alert.ActiveAt) >= rule.For {
+				alert.State = domain.AlertFiring
+				alert.FiredAt = now
+				changed = append(changed, *alert)
+			}
+		}
+	}
+
+	for key, alert := range al.alerts {
+		if _, ok := active[key]; ok {
+			continue
+		}
+		switch alert.State {
+		case domain.AlertFiring:
+			alert.State = domain.AlertResolved
+			alert.ResolvedAt = now
+			changed = append(changed, *alert)
+		default:
+			delete(al.alerts, key)
+		}
+	}
+
+	al.mu.Unlock()
+
+	for i := range changed {
+		for _, listener := range al.listeners {
+			listener(ctx, &changed[i])
+		}
+	}
+
+	return nil
+}
+
+// GetAlerts возвращает активные оповещения - в состояниях pending и firing.
+func (al *alertingUseCase) GetAlerts(ctx context.Context) ([]domain.Alert, error) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	res := make([]domain.Alert, 0, len(al.alerts))
+	for _, alert := range al.alerts {
+		if alert.State == domain.AlertResolved {
+			continue
+		}
+		res = append(res, *alert)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Rule != res[j].Rule {
+			return res[i].Rule < res[j].Rule
+		}
+		return domain.MetricKey(res[i].Metric, res[i].Labels) < domain.MetricKey(res[j].Metric, res[j].Labels)
+	})
+	return res, nil
+}
+
+func alertMetricKey(id string, labels domain.Labels, mType domain.MetricType) string {
+	return string(mType) + ":" + domain.MetricKey(id, labels)
+}
+
+func alertMetricValue(m *domain.Metrics) float64 {
+	switch m.MType {
+	case domain.CounterType:
+		return float64(*m.Delta)
+	default:
+		return *m.Value
+	}
+}
@@ -0,0 +1,207 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/app"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlerting_CheckRule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	al := app.NewAlerting(NewMockAllMetricsStorage(ctrl), NewMockAlertRulesReader(ctrl))
+
+	testCases := []struct {
+		name string
+		rule domain.AlertRule
+		isOk bool
+	}{
+		{"threshold", domain.AlertRule{Name: "r", Metric: "Alloc", MType: domain.GaugeType, Op: domain.OpGreater, Threshold: domain.ValuePtr(1)}, true},
+		{"compare", domain.AlertRule{Name: "r", Metric: "HeapAlloc", MType: domain.GaugeType, Op: domain.OpGreater, CompareTo: "HeapSys"}, true},
+		{"counter", domain.AlertRule{Name: "r", Metric: "PollCount", MType: domain.CounterType, Op: domain.OpGreaterEqual, Threshold: domain.ValuePtr(10), For: time.Minute}, true},
+		{"no name", domain.AlertRule{Metric: "Alloc", MType: domain.GaugeType, Op: domain.OpGreater, Threshold: domain.ValuePtr(1)}, false},
+		{"wrong metric", domain.AlertRule{Name: "r", Metric: "0Alloc", MType: domain.GaugeType, Op: domain.OpGreater, Threshold: domain.ValuePtr(1)}, false},
+		{"histogram", domain.AlertRule{Name: "r", Metric: "GCPause", MType: domain.HistogramType, Op: domain.OpGreater, Threshold: domain.ValuePtr(1)}, false},
+		{"wrong op", domain.AlertRule{Name: "r", Metric: "Alloc", MType: domain.GaugeType, Op: "=>", Threshold: domain.ValuePtr(1)}, false},
+		{"no threshold", domain.AlertRule{Name: "r", Metric: "Alloc", MType: domain.GaugeType, Op: domain.OpGreater}, false},
+		{"both", domain.AlertRule{Name: "r", Metric: "Alloc", MType: domain.GaugeType, Op: domain.OpGreater, Threshold: domain.ValuePtr(1), CompareTo: "HeapSys"}, false},
+		{"wrong label", domain.AlertRule{Name: "r", Metric: "Alloc", MType: domain.GaugeType, Op: domain.OpGreater, Threshold: domain.ValuePtr(1), Labels: domain.Labels{"a-b": "1"}}, false},
+		{"negative for", domain.AlertRule{Name: "r", Metric: "Alloc", MType: domain.GaugeType, Op: domain.OpGreater, Threshold: domain.ValuePtr(1), For: -time.Second}, false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			err := al.CheckRule(&test.rule)
+			if test.isOk {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, domain.ErrDataFormat))
+			}
+		})
+	}
+}
+
+func TestAlerting_LoadRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	reader := NewMockAlertRulesReader(ctrl)
+
+	rule := domain.AlertRule{Name: "r", Metric: "Alloc", MType: domain.GaugeType, Op: domain.OpGreater, Threshold: domain.ValuePtr(1)}
+
+	reader.EXPECT().Read(gomock.Any()).Return([]domain.AlertRule{rule, rule}, nil).Times(1)
+	reader.EXPECT().Read(gomock.Any()).Return(nil, errors.New("read error")).Times(1)
+	reader.EXPECT().Read(gomock.Any()).Return([]domain.AlertRule{rule}, nil).Times(1)
+
+	al := app.NewAlerting(NewMockAllMetricsStorage(ctrl), reader)
+
+	err := al.LoadRules(context.Background())
+	assert.True(t, errors.Is(err, domain.ErrDataFormat), "duplicate rule name")
+
+	err = al.LoadRules(context.Background())
+	assert.Error(t, err)
+
+	err = al.LoadRules(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestAlerting_Evaluate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := NewMockAllMetricsStorage(ctrl)
+	reader := NewMockAlertRulesReader(ctrl)
+
+	reader.EXPECT().Read(gomock.Any()).Return([]domain.AlertRule{
+		{
+			Name:      "HighAlloc",
+			Metric:    "Alloc",
+			MType:     domain.GaugeType,
+			Labels:    domain.Labels{"zone": "eu"},
+			Op:        domain.OpGreater,
+			Threshold: domain.ValuePtr(100),
+		},
+		{
+			Name:      "HeapNearSys",
+			Metric:    "HeapAlloc",
+			MType:     domain.GaugeType,
+			Op:        domain.OpGreaterEqual,
+			CompareTo: "HeapSys",
+			For:       time.Hour,
+		},
+	}, nil).Times(1)
+
+	var metrics []domain.Metrics
+	storage.EXPECT().GetAllMetrics(gomock.Any()).DoAndReturn(func(ctx context.Context) ([]domain.Metrics, error) {
+		return metrics, nil
+	}).AnyTimes()
+
+	al := app.NewAlerting(storage, reader)
+
+	var transitions []domain.Alert
+	al.AddListener(func(ctx context.Context, alert *domain.Alert) {
+		transitions = append(transitions, *alert)
+	})
+
+	ctx := context.Background()
+	require.NoError(t, al.LoadRules(ctx))
+
+	// условия выполняются: правило без For сразу срабатывает, с For - ожидает
+	metrics = []domain.Metrics{
+		{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(200), Labels: domain.Labels{"zone": "eu", "host": "h1"}},
+		{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(200), Labels: domain.Labels{"zone": "us"}},
+		{ID: "HeapAlloc", MType: domain.GaugeType, Value: domain.ValuePtr(10)},
+		{ID: "HeapSys", MType: domain.GaugeType, Value: domain.ValuePtr(10)},
+	}
+	require.NoError(t, al.Evaluate(ctx))
+
+	alerts, err := al.GetAlerts(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(alerts))
+
+	assert.Equal(t, "HeapNearSys", alerts[0].Rule)
+	assert.Equal(t, domain.AlertPending, alerts[0].State)
+	assert.Equal(t, float64(10), alerts[0].Threshold)
+
+	assert.Equal(t, "HighAlloc", alerts[1].Rule)
+	assert.Equal(t, domain.AlertFiring, alerts[1].State)
+	assert.Equal(t, domain.Labels{"zone": "eu", "host": "h1"}, alerts[1].Labels)
+	assert.Equal(t, float64(200), alerts[1].Value)
+
+	// pending(HighAlloc), firing(HighAlloc), pending(HeapNearSys)
+	require.Equal(t, 3, len(transitions))
+
+	// условия перестали выполняться: firing -> resolved, pending удаляется
+	metrics = []domain.Metrics{
+		{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(50), Labels: domain.Labels{"zone": "eu", "host": "h1"}},
+		{ID: "HeapAlloc", MType: domain.GaugeType, Value: domain.ValuePtr(5)},
+		{ID: "HeapSys", MType: domain.GaugeType, Value: domain.ValuePtr(10)},
+	}
+	transitions = nil
+	require.NoError(t, al.Evaluate(ctx))
+
+	alerts, err = al.GetAlerts(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(alerts))
+
+	require.Equal(t, 1, len(transitions))
+	assert.Equal(t, domain.AlertResolved, transitions[0].State)
+	assert.Equal(t, "HighAlloc", transitions[0].Rule)
+	assert.False(t, transitions[0].ResolvedAt.IsZero())
+
+	// resolved удаляется при следующей проверке без повторного оповещения
+	transitions = nil
+	require.NoError(t, al.Evaluate(ctx))
+	require.Equal(t, 0, len(transitions))
+}
+
+func TestAlerting_EvaluatePendingToFiring(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storage := NewMockAllMetricsStorage(ctrl)
+	reader := NewMockAlertRulesReader(ctrl)
+
+	reader.EXPECT().Read(gomock.Any()).Return([]domain.AlertRule{
+		{
+			Name:      "ManyPolls",
+			Metric:    "PollCount",
+			MType:     domain.CounterType,
+			Op:        domain.OpGreater,
+			Threshold: domain.ValuePtr(10),
+			For:       10 * time.Millisecond,
+		},
+	}, nil).Times(1)
+
+	storage.EXPECT().GetAllMetrics(gomock.Any()).Return([]domain.Metrics{
+		{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(20)},
+	}, nil).AnyTimes()
+
+	al := app.NewAlerting(storage, reader)
+
+	ctx := context.Background()
+	require.NoError(t, al.LoadRules(ctx))
+
+	require.NoError(t, al.Evaluate(ctx))
+	alerts, err := al.GetAlerts(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(alerts))
+	require.Equal(t, domain.AlertPending, alerts[0].State)
+
+	time.Sleep(20 * time.Millisecond)
+
+	require.NoError(t, al.Evaluate(ctx))
+	alerts, err = al.GetAlerts(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(alerts))
+	require.Equal(t, domain.AlertFiring, alerts[0].State)
+	require.Equal(t, float64(20), alerts[0].Value)
+}
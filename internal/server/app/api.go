@@ -2,11 +2,12 @@ package app
 
 import (
 	"context"
+	"time"
 
 	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
 )
 
-//go:generate mockgen -destination "./generated_mocks_test.go" -package ${GOPACKAGE}_test . Pinger,AllMetricsStorage,BackupFormatter,Storage,MetricsChecker
+//go:generate mockgen -destination "./generated_mocks_test.go" -package ${GOPACKAGE}_test . Pinger,AllMetricsStorage,BackupFormatter,Storage,HistoryStorage,IdempotencyStorage,MetricsChecker,AlertRulesReader,NotifyChannel
 
 type Pinger interface {
 	Ping(ctx context.Context) error
@@ -22,6 +23,10 @@ type BackupFormatter interface {
 	Read(ctx context.Context) ([]domain.Metrics, error)
 }
 
+type AlertRulesReader interface {
+	Read(ctx context.Context) ([]domain.AlertRule, error)
+}
+
 type MetricsChecker interface {
 	CheckMetrics(m *domain.Metrics) error
 }
@@ -31,6 +36,30 @@ type Storage interface {
 	Add(ctx context.Context, m *domain.Metrics) error
 	SetMetrics(ctx context.Context, metric []domain.Metrics) error
 	AddMetrics(ctx context.Context, metric []domain.Metrics) error
-	Get(ctx context.Context, id string, mType domain.MetricType) (*domain.Metrics, error)
+	Get(ctx context.Context, id string, labels domain.Labels, mType domain.MetricType) (*domain.Metrics, error)
 	GetAllMetrics(ctx context.Context) ([]domain.Metrics, error)
 }
+
+// HistoryStorage хранилище истории значений метрик.
+type HistoryStorage interface {
+	AppendSamples(ctx context.Context, ts time.Time, metrics []domain.Metrics) error
+	GetSamples(ctx context.Context, id string, labels domain.Labels, mType domain.MetricType, from, to time.Time) ([]domain.Sample, error)
+	DeleteSamplesBefore(ctx context.Context, ts time.Time) error
+}
+
+// IdempotencyStorage применяет пакеты накапливаемых значений ровно один раз.
+type IdempotencyStorage interface {
+	// AddMetricsOnce накапливает значения, если пакет с ключом key еще не применялся;
+	// для повторного пакета значения не применяются и возвращается false.
+	AddMetricsOnce(ctx context.Context, key string, metric []domain.Metrics) (bool, error)
+	// DeleteBatchKeysBefore удаляет ключи пакетов, примененных раньше ts.
+	DeleteBatchKeysBefore(ctx context.Context, ts time.Time) error
+}
+
+// NotifyChannel канал доставки оповещений.
+//
+// Временные ошибки доставки должны оборачивать domain.ErrNotification - такие отправки повторяются.
+type NotifyChannel interface {
+	Name() string
+	Send(ctx context.Context, notification *domain.Notification) error
+}
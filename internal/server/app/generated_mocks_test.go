@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/StasMerzlyakov/go-metrics/internal/server/app (interfaces: Pinger,AllMetricsStorage,BackupFormatter,Storage,MetricsChecker)
+// Source: github.com/StasMerzlyakov/go-metrics/internal/server/app (interfaces: Pinger,AllMetricsStorage,BackupFormatter,Storage,HistoryStorage,IdempotencyStorage,MetricsChecker,AlertRulesReader,NotifyChannel)
 
 // Package app_test is a generated GoMock package.
 package app_test
@@ -7,6 +7,7 @@ package app_test
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	domain "github.com/StasMerzlyakov/go-metrics/internal/server/domain"
 	gomock "github.com/golang/mock/gomock"
@@ -205,18 +206,18 @@ func (mr *MockStorageMockRecorder) AddMetrics(arg0, arg1 interface{}) *gomock.Ca
 }
 
 // Get mocks base method.
-func (m *MockStorage) Get(arg0 context.Context, arg1 string, arg2 domain.MetricType) (*domain.Metrics, error) {
+func (m *MockStorage) Get(arg0 context.Context, arg1 string, arg2 domain.Labels, arg3 domain.MetricType) (*domain.Metrics, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "Get", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(*domain.Metrics)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockStorageMockRecorder) Get(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) Get(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStorage)(nil).Get), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStorage)(nil).Get), arg0, arg1, arg2, arg3)
 }
 
 // GetAllMetrics mocks base method.
@@ -262,6 +263,124 @@ func (mr *MockStorageMockRecorder) SetMetrics(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMetrics", reflect.TypeOf((*MockStorage)(nil).SetMetrics), arg0, arg1)
 }
 
+// MockHistoryStorage is a mock of HistoryStorage interface.
+type MockHistoryStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockHistoryStorageMockRecorder
+}
+
+// MockHistoryStorageMockRecorder is the mock recorder for MockHistoryStorage.
+type MockHistoryStorageMockRecorder struct {
+	mock *MockHistoryStorage
+}
+
+// NewMockHistoryStorage creates a new mock instance.
+func NewMockHistoryStorage(ctrl *gomock.Controller) *MockHistoryStorage {
+	mock := &MockHistoryStorage{ctrl: ctrl}
+	mock.recorder = &MockHistoryStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHistoryStorage) EXPECT() *MockHistoryStorageMockRecorder {
+	return m.recorder
+}
+
+// AppendSamples mocks base method.
+func (m *MockHistoryStorage) AppendSamples(arg0 context.Context, arg1 time.Time, arg2 []domain.Metrics) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AppendSamples", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AppendSamples indicates an expected call of AppendSamples.
+func (mr *MockHistoryStorageMockRecorder) AppendSamples(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendSamples", reflect.TypeOf((*MockHistoryStorage)(nil).AppendSamples), arg0, arg1, arg2)
+}
+
+// DeleteSamplesBefore mocks base method.
+func (m *MockHistoryStorage) DeleteSamplesBefore(arg0 context.Context, arg1 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSamplesBefore", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSamplesBefore indicates an expected call of DeleteSamplesBefore.
+func (mr *MockHistoryStorageMockRecorder) DeleteSamplesBefore(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSamplesBefore", reflect.TypeOf((*MockHistoryStorage)(nil).DeleteSamplesBefore), arg0, arg1)
+}
+
+// GetSamples mocks base method.
+func (m *MockHistoryStorage) GetSamples(arg0 context.Context, arg1 string, arg2 domain.Labels, arg3 domain.MetricType, arg4, arg5 time.Time) ([]domain.Sample, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSamples", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].([]domain.Sample)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSamples indicates an expected call of GetSamples.
+func (mr *MockHistoryStorageMockRecorder) GetSamples(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSamples", reflect.TypeOf((*MockHistoryStorage)(nil).GetSamples), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// MockIdempotencyStorage is a mock of IdempotencyStorage interface.
+type MockIdempotencyStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyStorageMockRecorder
+}
+
+// MockIdempotencyStorageMockRecorder is the mock recorder for MockIdempotencyStorage.
+type MockIdempotencyStorageMockRecorder struct {
+	mock *MockIdempotencyStorage
+}
+
+// NewMockIdempotencyStorage creates a new mock instance.
+func NewMockIdempotencyStorage(ctrl *gomock.Controller) *MockIdempotencyStorage {
+	mock := &MockIdempotencyStorage{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotencyStorage) EXPECT() *MockIdempotencyStorageMockRecorder {
+	return m.recorder
+}
+
+// AddMetricsOnce mocks base method.
+func (m *MockIdempotencyStorage) AddMetricsOnce(arg0 context.Context, arg1 string, arg2 []domain.Metrics) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddMetricsOnce", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddMetricsOnce indicates an expected call of AddMetricsOnce.
+func (mr *MockIdempotencyStorageMockRecorder) AddMetricsOnce(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMetricsOnce", reflect.TypeOf((*MockIdempotencyStorage)(nil).AddMetricsOnce), arg0, arg1, arg2)
+}
+
+// DeleteBatchKeysBefore mocks base method.
+func (m *MockIdempotencyStorage) DeleteBatchKeysBefore(arg0 context.Context, arg1 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBatchKeysBefore", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBatchKeysBefore indicates an expected call of DeleteBatchKeysBefore.
+func (mr *MockIdempotencyStorageMockRecorder) DeleteBatchKeysBefore(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBatchKeysBefore", reflect.TypeOf((*MockIdempotencyStorage)(nil).DeleteBatchKeysBefore), arg0, arg1)
+}
+
 // MockMetricsChecker is a mock of MetricsChecker interface.
 type MockMetricsChecker struct {
 	ctrl     *gomock.Controller
@@ -298,3 +417,92 @@ func (mr *MockMetricsCheckerMockRecorder) CheckMetrics(arg0 interface{}) *gomock
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckMetrics", reflect.TypeOf((*MockMetricsChecker)(nil).CheckMetrics), arg0)
 }
+
+// MockAlertRulesReader is a mock of AlertRulesReader interface.
+type MockAlertRulesReader struct {
+	ctrl     *gomock.Controller
+	recorder *MockAlertRulesReaderMockRecorder
+}
+
+// MockAlertRulesReaderMockRecorder is the mock recorder for MockAlertRulesReader.
+type MockAlertRulesReaderMockRecorder struct {
+	mock *MockAlertRulesReader
+}
+
+// NewMockAlertRulesReader creates a new mock instance.
+func NewMockAlertRulesReader(ctrl *gomock.Controller) *MockAlertRulesReader {
+	mock := &MockAlertRulesReader{ctrl: ctrl}
+	mock.recorder = &MockAlertRulesReaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAlertRulesReader) EXPECT() *MockAlertRulesReaderMockRecorder {
+	return m.recorder
+}
+
+// Read mocks base method.
+func (m *MockAlertRulesReader) Read(arg0 context.Context) ([]domain.AlertRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read", arg0)
+	ret0, _ := ret[0].([]domain.AlertRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockAlertRulesReaderMockRecorder) Read(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockAlertRulesReader)(nil).Read), arg0)
+}
+
+// MockNotifyChannel is a mock of NotifyChannel interface.
+type MockNotifyChannel struct {
+	ctrl     *gomock.Controller
+	recorder *MockNotifyChannelMockRecorder
+}
+
+// MockNotifyChannelMockRecorder is the mock recorder for MockNotifyChannel.
+type MockNotifyChannelMockRecorder struct {
+	mock *MockNotifyChannel
+}
+
+// NewMockNotifyChannel creates a new mock instance.
+func NewMockNotifyChannel(ctrl *gomock.Controller) *MockNotifyChannel {
+	mock := &MockNotifyChannel{ctrl: ctrl}
+	mock.recorder = &MockNotifyChannelMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNotifyChannel) EXPECT() *MockNotifyChannelMockRecorder {
+	return m.recorder
+}
+
+// Name mocks base method.
+func (m *MockNotifyChannel) Name() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Name")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Name indicates an expected call of Name.
+func (mr *MockNotifyChannelMockRecorder) Name() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockNotifyChannel)(nil).Name))
+}
+
+// Send mocks base method.
+func (m *MockNotifyChannel) Send(arg0 context.Context, arg1 *domain.Notification) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockNotifyChannelMockRecorder) Send(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockNotifyChannel)(nil).Send), arg0, arg1)
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+// SetHistory включает ведение истории значений метрик.
+// Значения старше retention удаляются при вызове TrimHistory.
+func (mc *metricsUseCase) SetHistory(history HistoryStorage, retention time.Duration) {
+	mc.history = history
+	mc.retention = retention
+}
+
+// GetHistory возвращает историю значений метрики за запрошенный интервал.
+func (mc *metricsUseCase) GetHistory(ctx context.Context, q *domain.HistoryQuery) (*domain.MetricHistory, error) {
+	if q == nil {
+		return nil, fmt.Errorf("%w: input is null", domain.ErrDataFormat)
+	}
+
+	if mc.history == nil {
+		return nil, fmt.Errorf("%w: metric history is disabled", domain.ErrNotFound)
+	}
+
+	if !mc.CheckName(q.ID) {
+		return nil, errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("wrong metric ID %v", q.ID))
+	}
+
+	if err := mc.CheckLabels(q.Labels); err != nil {
+		return nil, err
+	}
+
+	if q.MType != domain.CounterType && q.MType != domain.GaugeType && q.MType != domain.HistogramType {
+		return nil, fmt.Errorf("%w: unknown metricType '%v'", domain.ErrDataFormat, q.MType)
+	}
+
+	to := q.To
+	if to.IsZero() {
+		to = mc.now()
+	}
+
+	from := q.From
+	if from.IsZero() {
+		from = to.Add(-mc.retention)
+	}
+
+	if from.After(to) {
+		return nil, fmt.Errorf("%w: from %v is after to %v", domain.ErrDataFormat, from, to)
+	}
+
+	samples, err := mc.history.GetSamples(ctx, q.ID, q.Labels, q.MType, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.MetricHistory{
+		ID:      q.ID,
+		MType:   q.MType,
+		Labels:  q.Labels.Copy(),
+		Samples: samples,
+	}, nil
+}
+
+// TrimHistory удаляет значения, вышедшие за пределы времени хранения.
+func (mc *metricsUseCase) TrimHistory(ctx context.Context) error {
+	if mc.history == nil {
+		return nil
+	}
+	return mc.history.DeleteSamplesBefore(ctx, mc.now().Add(-mc.retention))
+}
+
+// recordHistory сохраняет текущие значения метрик в историю.
+// Ошибка сохранения истории не должна приводить к повторной отправке данных, поэтому она только логируется.
+func (mc *metricsUseCase) recordHistory(ctx context.Context, metrics []domain.Metrics) {
+	if mc.history == nil || len(metrics) == 0 {
+		return
+	}
+
+	if err := mc.history.AppendSamples(ctx, mc.now(), metrics); err != nil {
+		logger := domain.GetCtxLogger(ctx)
+		action := domain.GetAction(1)
+		logger.Errorw(action, "status", "error", "msg", err.Error())
+	}
+}
+
+// recordAccumulatedHistory сохраняет в историю накопленные значения counter и histogram из пакета.
+func (mc *metricsUseCase) recordAccumulatedHistory(ctx context.Context, metrics []domain.Metrics) {
+	if mc.history == nil || len(metrics) == 0 {
+		return
+	}
+
+	var current []domain.Metrics
+	processed := make(map[string]struct{}, len(metrics))
+
+	for _, m := range metrics {
+		key := string(m.MType) + ":" + m.Key()
+		if _, ok := processed[key]; ok {
+			continue
+		}
+		processed[key] = struct{}{}
+
+		value, err := mc.storage.Get(ctx, m.ID, m.Labels, m.MType)
+		if err != nil {
+			logger := domain.GetCtxLogger(ctx)
+			action := domain.GetAction(1)
+			logger.Errorw(action, "status", "error", "msg", err.Error())
+			return
+		}
+		if value != nil {
+			current = append(current, *value)
+		}
+	}
+
+	mc.recordHistory(ctx, current)
+}
@@ -0,0 +1,152 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/app"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateAll_RecordHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := NewMockStorage(ctrl)
+	hs := NewMockHistoryStorage(ctrl)
+
+	st.EXPECT().SetMetrics(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	st.EXPECT().AddMetrics(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	// накопленное значение counter запрашивается один раз, даже если метрика встречается в пакете дважды
+	st.EXPECT().Get(gomock.Any(), "PollCount", gomock.Any(), domain.CounterType).Return(&domain.Metrics{
+		ID:    "PollCount",
+		MType: domain.CounterType,
+		Delta: domain.DeltaPtr(10),
+	}, nil).Times(1)
+
+	var recorded []domain.Metrics
+	hs.EXPECT().AppendSamples(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ts time.Time, ms []domain.Metrics) error {
+			recorded = append(recorded, ms...)
+			return nil
+		}).Times(2)
+
+	mc := app.NewMetrics(st)
+	mc.SetHistory(hs, time.Hour)
+
+	err := mc.UpdateAll(context.Background(), []domain.Metrics{
+		{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(1.5)},
+		{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(1)},
+		{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(2)},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, 2, len(recorded))
+	assert.Equal(t, 1.5, *recorded[0].Value)
+	assert.Equal(t, int64(10), *recorded[1].Delta)
+}
+
+func TestUpdateAll_HistoryErrorIgnored(t *testing.T) {
+	domain.SetMainLogger(testLogger())
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := NewMockStorage(ctrl)
+	hs := NewMockHistoryStorage(ctrl)
+
+	st.EXPECT().SetMetrics(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	st.EXPECT().AddMetrics(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	hs.EXPECT().AppendSamples(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("history error")).Times(1)
+
+	mc := app.NewMetrics(st)
+	mc.SetHistory(hs, time.Hour)
+
+	err := mc.UpdateAll(context.Background(), []domain.Metrics{
+		{ID: "Alloc", MType: domain.GaugeType, Value: domain.ValuePtr(1.5)},
+	})
+	require.NoError(t, err)
+}
+
+func TestGetHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := NewMockStorage(ctrl)
+	hs := NewMockHistoryStorage(ctrl)
+
+	to := time.Now()
+	from := to.Add(-time.Minute)
+
+	samples := []domain.Sample{
+		{Timestamp: from, Value: 1},
+		{Timestamp: to, Value: 2},
+	}
+
+	hs.EXPECT().GetSamples(gomock.Any(), "Alloc", gomock.Any(), domain.GaugeType, from, to).Return(samples, nil).Times(1)
+
+	mc := app.NewMetrics(st)
+
+	_, err := mc.GetHistory(context.Background(), &domain.HistoryQuery{ID: "Alloc", MType: domain.GaugeType})
+	require.ErrorIs(t, err, domain.ErrNotFound, "history is disabled")
+
+	mc.SetHistory(hs, time.Hour)
+
+	res, err := mc.GetHistory(context.Background(), &domain.HistoryQuery{
+		ID:     "Alloc",
+		MType:  domain.GaugeType,
+		Labels: domain.Labels{"host": "h1"},
+		From:   from,
+		To:     to,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, samples, res.Samples)
+	assert.Equal(t, domain.Labels{"host": "h1"}, res.Labels)
+
+	testCases := []struct {
+		name  string
+		input *domain.HistoryQuery
+	}{
+		{"nil query", nil},
+		{"wrong name", &domain.HistoryQuery{ID: "0Alloc", MType: domain.GaugeType}},
+		{"wrong type", &domain.HistoryQuery{ID: "Alloc", MType: "unknown"}},
+		{"wrong labels", &domain.HistoryQuery{ID: "Alloc", MType: domain.GaugeType, Labels: domain.Labels{"a-b": "1"}}},
+		{"wrong interval", &domain.HistoryQuery{ID: "Alloc", MType: domain.GaugeType, From: to, To: from}},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := mc.GetHistory(context.Background(), test.input)
+			assert.ErrorIs(t, err, domain.ErrDataFormat)
+		})
+	}
+}
+
+func TestTrimHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := NewMockStorage(ctrl)
+	hs := NewMockHistoryStorage(ctrl)
+
+	mc := app.NewMetrics(st)
+
+	// история не ведется
+	require.NoError(t, mc.TrimHistory(context.Background()))
+
+	mc.SetHistory(hs, time.Hour)
+
+	hs.EXPECT().DeleteSamplesBefore(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ts time.Time) error {
+			assert.WithinDuration(t, time.Now().Add(-time.Hour), ts, time.Minute)
+			return nil
+		}).Times(1)
+
+	require.NoError(t, mc.TrimHistory(context.Background()))
+}
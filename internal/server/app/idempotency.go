@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+// MaxIdempotencyKeyLen максимальная длина ключа идемпотентности пакета.
+const MaxIdempotencyKeyLen = 128
+
+// SetIdempotency включает применение пакетов с ключом идемпотентности ровно один раз.
+// Ключи хранятся не меньше window; более старые удаляются при вызове TrimBatchKeys.
+func (mc *metricsUseCase) SetIdempotency(batches IdempotencyStorage, window time.Duration) {
+	mc.batches = batches
+	mc.batchWindow = window
+}
+
+// TrimBatchKeys удаляет ключи пакетов, примененных раньше окна дедупликации.
+func (mc *metricsUseCase) TrimBatchKeys(ctx context.Context) error {
+	if mc.batches == nil {
+		return nil
+	}
+	return mc.batches.DeleteBatchKeysBefore(ctx, mc.now().Add(-mc.batchWindow))
+}
+
+// addMetricsOnce накапливает значения пакета; при наличии в контексте ключа идемпотентности повторный пакет
+// не применяется. Возвращает false, если значения не применялись.
+func (mc *metricsUseCase) addMetricsOnce(ctx context.Context, metrics []domain.Metrics) (bool, error) {
+	key := domain.GetIdempotencyKey(ctx)
+	if key == "" || mc.batches == nil {
+		return true, mc.storage.AddMetrics(ctx, metrics)
+	}
+
+	applied, err := mc.batches.AddMetricsOnce(ctx, key, metrics)
+	if err != nil {
+		return false, err
+	}
+
+	if !applied {
+		logger := domain.GetCtxLogger(ctx)
+		action := domain.GetAction(1)
+		logger.Infow(action, "status", "ok", "msg", fmt.Sprintf("batch %v is already applied", key))
+	}
+	return applied, nil
+}
+
+func checkIdempotencyKey(key string) error {
+	if len(key) > MaxIdempotencyKeyLen {
+		return fmt.Errorf("%w: idempotency key is longer than %d", domain.ErrDataFormat, MaxIdempotencyKeyLen)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package app_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/app"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateAll_Idempotency(t *testing.T) {
+	domain.SetMainLogger(testLogger())
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := NewMockStorage(ctrl)
+	hs := NewMockHistoryStorage(ctrl)
+	bs := NewMockIdempotencyStorage(ctrl)
+
+	counters := []domain.Metrics{
+		{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(1)},
+	}
+
+	st.EXPECT().SetMetrics(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	st.EXPECT().AddMetrics(gomock.Any(), gomock.Any()).Times(0)
+
+	gomock.InOrder(
+		bs.EXPECT().AddMetricsOnce(gomock.Any(), "batch-1", counters).Return(true, nil),
+		bs.EXPECT().AddMetricsOnce(gomock.Any(), "batch-1", counters).Return(false, nil),
+	)
+
+	// история накопленного значения записывается только для примененного пакета
+	st.EXPECT().Get(gomock.Any(), "PollCount", gomock.Any(), domain.CounterType).Return(&domain.Metrics{
+		ID:    "PollCount",
+		MType: domain.CounterType,
+		Delta: domain.DeltaPtr(1),
+	}, nil).Times(1)
+	hs.EXPECT().AppendSamples(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	mc := app.NewMetrics(st)
+	mc.SetHistory(hs, time.Hour)
+	mc.SetIdempotency(bs, time.Hour)
+
+	ctx := domain.EnrichWithIdempotencyKey(context.Background(), "batch-1")
+
+	require.NoError(t, mc.UpdateAll(ctx, counters))
+	require.NoError(t, mc.UpdateAll(ctx, counters))
+}
+
+func TestUpdateAll_IdempotencyNoKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := NewMockStorage(ctrl)
+	bs := NewMockIdempotencyStorage(ctrl)
+
+	st.EXPECT().SetMetrics(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	st.EXPECT().AddMetrics(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	bs.EXPECT().AddMetricsOnce(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	mc := app.NewMetrics(st)
+	mc.SetIdempotency(bs, time.Hour)
+
+	err := mc.UpdateAll(context.Background(), []domain.Metrics{
+		{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(1)},
+	})
+	require.NoError(t, err)
+}
+
+func TestUpdateAll_IdempotencyKeyTooLong(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := NewMockStorage(ctrl)
+	bs := NewMockIdempotencyStorage(ctrl)
+
+	mc := app.NewMetrics(st)
+	mc.SetIdempotency(bs, time.Hour)
+
+	ctx := domain.EnrichWithIdempotencyKey(context.Background(), strings.Repeat("k", app.MaxIdempotencyKeyLen+1))
+	err := mc.UpdateAll(ctx, []domain.Metrics{
+		{ID: "PollCount", MType: domain.CounterType, Delta: domain.DeltaPtr(1)},
+	})
+	require.ErrorIs(t, err, domain.ErrDataFormat)
+}
+
+func TestTrimBatchKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	st := NewMockStorage(ctrl)
+	bs := NewMockIdempotencyStorage(ctrl)
+
+	mc := app.NewMetrics(st)
+	require.NoError(t, mc.TrimBatchKeys(context.Background()))
+
+	bs.EXPECT().DeleteBatchKeysBefore(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, ts time.Time) error {
+			require.WithinDuration(t, time.Now().Add(-time.Hour), ts, time.Minute)
+			return nil
+		}).Times(1)
+
+	mc.SetIdempotency(bs, time.Hour)
+	require.NoError(t, mc.TrimBatchKeys(context.Background()))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -13,22 +14,32 @@ import (
 type metricsUseCase struct {
 	storage         Storage
 	changeListeners []domain.ChangeListener
+	history         HistoryStorage
+	retention       time.Duration
+	batches         IdempotencyStorage
+	batchWindow     time.Duration
+	now             func() time.Time
 }
 
 func NewMetrics(storage Storage) *metricsUseCase {
 	return &metricsUseCase{
 		storage: storage,
+		now:     time.Now,
 	}
 }
 
 var nameRegexp = regexp.MustCompile("^[a-zA-Z][a-zA-Z0-9_]*$")
 
-func (mc *metricsUseCase) Get(ctx context.Context, metricType domain.MetricType, name string) (*domain.Metrics, error) {
+var labelNameRegexp = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+
+func (mc *metricsUseCase) Get(ctx context.Context, metricType domain.MetricType, name string, labels domain.Labels) (*domain.Metrics, error) {
 	switch metricType {
 	case domain.CounterType:
-		return mc.GetCounter(ctx, name)
+		return mc.GetCounter(ctx, name, labels)
 	case domain.GaugeType:
-		return mc.GetGauge(ctx, name)
+		return mc.GetGauge(ctx, name, labels)
+	case domain.HistogramType:
+		return mc.GetHistogram(ctx, name, labels)
 	default:
 		return nil, fmt.Errorf("%w: unknown metricType '%v'", domain.ErrDataFormat, metricType)
 	}
@@ -50,6 +61,11 @@ func (mc *metricsUseCase) Update(ctx context.Context, mtr *domain.Metrics) (*dom
 			return nil, err
 		}
 		return mtr, nil
+	case domain.HistogramType:
+		if err := mc.AddHistogram(ctx, mtr); err != nil {
+			return nil, err
+		}
+		return mtr, nil
 	default:
 		return nil, fmt.Errorf("%w: unknown metricType '%v'", domain.ErrDataFormat, mtr.MType)
 	}
@@ -63,15 +79,32 @@ func (mc *metricsUseCase) CheckName(name string) bool {
 	return nameRegexp.MatchString(name)
 }
 
+func (mc *metricsUseCase) CheckLabels(labels domain.Labels) error {
+	for k := range labels {
+		if !labelNameRegexp.MatchString(k) {
+			return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("wrong label name %v", k))
+		}
+	}
+	return nil
+}
+
 func (mc *metricsUseCase) CheckMetrics(m *domain.Metrics) error {
 	if !mc.CheckName(m.ID) {
 		return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("wrong metric ID %v", m.ID))
 	}
 
-	if m.MType != domain.CounterType && m.MType != domain.GaugeType {
+	if err := mc.CheckLabels(m.Labels); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("metric ID: %v", m.ID))
+	}
+
+	if m.MType != domain.CounterType && m.MType != domain.GaugeType && m.MType != domain.HistogramType {
 		return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("metric ID: %v have type %v", m.ID, m.MType))
 	}
 
+	if m.MType != domain.HistogramType && m.Histogram != nil {
+		return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("metric ID: %v have MType %v, but histogram is not null", m.ID, m.MType))
+	}
+
 	if m.MType == domain.CounterType {
 		if m.Delta == nil {
 			return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("metric ID: %v have MType %v, but delta is null", m.ID, m.MType))
@@ -90,6 +123,24 @@ func (mc *metricsUseCase) CheckMetrics(m *domain.Metrics) error {
 		if m.Delta != nil {
 			return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("metric ID: %v have MType %v, but delta is not null", m.ID, m.MType))
 		}
+
+		if !domain.IsFinite(*m.Value) {
+			return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("metric ID: %v have not finite value %v", m.ID, *m.Value))
+		}
+	}
+
+	if m.MType == domain.HistogramType {
+		if m.Histogram == nil {
+			return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("metric ID: %v have MType %v, but histogram is null", m.ID, m.MType))
+		}
+
+		if m.Delta != nil || m.Value != nil {
+			return errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("metric ID: %v have MType %v, but delta or value is not null", m.ID, m.MType))
+		}
+
+		if err := m.Histogram.Check(); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("metric ID: %v", m.ID))
+		}
 	}
 	return nil
 }
@@ -98,18 +149,34 @@ func (mc *metricsUseCase) GetAllMetrics(ctx context.Context) ([]domain.Metrics,
 	return mc.storage.GetAllMetrics(ctx)
 }
 
-func (mc *metricsUseCase) GetCounter(ctx context.Context, name string) (*domain.Metrics, error) {
+func (mc *metricsUseCase) GetCounter(ctx context.Context, name string, labels domain.Labels) (*domain.Metrics, error) {
 	if !mc.CheckName(name) {
 		return nil, errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("wrong metric ID %v", name))
 	}
-	return mc.storage.Get(ctx, name, domain.CounterType)
+	if err := mc.CheckLabels(labels); err != nil {
+		return nil, err
+	}
+	return mc.storage.Get(ctx, name, labels, domain.CounterType)
 }
 
-func (mc *metricsUseCase) GetGauge(ctx context.Context, name string) (*domain.Metrics, error) {
+func (mc *metricsUseCase) GetGauge(ctx context.Context, name string, labels domain.Labels) (*domain.Metrics, error) {
 	if !mc.CheckName(name) {
 		return nil, errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("wrong metric ID %v", name))
 	}
-	return mc.storage.Get(ctx, name, domain.GaugeType)
+	if err := mc.CheckLabels(labels); err != nil {
+		return nil, err
+	}
+	return mc.storage.Get(ctx, name, labels, domain.GaugeType)
+}
+
+func (mc *metricsUseCase) GetHistogram(ctx context.Context, name string, labels domain.Labels) (*domain.Metrics, error) {
+	if !mc.CheckName(name) {
+		return nil, errors.Wrap(domain.ErrDataFormat, fmt.Sprintf("wrong metric ID %v", name))
+	}
+	if err := mc.CheckLabels(labels); err != nil {
+		return nil, err
+	}
+	return mc.storage.Get(ctx, name, labels, domain.HistogramType)
 }
 
 func (mc *metricsUseCase) AddCounter(ctx context.Context, m *domain.Metrics) error {
@@ -125,13 +192,15 @@ func (mc *metricsUseCase) AddCounter(ctx context.Context, m *domain.Metrics) err
 		return err
 	}
 
-	if newValue, err := mc.storage.Get(ctx, m.ID, m.MType); err != nil {
+	if newValue, err := mc.storage.Get(ctx, m.ID, m.Labels, m.MType); err != nil {
 		return err
 	} else {
 		delta := *newValue.Delta
 		m.Delta = &delta
 	}
 
+	mc.recordHistory(ctx, []domain.Metrics{*m})
+
 	for _, changeListenerFn := range mc.changeListeners {
 		changeListenerFn(ctx, m)
 	}
@@ -150,6 +219,36 @@ func (mc *metricsUseCase) SetGauge(ctx context.Context, m *domain.Metrics) error
 		return err
 	}
 
+	mc.recordHistory(ctx, []domain.Metrics{*m})
+
+	for _, changeListenerFn := range mc.changeListeners {
+		changeListenerFn(ctx, m)
+	}
+
+	return nil
+}
+
+func (mc *metricsUseCase) AddHistogram(ctx context.Context, m *domain.Metrics) error {
+	if err := mc.CheckMetrics(m); err != nil {
+		return err
+	}
+
+	if m.MType != domain.HistogramType {
+		return fmt.Errorf("unexpected MType %v, expected %v", m.MType, domain.HistogramType)
+	}
+
+	if err := mc.storage.Add(ctx, m); err != nil {
+		return err
+	}
+
+	if newValue, err := mc.storage.Get(ctx, m.ID, m.Labels, m.MType); err != nil {
+		return err
+	} else {
+		m.Histogram = newValue.Histogram.Copy()
+	}
+
+	mc.recordHistory(ctx, []domain.Metrics{*m})
+
 	for _, changeListenerFn := range mc.changeListeners {
 		changeListenerFn(ctx, m)
 	}
@@ -159,14 +258,18 @@ func (mc *metricsUseCase) SetGauge(ctx context.Context, m *domain.Metrics) error
 
 func (mc *metricsUseCase) UpdateAll(ctx context.Context, mtr []domain.Metrics) error {
 	var gaugeList []domain.Metrics
-	var counterList []domain.Metrics
+	var counterList []domain.Metrics // counter и histogram накапливаются
+
+	if err := checkIdempotencyKey(domain.GetIdempotencyKey(ctx)); err != nil {
+		return err
+	}
 
 	for _, m := range mtr {
 		if err := mc.CheckMetrics(&m); err != nil {
 			return err
 		}
 		switch m.MType {
-		case domain.CounterType:
+		case domain.CounterType, domain.HistogramType:
 			counterList = append(counterList, m)
 		case domain.GaugeType:
 			gaugeList = append(gaugeList, m)
@@ -177,9 +280,15 @@ func (mc *metricsUseCase) UpdateAll(ctx context.Context, mtr []domain.Metrics) e
 		return err
 	}
 
-	if err := mc.storage.AddMetrics(ctx, counterList); err != nil {
+	applied, err := mc.addMetricsOnce(ctx, counterList)
+	if err != nil {
 		return err
 	}
 
+	mc.recordHistory(ctx, gaugeList)
+	if applied {
+		mc.recordAccumulatedHistory(ctx, counterList)
+	}
+
 	return nil
 }
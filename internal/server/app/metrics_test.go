@@ -3,6 +3,7 @@ package app_test
 import (
 	"context"
 	"errors"
+	"math"
 	"testing"
 
 	"github.com/StasMerzlyakov/go-metrics/internal/server/app"
@@ -125,6 +126,92 @@ func TestCheckMetrics(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"CheckMetrics_5",
+			&domain.Metrics{
+				ID:    "Histogram",
+				MType: domain.HistogramType,
+				Histogram: &domain.Histogram{
+					Bounds: []float64{1},
+					Counts: []int64{1, 1},
+					Sum:    3,
+					Count:  2,
+				},
+			},
+			true,
+		},
+		{
+			"CheckMetrics_6",
+			&domain.Metrics{
+				ID:    "Histogram",
+				MType: domain.HistogramType,
+				Value: domain.ValuePtr(1),
+			},
+			false,
+		},
+		{
+			"CheckMetrics_7",
+			&domain.Metrics{
+				ID:    "Histogram",
+				MType: domain.HistogramType,
+				Histogram: &domain.Histogram{
+					Bounds: []float64{1},
+					Counts: []int64{1},
+					Count:  1,
+				},
+			},
+			false,
+		},
+		{
+			"CheckMetrics_labels_ok",
+			&domain.Metrics{
+				ID:     "OK",
+				MType:  domain.GaugeType,
+				Value:  domain.ValuePtr(1),
+				Labels: domain.Labels{"host": "h1", "_zone": "eu-1"},
+			},
+			true,
+		},
+		{
+			"CheckMetrics_labels_wrong",
+			&domain.Metrics{
+				ID:     "OK",
+				MType:  domain.GaugeType,
+				Value:  domain.ValuePtr(1),
+				Labels: domain.Labels{"host-name": "h1"},
+			},
+			false,
+		},
+		{
+			"CheckMetrics_8",
+			&domain.Metrics{
+				ID:    "OK",
+				MType: domain.GaugeType,
+				Value: domain.ValuePtr(1),
+				Histogram: &domain.Histogram{
+					Counts: []int64{0},
+				},
+			},
+			false,
+		},
+		{
+			"CheckMetrics_NaN",
+			&domain.Metrics{
+				ID:    "OK",
+				MType: domain.GaugeType,
+				Value: domain.ValuePtr(math.NaN()),
+			},
+			false,
+		},
+		{
+			"CheckMetrics_Inf",
+			&domain.Metrics{
+				ID:    "OK",
+				MType: domain.GaugeType,
+				Value: domain.ValuePtr(math.Inf(1)),
+			},
+			false,
+		},
 	}
 
 	for _, test := range testCases {
@@ -156,7 +243,7 @@ func TestUpdateMetrics(t *testing.T) {
 	m.EXPECT().AddMetrics(gomock.Any(), gomock.Any()).DoAndReturn(
 		func(ctx context.Context, ms []domain.Metrics) error {
 			for _, m := range ms {
-				assert.Equal(t, domain.CounterType, m.MType)
+				assert.Contains(t, []domain.MetricType{domain.CounterType, domain.HistogramType}, m.MType)
 			}
 			return nil
 		}).AnyTimes()
@@ -201,6 +288,21 @@ func TestUpdateMetrics(t *testing.T) {
 				},
 			},
 		},
+		{
+			"add histogram",
+			[]domain.Metrics{
+				{
+					ID:    "Histogram",
+					MType: domain.HistogramType,
+					Histogram: &domain.Histogram{
+						Bounds: []float64{1},
+						Counts: []int64{0, 1},
+						Sum:    2,
+						Count:  1,
+					},
+				},
+			},
+		},
 	}
 
 	for _, test := range testCases {
@@ -230,7 +332,7 @@ func TestUpdate_Counter(t *testing.T) {
 			return nil
 		}).MaxTimes(1)
 
-	m.EXPECT().Get(gomock.Any(), gomock.Eq(input.ID), gomock.Eq(input.MType)).Return(
+	m.EXPECT().Get(gomock.Any(), gomock.Eq(input.ID), gomock.Any(), gomock.Eq(input.MType)).Return(
 		input, nil,
 	).MaxTimes(1)
 
@@ -260,7 +362,7 @@ func TestUpdate_Gauge(t *testing.T) {
 			return nil
 		}).MaxTimes(1)
 
-	m.EXPECT().Get(gomock.Any(), gomock.Eq(input.ID), gomock.Eq(input.MType)).Return(
+	m.EXPECT().Get(gomock.Any(), gomock.Eq(input.ID), gomock.Any(), gomock.Eq(input.MType)).Return(
 		input, nil,
 	).MaxTimes(1)
 
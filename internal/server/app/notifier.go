@@ -0,0 +1,210 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+)
+
+func NewNotifier(groupWait time.Duration, dedupWindow time.Duration) *notifierUseCase {
+	return &notifierUseCase{
+		groupWait:   groupWait,
+		dedupWindow: dedupWindow,
+		sent:        make(map[string]time.Time),
+		now:         time.Now,
+	}
+}
+
+type notifyChannel struct {
+	channel NotifyChannel
+	invoker domain.RetriableInvoker
+}
+
+// pendingAlert оповещение, ожидающее отправки.
+type pendingAlert struct {
+	alert    domain.Alert
+	queuedAt time.Time
+	channels map[int]struct{} // индексы каналов, в которые нужно отправить; nil - во все
+}
+
+func (p *pendingAlert) sendTo(channel int) bool {
+	if p.channels == nil {
+		return true
+	}
+	_, ok := p.channels[channel]
+	return ok
+}
+
+// notifierUseCase доставляет оповещения в каналы.
+//
+// Оповещения firing и resolved накапливаются в течение groupWait и отправляются группами - по одной на правило.
+// Повторно полученное оповещение (то же правило, метрика, состояние и время начала) в течение dedupWindow не отправляется.
+// Если канал не принял группу из-за временной ошибки и повторы invoker не помогли, оповещения группы
+// отправляются в этот канал при следующем сбросе, но не позже dedupWindow после получения.
+type notifierUseCase struct {
+	groupWait   time.Duration
+	dedupWindow time.Duration
+	channels    []notifyChannel
+
+	mu      sync.Mutex
+	pending []pendingAlert
+	sent    map[string]time.Time // ключ оповещения - время постановки в очередь
+	now     func() time.Time
+}
+
+// AddChannel добавляет канал доставки; ошибки отправки повторяются с помощью invoker.
+func (nt *notifierUseCase) AddChannel(channel NotifyChannel, invoker domain.RetriableInvoker) {
+	nt.channels = append(nt.channels, notifyChannel{
+		channel: channel,
+		invoker: invoker,
+	})
+}
+
+// OnAlert принимает изменение состояния оповещения; используется как domain.AlertListener.
+func (nt *notifierUseCase) OnAlert(ctx context.Context, alert *domain.Alert) {
+	if alert.State != domain.AlertFiring && alert.State != domain.AlertResolved {
+		return
+	}
+
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+
+	key := notificationKey(alert)
+	if _, ok := nt.sent[key]; ok {
+		return
+	}
+
+	for _, p := range nt.pending {
+		if notificationKey(&p.alert) == key {
+			return
+		}
+	}
+
+	nt.pending = append(nt.pending, pendingAlert{alert: *alert, queuedAt: nt.now()})
+}
+
+// Run отправляет накопленные оповещения каждые groupWait до отмены контекста.
+func (nt *notifierUseCase) Run(ctx context.Context) {
+	ticker := time.NewTicker(nt.groupWait)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// оставшиеся оповещения отправляем с отдельным ограничением по времени
+			flushCtx, cancelFn := context.WithTimeout(context.Background(), nt.groupWait)
+			nt.Flush(flushCtx)
+			cancelFn()
+			return
+		case <-ticker.C:
+			nt.Flush(ctx)
+		}
+	}
+}
+
+// Flush группирует накопленные оповещения по правилам и отправляет в каналы.
+func (nt *notifierUseCase) Flush(ctx context.Context) {
+	nt.mu.Lock()
+	alerts := nt.pending
+	nt.pending = nil
+
+	now := nt.now()
+	for key, sentAt := range nt.sent {
+		if now.Sub(sentAt) > nt.dedupWindow {
+			delete(nt.sent, key)
+		}
+	}
+	// оповещение считается отправленным с момента постановки в очередь: OnAlert не добавит его повторно
+	for i := range alerts {
+		key := notificationKey(&alerts[i].alert)
+		if _, ok := nt.sent[key]; !ok {
+			nt.sent[key] = alerts[i].queuedAt
+		}
+	}
+	nt.mu.Unlock()
+
+	if len(alerts) == 0 {
+		return
+	}
+
+	logger := domain.GetCtxLogger(ctx)
+	action := domain.GetAction(1)
+
+	failed := make(map[string]map[int]struct{}) // ключ оповещения - каналы с временной ошибкой
+	for i, ch := range nt.channels {
+		var channelAlerts []domain.Alert
+		for _, p := range alerts {
+			if p.sendTo(i) {
+				channelAlerts = append(channelAlerts, p.alert)
+			}
+		}
+
+		for _, notification := range groupAlerts(channelAlerts) {
+			err := ch.invoker.Invoke(ctx, func(ctx context.Context) error {
+				return ch.channel.Send(ctx, &notification)
+			})
+			if err == nil {
+				continue
+			}
+			logger.Errorw(action, "channel", ch.channel.Name(), "group", notification.Group, "error", err.Error())
+			if !errors.Is(err, domain.ErrNotification) && !errors.Is(err, context.DeadlineExceeded) {
+				continue
+			}
+			for j := range notification.Alerts {
+				key := notificationKey(&notification.Alerts[j])
+				if failed[key] == nil {
+					failed[key] = make(map[int]struct{})
+				}
+				failed[key][i] = struct{}{}
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		nt.retry(alerts, failed)
+	}
+}
+
+// retry возвращает в очередь оповещения, не доставленные в каналы из-за временной ошибки.
+func (nt *notifierUseCase) retry(alerts []pendingAlert, failed map[string]map[int]struct{}) {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+
+	now := nt.now()
+	for _, p := range alerts {
+		channels, ok := failed[notificationKey(&p.alert)]
+		if !ok || now.Sub(p.queuedAt) > nt.dedupWindow {
+			continue
+		}
+		p.channels = channels
+		nt.pending = append(nt.pending, p)
+	}
+}
+
+func notificationKey(alert *domain.Alert) string {
+	return alert.Rule + ":" + domain.MetricKey(alert.Metric, alert.Labels) + ":" + string(alert.State) + ":" + alert.ActiveAt.String()
+}
+
+// groupAlerts объединяет оповещения одного правила; группы упорядочены по имени правила.
+func groupAlerts(alerts []domain.Alert) []domain.Notification {
+	groups := make(map[string][]domain.Alert)
+	for _, alert := range alerts {
+		groups[alert.Rule] = append(groups[alert.Rule], alert)
+	}
+
+	res := make([]domain.Notification, 0, len(groups))
+	for group, groupAlerts := range groups {
+		res = append(res, domain.Notification{
+			Group:  group,
+			Alerts: groupAlerts,
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Group < res[j].Group
+	})
+	return res
+}
@@ -0,0 +1,216 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/app"
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func testLogger() *zap.SugaredLogger {
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		// вызываем панику, если ошибка
+		panic("cannot initialize zap")
+	}
+	return logger.Sugar()
+}
+
+func testInvoker() domain.RetriableInvoker {
+	return domain.CreateRetriableInvokerByConf(&domain.RetriableInvokerConf{
+		RetriableErr:    domain.ErrNotification,
+		FirstRetryDelay: time.Millisecond,
+		DelayIncrement:  time.Millisecond,
+		RetryCount:      3,
+	})
+}
+
+func TestNotifier_GroupAndDeduplicate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	domain.SetMainLogger(testLogger())
+
+	ch := NewMockNotifyChannel(ctrl)
+	ch.EXPECT().Name().Return("test").AnyTimes()
+
+	var sent []domain.Notification
+	ch.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, n *domain.Notification) error {
+			sent = append(sent, *n)
+			return nil
+		}).Times(2)
+
+	nt := app.NewNotifier(time.Second, time.Hour)
+	nt.AddChannel(ch, testInvoker())
+
+	ctx := context.Background()
+	activeAt := time.Now()
+
+	h1 := domain.Alert{Rule: "HighAlloc", Metric: "Alloc", Labels: domain.Labels{"host": "h1"}, State: domain.AlertFiring, ActiveAt: activeAt}
+	h2 := domain.Alert{Rule: "HighAlloc", Metric: "Alloc", Labels: domain.Labels{"host": "h2"}, State: domain.AlertFiring, ActiveAt: activeAt}
+	polls := domain.Alert{Rule: "ManyPolls", Metric: "PollCount", State: domain.AlertFiring, ActiveAt: activeAt}
+	pending := domain.Alert{Rule: "ManyPolls", Metric: "Other", State: domain.AlertPending, ActiveAt: activeAt}
+
+	nt.OnAlert(ctx, &h1)
+	nt.OnAlert(ctx, &h2)
+	nt.OnAlert(ctx, &h1) // дубликат в буфере
+	nt.OnAlert(ctx, &polls)
+	nt.OnAlert(ctx, &pending) // pending не отправляется
+
+	nt.Flush(ctx)
+
+	require.Equal(t, 2, len(sent))
+	require.Equal(t, "HighAlloc", sent[0].Group)
+	require.Equal(t, 2, len(sent[0].Alerts))
+	require.Equal(t, "ManyPolls", sent[1].Group)
+	require.Equal(t, 1, len(sent[1].Alerts))
+
+	// уже отправленное оповещение не отправляется повторно
+	nt.OnAlert(ctx, &h1)
+	nt.Flush(ctx)
+	require.Equal(t, 2, len(sent))
+}
+
+func TestNotifier_Resolved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	domain.SetMainLogger(testLogger())
+
+	ch := NewMockNotifyChannel(ctrl)
+	ch.EXPECT().Name().Return("test").AnyTimes()
+
+	var sent []domain.Notification
+	ch.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, n *domain.Notification) error {
+			sent = append(sent, *n)
+			return nil
+		}).Times(2)
+
+	nt := app.NewNotifier(time.Second, time.Hour)
+	nt.AddChannel(ch, testInvoker())
+
+	ctx := context.Background()
+	alert := domain.Alert{Rule: "HighAlloc", Metric: "Alloc", State: domain.AlertFiring, ActiveAt: time.Now()}
+
+	nt.OnAlert(ctx, &alert)
+	nt.Flush(ctx)
+
+	alert.State = domain.AlertResolved
+	nt.OnAlert(ctx, &alert)
+	nt.Flush(ctx)
+
+	require.Equal(t, 2, len(sent))
+	require.Equal(t, domain.AlertResolved, sent[1].Alerts[0].State)
+}
+
+func TestNotifier_ChannelRetry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	domain.SetMainLogger(testLogger())
+
+	// первый канал восстанавливается после временной ошибки
+	retried := NewMockNotifyChannel(ctrl)
+	retried.EXPECT().Name().Return("retried").AnyTimes()
+	gomock.InOrder(
+		retried.EXPECT().Send(gomock.Any(), gomock.Any()).Return(fmt.Errorf("%w: timeout", domain.ErrNotification)).Times(1),
+		retried.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil).Times(1),
+	)
+
+	// второй канал возвращает постоянную ошибку - повторов нет
+	failed := NewMockNotifyChannel(ctrl)
+	failed.EXPECT().Name().Return("failed").AnyTimes()
+	failed.EXPECT().Send(gomock.Any(), gomock.Any()).Return(errors.New("bad request")).Times(1)
+
+	// ошибка второго канала не влияет на третий
+	ok := NewMockNotifyChannel(ctrl)
+	ok.EXPECT().Name().Return("ok").AnyTimes()
+	ok.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	nt := app.NewNotifier(time.Second, time.Hour)
+	nt.AddChannel(retried, testInvoker())
+	nt.AddChannel(failed, testInvoker())
+	nt.AddChannel(ok, testInvoker())
+
+	ctx := context.Background()
+	nt.OnAlert(ctx, &domain.Alert{Rule: "HighAlloc", Metric: "Alloc", State: domain.AlertFiring, ActiveAt: time.Now()})
+	nt.Flush(ctx)
+}
+
+func TestNotifier_RetryUndelivered(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	domain.SetMainLogger(testLogger())
+
+	// канал недоступен дольше, чем длятся повторы invoker
+	unavailable := NewMockNotifyChannel(ctrl)
+	unavailable.EXPECT().Name().Return("unavailable").AnyTimes()
+	gomock.InOrder(
+		unavailable.EXPECT().Send(gomock.Any(), gomock.Any()).Return(fmt.Errorf("%w: timeout", domain.ErrNotification)).Times(4),
+		unavailable.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil).Times(1),
+	)
+
+	// доступный канал получает оповещение один раз
+	ok := NewMockNotifyChannel(ctrl)
+	ok.EXPECT().Name().Return("ok").AnyTimes()
+	ok.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	nt := app.NewNotifier(time.Second, time.Hour)
+	nt.AddChannel(unavailable, testInvoker())
+	nt.AddChannel(ok, testInvoker())
+
+	ctx := context.Background()
+	alert := domain.Alert{Rule: "HighAlloc", Metric: "Alloc", State: domain.AlertFiring, ActiveAt: time.Now()}
+	nt.OnAlert(ctx, &alert)
+	nt.Flush(ctx)
+
+	// повторно полученное оповещение не дублирует ожидающее отправки
+	nt.OnAlert(ctx, &alert)
+	nt.Flush(ctx)
+
+	// оповещение доставлено - повторов больше нет
+	nt.Flush(ctx)
+}
+
+func TestNotifier_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	domain.SetMainLogger(testLogger())
+
+	ch := NewMockNotifyChannel(ctrl)
+	ch.EXPECT().Name().Return("test").AnyTimes()
+
+	done := make(chan struct{})
+	ch.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, n *domain.Notification) error {
+			close(done)
+			return nil
+		}).Times(1)
+
+	nt := app.NewNotifier(10*time.Millisecond, time.Hour)
+	nt.AddChannel(ch, testInvoker())
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	nt.OnAlert(ctx, &domain.Alert{Rule: "HighAlloc", Metric: "Alloc", State: domain.AlertFiring, ActiveAt: time.Now()})
+
+	go nt.Run(ctx)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("notification is not sent")
+	}
+}
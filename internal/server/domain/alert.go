@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// CompareOp операция сравнения в правиле оповещения.
+type CompareOp string
+
+// Допустимые операции сравнения
+const (
+	OpGreater      CompareOp = ">"
+	OpGreaterEqual CompareOp = ">="
+	OpLess         CompareOp = "<"
+	OpLessEqual    CompareOp = "<="
+	OpEqual        CompareOp = "=="
+	OpNotEqual     CompareOp = "!="
+)
+
+// Compare возвращает результат сравнения left op right.
+func (op CompareOp) Compare(left, right float64) (bool, error) {
+	switch op {
+	case OpGreater:
+		return left > right, nil
+	case OpGreaterEqual:
+		return left >= right, nil
+	case OpLess:
+		return left < right, nil
+	case OpLessEqual:
+		return left <= right, nil
+	case OpEqual:
+		return left == right, nil
+	case OpNotEqual:
+		return left != right, nil
+	default:
+		return false, fmt.Errorf("%w: unknown compare operation '%v'", ErrDataFormat, op)
+	}
+}
+
+// AlertRule правило оповещения.
+//
+// Значение метрики Metric сравнивается либо с порогом Threshold, либо со значением метрики CompareTo
+// того же типа и с теми же метками. Условие должно выполняться в течение For, чтобы оповещение сработало.
+type AlertRule struct {
+	Name      string        // уникальное имя правила
+	Metric    string        // имя метрики
+	MType     MetricType    // тип метрики: gauge или counter
+	Labels    Labels        // метки, которые должны быть у метрики; пустые - любые
+	Op        CompareOp     // операция сравнения
+	Threshold *float64      // порог
+	CompareTo string        // имя метрики для сравнения
+	For       time.Duration // время выполнения условия до срабатывания
+}
+
+// AlertState состояние оповещения.
+type AlertState string
+
+// Допустимые состояния оповещения
+const (
+	AlertPending  AlertState = "pending"  // условие выполняется, время For еще не прошло
+	AlertFiring   AlertState = "firing"   // оповещение сработало
+	AlertResolved AlertState = "resolved" // условие перестало выполняться после срабатывания
+)
+
+// Alert оповещение, созданное правилом для конкретной метрики.
+type Alert struct {
+	Rule       string     `json:"rule"`             // имя правила
+	Metric     string     `json:"metric"`           // имя метрики
+	MType      MetricType `json:"type"`             // тип метрики
+	Labels     Labels     `json:"labels,omitempty"` // метки метрики
+	State      AlertState `json:"state"`            // состояние
+	Value      float64    `json:"value"`            // последнее значение метрики
+	Threshold  float64    `json:"threshold"`        // значение, с которым сравнивалась метрика
+	ActiveAt   time.Time  `json:"activeAt"`         // время, с которого выполняется условие
+	FiredAt    time.Time  `json:"firedAt"`          // время срабатывания
+	ResolvedAt time.Time  `json:"resolvedAt"`       // время разрешения
+}
+
+// Notification группа оповещений одного правила, отправляемая одним сообщением.
+type Notification struct {
+	Group  string  `json:"group"`  // имя правила
+	Alerts []Alert `json:"alerts"` // оповещения группы
+}
@@ -0,0 +1,37 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareOp(t *testing.T) {
+	testCases := []struct {
+		op          domain.CompareOp
+		left, right float64
+		result      bool
+	}{
+		{domain.OpGreater, 2, 1, true},
+		{domain.OpGreater, 1, 1, false},
+		{domain.OpGreaterEqual, 1, 1, true},
+		{domain.OpLess, 1, 2, true},
+		{domain.OpLess, 2, 2, false},
+		{domain.OpLessEqual, 2, 2, true},
+		{domain.OpEqual, 2, 2, true},
+		{domain.OpNotEqual, 2, 2, false},
+	}
+
+	for _, test := range testCases {
+		t.Run(string(test.op), func(t *testing.T) {
+			res, err := test.op.Compare(test.left, test.right)
+			assert.NoError(t, err)
+			assert.Equal(t, test.result, res)
+		})
+	}
+
+	_, err := domain.CompareOp("=>").Compare(1, 2)
+	assert.True(t, errors.Is(err, domain.ErrDataFormat))
+}
@@ -53,3 +53,18 @@ func (l *requestIDLogger) Errorw(msg string, keysAndValues ...any) {
 	keysAndValues = append(keysAndValues, LoggerKeyRequestID, l.requestID)
 	l.internalLogger.Infow(msg, keysAndValues...)
 }
+
+const IdempotencyKey = ContextKey("IdempotencyKey")
+
+// EnrichWithIdempotencyKey добавляет в контекст ключ идемпотентности пакета значений.
+func EnrichWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, IdempotencyKey, key)
+}
+
+// GetIdempotencyKey возвращает ключ идемпотентности из контекста; пустая строка - ключ не задан.
+func GetIdempotencyKey(ctx context.Context) string {
+	if v, ok := ctx.Value(IdempotencyKey).(string); ok {
+		return v
+	}
+	return ""
+}
@@ -53,3 +53,11 @@ func TestEnrichContextRequestID(t *testing.T) {
 	log.Errorw("test errorw", "msg", "hello")
 	log.Infow("test errorw", "msg", "hello")
 }
+
+func TestEnrichContextIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	require.Empty(t, domain.GetIdempotencyKey(ctx))
+
+	enrichedCtx := domain.EnrichWithIdempotencyKey(ctx, "batch-1")
+	require.Equal(t, "batch-1", domain.GetIdempotencyKey(enrichedCtx))
+}
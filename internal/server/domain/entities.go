@@ -1,17 +1,37 @@
 package domain
 
 // Metrics используется для передачи данных о метриках при взаимодействии с сервисом сбора метрик.
+// Метрика идентифицируется парой (ID, Labels).
 type Metrics struct {
-	ID    string     `json:"id"`              // имя метрики
-	MType MetricType `json:"type"`            // параметр, принимающий значение gauge или counter
-	Delta *int64     `json:"delta,omitempty"` // значение метрики в случае передачи counter
-	Value *float64   `json:"value,omitempty"` // значение метрики в случае передачи gauge
+	ID        string     `json:"id"`                  // имя метрики
+	MType     MetricType `json:"type"`                // параметр, принимающий значение gauge, counter или histogram
+	Delta     *int64     `json:"delta,omitempty"`     // значение метрики в случае передачи counter
+	Value     *float64   `json:"value,omitempty"`     // значение метрики в случае передачи gauge
+	Histogram *Histogram `json:"histogram,omitempty"` // значение метрики в случае передачи histogram
+	Labels    Labels     `json:"labels,omitempty"`    // метки метрики
+}
+
+// Key возвращает ключ, идентифицирующий метрику в хранилище.
+func (m *Metrics) Key() string {
+	return MetricKey(m.ID, m.Labels)
+}
+
+// Histogram распределение наблюдений по корзинам.
+//
+// Bounds - верхние границы корзин по возрастанию; Counts содержит на один элемент больше,
+// последний элемент - количество наблюдений, превысивших последнюю границу (+Inf).
+type Histogram struct {
+	Bounds []float64 `json:"bounds"` // верхние границы корзин
+	Counts []int64   `json:"counts"` // количество наблюдений в каждой корзине
+	Sum    float64   `json:"sum"`    // сумма наблюдений
+	Count  int64     `json:"count"`  // общее количество наблюдений
 }
 
 type MetricType string
 
 // Допустимые значения типа метрики
 const (
-	GaugeType   MetricType = "gauge"
-	CounterType MetricType = "counter"
+	GaugeType     MetricType = "gauge"
+	CounterType   MetricType = "counter"
+	HistogramType MetricType = "histogram"
 )
@@ -12,6 +12,8 @@ var (
 	ErrNotFound          = errors.New("NotFoundError")
 	ErrDBConnection      = errors.New("DatabaseConnectionError")
 	ErrMediaType         = errors.New("UnsupportedMediaTypeError")
+	ErrEntityTooLarge    = errors.New("EntityTooLargeError") // Превышен допустимый размер запроса
+	ErrNotification      = errors.New("NotificationError")   // Временная ошибка доставки оповещения; отправку можно повторить
 )
 
 func MapDomainErrorToHTTPStatusErr(err error) int {
@@ -19,6 +21,10 @@ func MapDomainErrorToHTTPStatusErr(err error) int {
 		return http.StatusUnsupportedMediaType
 	}
 
+	if errors.Is(err, ErrEntityTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+
 	if errors.Is(err, ErrDataFormat) {
 		return http.StatusBadRequest
 	}
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NewHistogram создает пустую гистограмму с заданными границами корзин.
+func NewHistogram(bounds []float64) *Histogram {
+	b := make([]float64, len(bounds))
+	copy(b, bounds)
+	return &Histogram{
+		Bounds: b,
+		Counts: make([]int64, len(b)+1),
+	}
+}
+
+// Observe добавляет наблюдение в гистограмму.
+func (h *Histogram) Observe(v float64) {
+	h.ObserveN(v, 1)
+}
+
+// ObserveN добавляет n одинаковых наблюдений v.
+func (h *Histogram) ObserveN(v float64, n int64) {
+	idx := sort.SearchFloat64s(h.Bounds, v)
+	h.Counts[idx] += n
+	h.Sum += float64(n) * v
+	h.Count += n
+}
+
+// Copy возвращает глубокую копию гистограммы.
+func (h *Histogram) Copy() *Histogram {
+	if h == nil {
+		return nil
+	}
+	res := &Histogram{
+		Bounds: make([]float64, len(h.Bounds)),
+		Counts: make([]int64, len(h.Counts)),
+		Sum:    h.Sum,
+		Count:  h.Count,
+	}
+	copy(res.Bounds, h.Bounds)
+	copy(res.Counts, h.Counts)
+	return res
+}
+
+// SameBounds проверяет совпадение границ корзин.
+func (h *Histogram) SameBounds(other *Histogram) bool {
+	if len(h.Bounds) != len(other.Bounds) {
+		return false
+	}
+	for i := range h.Bounds {
+		if h.Bounds[i] != other.Bounds[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Check проверяет целостность гистограммы.
+func (h *Histogram) Check() error {
+	if len(h.Counts) != len(h.Bounds)+1 {
+		return fmt.Errorf("%w: histogram has %d bounds and %d counts", ErrDataFormat, len(h.Bounds), len(h.Counts))
+	}
+
+	if !IsFinite(h.Sum) {
+		return fmt.Errorf("%w: histogram sum is not finite", ErrDataFormat)
+	}
+
+	for i, b := range h.Bounds {
+		if !IsFinite(b) {
+			return fmt.Errorf("%w: histogram bound is not finite", ErrDataFormat)
+		}
+		if i > 0 && b <= h.Bounds[i-1] {
+			return fmt.Errorf("%w: histogram bounds are not strictly increasing", ErrDataFormat)
+		}
+	}
+
+	var total int64
+	for _, c := range h.Counts {
+		if c < 0 {
+			return fmt.Errorf("%w: histogram has negative bucket count", ErrDataFormat)
+		}
+		total += c
+	}
+
+	if total != h.Count {
+		return fmt.Errorf("%w: histogram count %d doesn't match bucket counts sum %d", ErrDataFormat, h.Count, total)
+	}
+	return nil
+}
+
+// MergeHistogram возвращает результат слияния накопленной гистограммы cur с новыми наблюдениями in.
+// Если границы корзин изменились, накопленные данные сбрасываются и результатом является копия in.
+func MergeHistogram(cur *Histogram, in *Histogram) *Histogram {
+	if cur == nil || !cur.SameBounds(in) {
+		return in.Copy()
+	}
+
+	res := cur.Copy()
+	for i, c := range in.Counts {
+		res.Counts[i] += c
+	}
+	res.Sum += in.Sum
+	res.Count += in.Count
+	return res
+}
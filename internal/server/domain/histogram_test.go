@@ -0,0 +1,130 @@
+package domain_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramObserve(t *testing.T) {
+	h := domain.NewHistogram([]float64{1, 5, 10})
+
+	for _, v := range []float64{0.5, 1, 3, 7, 100} {
+		h.Observe(v)
+	}
+
+	assert.Equal(t, []int64{2, 1, 1, 1}, h.Counts)
+	assert.Equal(t, int64(5), h.Count)
+	assert.Equal(t, 111.5, h.Sum)
+	require.NoError(t, h.Check())
+}
+
+func TestHistogramObserveN(t *testing.T) {
+	h := domain.NewHistogram([]float64{1, 5, 10})
+
+	h.ObserveN(3, 1_000_000_000)
+	h.ObserveN(0.5, 2)
+
+	assert.Equal(t, []int64{2, 1_000_000_000, 0, 0}, h.Counts)
+	assert.Equal(t, int64(1_000_000_002), h.Count)
+	assert.Equal(t, 3_000_000_001., h.Sum)
+	require.NoError(t, h.Check())
+}
+
+func TestHistogramCheck(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input *domain.Histogram
+		isOk  bool
+	}{
+		{
+			"ok",
+			&domain.Histogram{Bounds: []float64{1, 2}, Counts: []int64{1, 0, 2}, Sum: 10, Count: 3},
+			true,
+		},
+		{
+			"no bounds",
+			&domain.Histogram{Counts: []int64{3}, Sum: 10, Count: 3},
+			true,
+		},
+		{
+			"wrong counts length",
+			&domain.Histogram{Bounds: []float64{1, 2}, Counts: []int64{1, 2}, Count: 3},
+			false,
+		},
+		{
+			"unsorted bounds",
+			&domain.Histogram{Bounds: []float64{2, 1}, Counts: []int64{1, 0, 2}, Count: 3},
+			false,
+		},
+		{
+			"count mismatch",
+			&domain.Histogram{Bounds: []float64{1, 2}, Counts: []int64{1, 0, 2}, Count: 4},
+			false,
+		},
+		{
+			"negative count",
+			&domain.Histogram{Bounds: []float64{1}, Counts: []int64{-1, 1}, Count: 0},
+			false,
+		},
+		{
+			"NaN bound",
+			&domain.Histogram{Bounds: []float64{1, math.NaN()}, Counts: []int64{1, 0, 2}, Count: 3},
+			false,
+		},
+		{
+			"Inf bound",
+			&domain.Histogram{Bounds: []float64{1, math.Inf(1)}, Counts: []int64{1, 0, 2}, Count: 3},
+			false,
+		},
+		{
+			"NaN sum",
+			&domain.Histogram{Bounds: []float64{1}, Counts: []int64{1, 1}, Sum: math.NaN(), Count: 2},
+			false,
+		},
+		{
+			"Inf sum",
+			&domain.Histogram{Bounds: []float64{1}, Counts: []int64{1, 1}, Sum: math.Inf(-1), Count: 2},
+			false,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.input.Check()
+			if test.isOk {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, domain.ErrDataFormat))
+			}
+		})
+	}
+}
+
+func TestMergeHistogram(t *testing.T) {
+	in := &domain.Histogram{Bounds: []float64{1, 2}, Counts: []int64{1, 0, 2}, Sum: 10, Count: 3}
+
+	t.Run("empty", func(t *testing.T) {
+		res := domain.MergeHistogram(nil, in)
+		assert.Equal(t, in, res)
+		res.Counts[0] = 100
+		assert.Equal(t, int64(1), in.Counts[0], "result must be a copy")
+	})
+
+	t.Run("same bounds", func(t *testing.T) {
+		res := domain.MergeHistogram(in, in)
+		assert.Equal(t, []int64{2, 0, 4}, res.Counts)
+		assert.Equal(t, int64(6), res.Count)
+		assert.Equal(t, float64(20), res.Sum)
+	})
+
+	t.Run("bounds changed", func(t *testing.T) {
+		cur := &domain.Histogram{Bounds: []float64{5}, Counts: []int64{7, 7}, Sum: 100, Count: 14}
+		res := domain.MergeHistogram(cur, in)
+		assert.Equal(t, in, res)
+	})
+}
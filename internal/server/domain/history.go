@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// Sample значение метрики в момент времени.
+//
+// Для gauge хранится значение, для counter - накопленное значение, для histogram - общее количество наблюдений.
+type Sample struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// HistoryQuery запрос истории метрики за интервал [From, To].
+type HistoryQuery struct {
+	ID     string     `json:"id"`               // имя метрики
+	MType  MetricType `json:"type"`             // тип метрики
+	Labels Labels     `json:"labels,omitempty"` // метки метрики
+	From   time.Time  `json:"from"`             // начало интервала; если не задано - с начала хранимой истории
+	To     time.Time  `json:"to"`               // конец интервала; если не задан - текущий момент
+}
+
+// MetricHistory история значений метрики.
+type MetricHistory struct {
+	ID      string     `json:"id"`
+	MType   MetricType `json:"type"`
+	Labels  Labels     `json:"labels,omitempty"`
+	Samples []Sample   `json:"samples"`
+}
+
+// ToSample возвращает значение метрики в момент времени ts.
+func ToSample(m *Metrics, ts time.Time) Sample {
+	sample := Sample{
+		Timestamp: ts,
+	}
+	switch m.MType {
+	case CounterType:
+		if m.Delta != nil {
+			sample.Value = float64(*m.Delta)
+		}
+	case GaugeType:
+		if m.Value != nil {
+			sample.Value = *m.Value
+		}
+	case HistogramType:
+		if m.Histogram != nil {
+			sample.Value = float64(m.Histogram.Count)
+		}
+	}
+	return sample
+}
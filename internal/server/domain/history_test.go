@@ -0,0 +1,35 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToSample(t *testing.T) {
+	ts := time.Now()
+
+	h := domain.NewHistogram([]float64{1, 2})
+	h.Observe(0.5)
+	h.Observe(3)
+
+	testData := []struct {
+		name     string
+		metrics  *domain.Metrics
+		expected float64
+	}{
+		{"counter", &domain.Metrics{ID: "c", MType: domain.CounterType, Delta: domain.DeltaPtr(5)}, 5},
+		{"gauge", &domain.Metrics{ID: "g", MType: domain.GaugeType, Value: domain.ValuePtr(1.5)}, 1.5},
+		{"histogram", &domain.Metrics{ID: "h", MType: domain.HistogramType, Histogram: h}, 2},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			sample := domain.ToSample(test.metrics, ts)
+			assert.Equal(t, test.expected, sample.Value)
+			assert.Equal(t, ts, sample.Timestamp)
+		})
+	}
+}
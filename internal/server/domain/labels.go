@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Labels метки метрики: имя метки - значение.
+type Labels map[string]string
+
+// String возвращает каноническое представление меток вида {a="1",b="2"}; для пустых меток - пустую строку.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(l[k]))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// Copy возвращает копию меток; для пустых меток - nil.
+func (l Labels) Copy() Labels {
+	if len(l) == 0 {
+		return nil
+	}
+	res := make(Labels, len(l))
+	for k, v := range l {
+		res[k] = v
+	}
+	return res
+}
+
+// Match проверяет, что метки содержат все пары из matchers.
+func (l Labels) Match(matchers Labels) bool {
+	for k, v := range matchers {
+		if value, ok := l[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// MetricKey возвращает ключ метрики, однозначно определяемый именем и метками.
+func MetricKey(id string, labels Labels) string {
+	return id + labels.String()
+}
+
+// MetricIDFromKey извлекает имя метрики из ключа, построенного MetricKey.
+func MetricIDFromKey(key string) string {
+	if idx := strings.IndexByte(key, '{'); idx >= 0 {
+		return key[:idx]
+	}
+	return key
+}
@@ -0,0 +1,61 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/StasMerzlyakov/go-metrics/internal/server/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricKey(t *testing.T) {
+	testCases := []struct {
+		name   string
+		id     string
+		labels domain.Labels
+		key    string
+	}{
+		{
+			"no labels",
+			"Alloc",
+			nil,
+			"Alloc",
+		},
+		{
+			"empty labels",
+			"Alloc",
+			domain.Labels{},
+			"Alloc",
+		},
+		{
+			"sorted labels",
+			"Alloc",
+			domain.Labels{"zone": "eu", "host": "h1"},
+			`Alloc{host="h1",zone="eu"}`,
+		},
+		{
+			"quoted value",
+			"Alloc",
+			domain.Labels{"host": `a",b="c`},
+			`Alloc{host="a\",b=\"c"}`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			key := domain.MetricKey(test.id, test.labels)
+			assert.Equal(t, test.key, key)
+			assert.Equal(t, test.id, domain.MetricIDFromKey(key))
+		})
+	}
+}
+
+func TestLabelsMatch(t *testing.T) {
+	labels := domain.Labels{"host": "h1", "zone": "eu"}
+
+	assert.True(t, labels.Match(nil))
+	assert.True(t, labels.Match(domain.Labels{"host": "h1"}))
+	assert.True(t, labels.Match(domain.Labels{"host": "h1", "zone": "eu"}))
+	assert.False(t, labels.Match(domain.Labels{"host": "h2"}))
+	assert.False(t, labels.Match(domain.Labels{"dc": "1"}))
+	assert.False(t, domain.Labels(nil).Match(domain.Labels{"host": "h1"}))
+}
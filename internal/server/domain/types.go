@@ -3,3 +3,6 @@ package domain
 import "context"
 
 type ChangeListener func(ctx context.Context, m *Metrics) error
+
+// AlertListener вызывается при изменении состояния оповещения.
+type AlertListener func(ctx context.Context, alert *Alert)
@@ -1,8 +1,10 @@
 package domain
 
 import (
+	"math"
 	"runtime"
 	"strconv"
+	"strings"
 )
 
 func DeltaPtr(v int64) *int64 {
@@ -21,6 +23,21 @@ func ExtractFloat64(valueStr string) (float64, error) {
 	return value, nil
 }
 
+// IsFinite проверяет, что значение не является NaN или ±Inf.
+func IsFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// SanitizeName заменяет символы, недопустимые в имени метрики и метки (например, '.' и '-'), на '_'.
+func SanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
 func ExtractInt64(valueStr string) (int64, error) {
 	value, err := strconv.ParseInt(valueStr, 10, 64)
 	if err != nil {
@@ -104,3 +104,21 @@ func TestExtractInt64(t *testing.T) {
 		})
 	}
 }
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"cpu_usage", "cpu_usage"},
+		{"servers.web-1.cpu", "servers_web_1_cpu"},
+		{"http.server.duration", "http_server_duration"},
+		{"температура", "___________"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.expected, domain.SanitizeName(tt.input))
+		})
+	}
+}